@@ -0,0 +1,51 @@
+// Package accesslog provides a gRPC server interceptor that logs one line per
+// RPC via klog, tagged with a per-request ID so that other log lines emitted
+// while handling the same RPC (e.g. from the handler itself) can be
+// correlated with it.
+package accesslog
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// requestIDKey is the context key under which UnaryServerInterceptor stores
+// the request ID it generates. It's unexported so that RequestID is the only
+// way to read it back out.
+type requestIDKey struct{}
+
+// RequestID returns the ID that UnaryServerInterceptor assigned to ctx's RPC,
+// or "" if ctx didn't come from one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that assigns a
+// random request ID to the context passed to the handler, then logs the
+// method, peer address, duration, and resulting status code of every RPC it
+// handles.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := uuid.NewString()
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		peerAddr := "unknown"
+		if p, ok := peer.FromContext(ctx); ok {
+			peerAddr = p.Addr.String()
+		}
+		klog.Infof("request_id=%s method=%q peer=%q duration=%s code=%s", id, info.FullMethod, peerAddr, duration, status.Code(err))
+
+		return resp, err
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"k8s.io/klog/v2"
 
 	// For statically embedding the template.
@@ -26,10 +30,15 @@ var (
 	namespaceFlag = flag.String("namespace", "", `The namespace in which the solver functions live. Should be double-colon-separated, e.g., "adventofcode::cc::year2022::day01". If left empty the value will be derived from the -header_file flag by replacing slashes with double colons and stripping the .h suffix, e.g., "adventofcode/cc/year2022/day01.h" => "adventofcode::cc::year2022::day01".`)
 	part1Func     = flag.String("part1_func", "", "The name of the function solving part 1. Required if -part1_pairs is non-empty.")
 	part2Func     = flag.String("part2_func", "", "The name of the function solving part 2. Required if -part2_pairs is non-empty.")
-	part1Pairs    = flag.String("part1_pairs", "", `Comma-separated list of file pairs of the form "name:in_file:out_file" containing problem inputs and corresponding expected outputs. Required if -part1_func is true.`)
-	part2Pairs    = flag.String("part2_pairs", "", `Comma-separated list of file pairs of the form "name:in_file:out_file" containing problem inputs and corresponding expected outputs. Required if -part2_func is true.`)
+	part1Pairs    = flag.String("part1_pairs", "", `Comma-separated list of file pairs of the form "name:in_file:out_file" containing problem inputs and corresponding expected outputs. Mutually exclusive with -part1_dir.`)
+	part2Pairs    = flag.String("part2_pairs", "", `Comma-separated list of file pairs of the form "name:in_file:out_file" containing problem inputs and corresponding expected outputs. Mutually exclusive with -part2_dir.`)
+	part1Dir      = flag.String("part1_dir", "", "Directory to scan for pairs of files matching -in_pattern/-out_pattern, using the shared stem as the test name. Mutually exclusive with -part1_pairs.")
+	part2Dir      = flag.String("part2_dir", "", "Directory to scan for pairs of files matching -in_pattern/-out_pattern, using the shared stem as the test name. Mutually exclusive with -part2_pairs.")
+	inPattern     = flag.String("in_pattern", "*.in", "Glob pattern, relative to -part1_dir/-part2_dir, matching input files.")
+	outPattern    = flag.String("out_pattern", "*.out", "Glob pattern, relative to -part1_dir/-part2_dir, matching expected-output files. Each input file's stem (the match with the leading '*' of -in_pattern substituted in) is paired with the output file of the same stem.")
 
-	output = flag.String("output", "", "Path to write the generated file to. Leaving this empty writes the file to stdout.")
+	output = flag.String("output", "", "Path to write the generated file to. Leaving this empty writes the file to stdout. Required if -watch is set.")
+	watch  = flag.Bool("watch", false, "Instead of generating once and exiting, keep running and regenerate -output whenever the header file or any input/output file changes, debouncing bursts of changes into a single regeneration.")
 )
 
 var (
@@ -50,11 +59,107 @@ type templateArgs struct {
 	Part1Pairs, Part2Pairs []inOutPair
 }
 
-func errmain() error {
+// collectExplicitPairs parses the old "name:in_file:out_file" comma-separated
+// flag format, returning the parsed pairs and the list of files read, in the
+// order they should be watched.
+func collectExplicitPairs(flagName, pairsFlag string) ([]inOutPair, []string, error) {
+	var pairs []inOutPair
+	var paths []string
+	for _, pair := range strings.Split(pairsFlag, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("%s contains invalid element %q", flagName, pair)
+		}
+		name := parts[0]
+		if name == "" {
+			return nil, nil, fmt.Errorf("%s contains element with empty name: %q", flagName, pair)
+		}
+		in, out := parts[1], parts[2]
+		inData, err := os.ReadFile(in)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s contained unreadable file: %v", flagName, err)
+		}
+		outData, err := os.ReadFile(out)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s contained unreadable file: %v", flagName, err)
+		}
+		pairs = append(pairs, inOutPair{Name: name, In: string(inData), Out: string(outData)})
+		paths = append(paths, in, out)
+	}
+	return pairs, paths, nil
+}
+
+// globPairs scans dir for files matching inPattern (default "*.in"),
+// pairing each one with the file of the same stem matching outPattern
+// (default "*.out") in the same directory. The stem is the part of the
+// input file's name matched by inPattern's "*", and becomes the pair's test
+// name. It returns the parsed pairs, sorted by input file name, and the
+// list of files read, in the order they should be watched.
+func globPairs(dir, inPattern, outPattern string) ([]inOutPair, []string, error) {
+	inStar := strings.IndexByte(inPattern, '*')
+	if inStar < 0 {
+		return nil, nil, fmt.Errorf("-in_pattern=%q must contain a '*'", inPattern)
+	}
+	outStar := strings.IndexByte(outPattern, '*')
+	if outStar < 0 {
+		return nil, nil, fmt.Errorf("-out_pattern=%q must contain a '*'", outPattern)
+	}
+	inSuffix, outSuffix := inPattern[inStar+1:], outPattern[outStar+1:]
+
+	matches, err := filepath.Glob(filepath.Join(dir, inPattern))
+	if err != nil {
+		return nil, nil, fmt.Errorf("glob %s in %s: %w", inPattern, dir, err)
+	}
+	sort.Strings(matches)
+
+	var pairs []inOutPair
+	var paths []string
+	for _, inPath := range matches {
+		stem := strings.TrimSuffix(filepath.Base(inPath), inSuffix)
+		outPath := filepath.Join(dir, stem+outSuffix)
+		inData, err := os.ReadFile(inPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read input file %s: %w", inPath, err)
+		}
+		outData, err := os.ReadFile(outPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s has no matching output file %s: %w", inPath, outPath, err)
+		}
+		pairs = append(pairs, inOutPair{Name: stem, In: string(inData), Out: string(outData)})
+		paths = append(paths, inPath, outPath)
+	}
+	return pairs, paths, nil
+}
+
+// resolvePairs returns the pairs for one part (1 or 2), sourced from either
+// the old pairsFlag or the new dirFlag, whichever is set -- the two are
+// mutually exclusive. It also returns every file read, so callers can watch
+// them for changes.
+func resolvePairs(partLabel, fn, pairsFlag, dirFlag string) ([]inOutPair, []string, error) {
+	if pairsFlag != "" && dirFlag != "" {
+		return nil, nil, fmt.Errorf("-%s_pairs and -%s_dir are mutually exclusive, but both were set", partLabel, partLabel)
+	}
+	have := pairsFlag != "" || dirFlag != ""
+	if (fn != "") != have {
+		return nil, nil, fmt.Errorf("-%s_func=%q and -%s_pairs/-%s_dir; either both or none must be set", partLabel, fn, partLabel, partLabel)
+	}
+	if fn == "" {
+		return nil, nil, nil
+	}
+	if dirFlag != "" {
+		return globPairs(dirFlag, *inPattern, *outPattern)
+	}
+	return collectExplicitPairs("-"+partLabel+"_pairs", pairsFlag)
+}
+
+// buildArgs reads every flag-selected input and output file and returns the
+// resulting templateArgs, along with every file that was read -- the latter
+// is what -watch watches for changes.
+func buildArgs() (templateArgs, []string, error) {
 	var args templateArgs
 
 	if *headerFile == "" {
-		return errors.New("-header_file is required but was empty")
+		return templateArgs{}, nil, errors.New("-header_file is required but was empty")
 	}
 	args.HeaderFile = *headerFile
 
@@ -64,73 +169,35 @@ func errmain() error {
 	}
 	args.Namespace = namespace
 
-	// The conditions in the if statements below evaluates to false if one of
-	// the flags is set but not the other.
-	if fn, pairs := *part1Func, *part1Pairs; (fn != "") != (pairs != "") {
-		return fmt.Errorf("-part1_func=%q and -part1_pairs=%q; either both or none must be set", fn, pairs)
-	}
-	args.Part1Func = *part1Func
-	if fn, pairs := *part2Func, *part2Pairs; (fn != "") != (pairs != "") {
-		return fmt.Errorf("-part2_func=%q and -part2_pairs=%q; either both or none must be set", fn, pairs)
-	}
-	args.Part2Func = *part2Func
+	var paths []string
 
-	if *part1Func != "" {
-		for _, pair := range strings.Split(*part1Pairs, ",") {
-			parts := strings.Split(pair, ":")
-			if len(parts) != 3 {
-				return fmt.Errorf("-part1_pairs contains invalid element %q", pair)
-			}
-			name := parts[0]
-			if name == "" {
-				return fmt.Errorf("-part1_pairs contains element with empty name: %q", pair)
-			}
-			in, out := parts[1], parts[2]
-			inData, err := os.ReadFile(in)
-			if err != nil {
-				return fmt.Errorf("-part1_pairs contained unreadable file: %v", err)
-			}
-			outData, err := os.ReadFile(out)
-			if err != nil {
-				return fmt.Errorf("-part1_pairs contained unreadable file: %v", err)
-			}
-			args.Part1Pairs = append(args.Part1Pairs, inOutPair{
-				Name: name,
-				In:   string(inData),
-				Out:  string(outData),
-			})
-		}
+	pairs1, paths1, err := resolvePairs("part1", *part1Func, *part1Pairs, *part1Dir)
+	if err != nil {
+		return templateArgs{}, nil, err
 	}
+	args.Part1Func = *part1Func
+	args.Part1Pairs = pairs1
+	paths = append(paths, paths1...)
 
-	if *part2Func != "" {
-		for _, pair := range strings.Split(*part2Pairs, ",") {
-			parts := strings.Split(pair, ":")
-			if len(parts) != 3 {
-				return fmt.Errorf("-part2_pairs contains invalid element %q", pair)
-			}
-			name := parts[0]
-			if name == "" {
-				return fmt.Errorf("-part2_pairs contains element with empty name: %q", pair)
-			}
-			in, out := parts[1], parts[2]
-			inData, err := os.ReadFile(in)
-			if err != nil {
-				return fmt.Errorf("-part2_pairs contained unreadable file: %v", err)
-			}
-			outData, err := os.ReadFile(out)
-			if err != nil {
-				return fmt.Errorf("-part2_pairs contained unreadable file: %v", err)
-			}
-			args.Part2Pairs = append(args.Part2Pairs, inOutPair{
-				Name: name,
-				In:   string(inData),
-				Out:  string(outData),
-			})
-		}
+	pairs2, paths2, err := resolvePairs("part2", *part2Func, *part2Pairs, *part2Dir)
+	if err != nil {
+		return templateArgs{}, nil, err
 	}
+	args.Part2Func = *part2Func
+	args.Part2Pairs = pairs2
+	paths = append(paths, paths2...)
 
 	klog.V(1).Infof("Template args: %+v", args)
+	return args, paths, nil
+}
 
+// generateOnce builds the template args from the current flags and writes
+// the rendered template to -output (or stdout, if -output is empty).
+func generateOnce() error {
+	args, _, err := buildArgs()
+	if err != nil {
+		return err
+	}
 	var out io.Writer
 	if *output == "" {
 		out = os.Stdout
@@ -148,6 +215,97 @@ func errmain() error {
 	return nil
 }
 
+// watchDebounce is how long runWatch waits, after the last change it
+// noticed, before regenerating -- so that a burst of editor writes to
+// several files coalesces into a single regeneration.
+const watchDebounce = 300 * time.Millisecond
+
+// runWatch generates -output once, then keeps running, regenerating it
+// whenever the header file or any input/output file -part1_dir/-part2_dir
+// or -part1_pairs/-part2_pairs resolve to changes. It re-scans the watched
+// set after every regeneration, so files added to a -part1_dir/-part2_dir
+// later are picked up without restarting.
+func runWatch() error {
+	if *output == "" {
+		return errors.New("-watch requires -output to be set")
+	}
+	if err := generateOnce(); err != nil {
+		return err
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	defer w.Close()
+
+	watched := map[string]bool{*headerFile: false}
+	if err := w.Add(*headerFile); err != nil {
+		return fmt.Errorf("watch %s: %w", *headerFile, err)
+	}
+	watched[*headerFile] = true
+
+	addWatches := func() error {
+		_, paths, err := buildArgs()
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			if watched[p] {
+				continue
+			}
+			if err := w.Add(p); err != nil {
+				return fmt.Errorf("watch %s: %w", p, err)
+			}
+			watched[p] = true
+		}
+		return nil
+	}
+	if err := addWatches(); err != nil {
+		return err
+	}
+
+	var timer *time.Timer
+	regenerate := func() {
+		if err := generateOnce(); err != nil {
+			klog.Errorf("regenerate %s: %v", *output, err)
+			return
+		}
+		klog.Infof("regenerated %s", *output)
+		if err := addWatches(); err != nil {
+			klog.Errorf("update watches: %v", err)
+		}
+	}
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(watchDebounce, regenerate)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			klog.Errorf("watcher error: %v", err)
+		}
+	}
+}
+
+func errmain() error {
+	if *watch {
+		return runWatch()
+	}
+	return generateOnce()
+}
+
 func main() {
 	flag.Parse()
 	if err := errmain(); err != nil {
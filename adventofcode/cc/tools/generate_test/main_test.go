@@ -13,6 +13,7 @@ var (
 	binary  = runfiles.MustPath("adventofcode/cc/tools/generate_test/generate_test_/generate_test")
 	inFile  = runfiles.MustPath("adventofcode/cc/tools/generate_test/testdata/test.in")
 	outFile = runfiles.MustPath("adventofcode/cc/tools/generate_test/testdata/test.out")
+	pairDir = runfiles.MustPath("adventofcode/cc/tools/generate_test/testdata/pairs")
 )
 
 func TestGenerateTest(t *testing.T) {
@@ -98,6 +99,24 @@ func TestGenerateTest(t *testing.T) {
 				"-part2_pairs": "test:" + inFile + ":" + outFile,
 			},
 		},
+		{
+			name: "Part1Dir",
+			flags: map[string]string{
+				"-header_file": "adventofcode/cc/year2050/day01.h",
+				"-part1_func":  "Part1",
+				"-part1_dir":   pairDir,
+			},
+		},
+		{
+			name: "Part1DirAndPart2Pairs",
+			flags: map[string]string{
+				"-header_file": "adventofcode/cc/year2050/day01.h",
+				"-part1_func":  "Part1",
+				"-part1_dir":   pairDir,
+				"-part2_func":  "Part2",
+				"-part2_pairs": "test:" + inFile + ":" + outFile,
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			var args []string
@@ -187,6 +206,29 @@ func TestGenerateTest_Error(t *testing.T) {
 				"-part1_pairs": ":" + inFile + ":" + outFile,
 			},
 		},
+		{
+			name: "Part1PairsAndPart1DirBothSet",
+			flags: map[string]string{
+				"-header_file": "adventofcode/cc/year2050/day01.h",
+				"-part1_func":  "Part1",
+				"-part1_pairs": "test:" + inFile + ":" + outFile,
+				"-part1_dir":   pairDir,
+			},
+		},
+		{
+			name: "Part1FuncButNoPairsOrDir",
+			flags: map[string]string{
+				"-header_file": "adventofcode/cc/year2050/day01.h",
+				"-part1_func":  "Part1",
+			},
+		},
+		{
+			name: "WatchWithoutOutput",
+			flags: map[string]string{
+				"-header_file": "adventofcode/cc/year2050/day01.h",
+				"-watch":       "true",
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			var args []string
@@ -2,6 +2,76 @@
 // authentication and authorization in gRPC services.
 package auth
 
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
 // MetadataKey is the canonical key in gRPC metadata where
 // authentication/authorization data is stored.
 const MetadataKey = "authorization"
+
+// Scheme authenticates requests carrying one particular authentication
+// scheme, identified by the keyword at the start of the MetadataKey value
+// (e.g. "Basic", "Bearer"). See basic.Scheme and bearer.Scheme.
+type Scheme interface {
+	// Keyword is the scheme keyword this Scheme handles, exactly as it
+	// appears at the start of the MetadataKey value, e.g. "Basic".
+	Keyword() string
+	// Authenticate is called with the request's full MetadataKey value
+	// (including the keyword) whenever that value starts with Keyword().
+	// It returns the context the call should continue with -- typically
+	// ctx itself, or ctx with request-scoped data such as claims attached
+	// -- or an error if the value isn't valid for this scheme. The error
+	// is returned to the caller as-is if it's already a *status.Status
+	// error (via status.FromError); otherwise ChainInterceptor wraps it as
+	// codes.Unauthenticated.
+	Authenticate(ctx context.Context, value string) (context.Context, error)
+}
+
+// ChainInterceptor returns a grpc.UnaryServerInterceptor that authenticates
+// each request by trying every scheme in schemes, in order, against the
+// request's MetadataKey value: only the scheme whose Keyword() matches the
+// value's prefix is asked to authenticate it. The first scheme that
+// accepts wins. If none of the schemes' keywords match, or the one scheme
+// that does match rejects the request, the interceptor returns
+// codes.Unauthenticated.
+//
+// This lets a single server accept multiple authentication schemes at
+// once -- e.g. HTTP Basic for local dev tools alongside bearer tokens
+// issued by an OIDC provider for CI -- by combining their Schemes here,
+// instead of each scheme's package having to know about the others.
+func ChainInterceptor(schemes ...Scheme) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return nil, status.Errorf(codes.Unauthenticated, "auth: no metadata in incoming context")
+		}
+		values := md.Get(MetadataKey)
+		if got, want := len(values), 1; got != want {
+			return nil, status.Errorf(codes.Unauthenticated, "auth: metadata key %q has %d values; want exactly %d", MetadataKey, got, want)
+		}
+		value := values[0]
+		keyword, _, _ := strings.Cut(value, " ")
+
+		for _, scheme := range schemes {
+			if scheme.Keyword() != keyword {
+				continue
+			}
+			newCtx, err := scheme.Authenticate(ctx, value)
+			if err != nil {
+				if _, ok := status.FromError(err); ok {
+					return nil, err
+				}
+				return nil, status.Errorf(codes.Unauthenticated, "auth: %v", err)
+			}
+			return handler(newCtx, req)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "auth: no configured scheme handles authorization scheme %q", keyword)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeScheme is a minimal auth.Scheme for testing ChainInterceptor in
+// isolation from any real scheme implementation.
+type fakeScheme struct {
+	keyword string
+	err     error
+}
+
+func (s *fakeScheme) Keyword() string { return s.keyword }
+
+func (s *fakeScheme) Authenticate(ctx context.Context, value string) (context.Context, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return context.WithValue(ctx, fakeScheme{}, s.keyword), nil
+}
+
+func call(t *testing.T, interceptor grpc.UnaryServerInterceptor, value string) error {
+	t.Helper()
+	ctx := context.Background()
+	if value != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, value))
+	}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	return err
+}
+
+func TestChainInterceptor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("FirstMatchingSchemeWins", func(t *testing.T) {
+		t.Parallel()
+		interceptor := ChainInterceptor(&fakeScheme{keyword: "Basic"}, &fakeScheme{keyword: "Bearer"})
+		if err := call(t, interceptor, "Bearer sometoken"); err != nil {
+			t.Errorf("call() err = %v; want nil", err)
+		}
+	})
+
+	t.Run("NoSchemeRecognizesKeyword", func(t *testing.T) {
+		t.Parallel()
+		interceptor := ChainInterceptor(&fakeScheme{keyword: "Basic"})
+		err := call(t, interceptor, "Digest whatever")
+		if got, want := status.Code(err), codes.Unauthenticated; got != want {
+			t.Errorf("call() code = %v; want %v", got, want)
+		}
+	})
+
+	t.Run("MatchingSchemeRejects", func(t *testing.T) {
+		t.Parallel()
+		interceptor := ChainInterceptor(&fakeScheme{keyword: "Basic", err: errors.New("bad credentials")})
+		err := call(t, interceptor, "Basic whatever")
+		if got, want := status.Code(err), codes.Unauthenticated; got != want {
+			t.Errorf("call() code = %v; want %v", got, want)
+		}
+	})
+
+	t.Run("NoMetadata", func(t *testing.T) {
+		t.Parallel()
+		interceptor := ChainInterceptor(&fakeScheme{keyword: "Basic"})
+		err := call(t, interceptor, "")
+		if got, want := status.Code(err), codes.Unauthenticated; got != want {
+			t.Errorf("call() code = %v; want %v", got, want)
+		}
+	})
+
+	t.Run("SchemeReturnsExistingStatus", func(t *testing.T) {
+		t.Parallel()
+		interceptor := ChainInterceptor(&fakeScheme{keyword: "Basic", err: status.Error(codes.PermissionDenied, "nope")})
+		err := call(t, interceptor, "Basic whatever")
+		if got, want := status.Code(err), codes.PermissionDenied; got != want {
+			t.Errorf("call() code = %v; want %v", got, want)
+		}
+	})
+}
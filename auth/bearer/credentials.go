@@ -0,0 +1,71 @@
+package bearer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.saser.se/auth"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// Token is a JWT bearer token, as supplied in a request using HTTP Bearer
+// authentication. Token is to be used for per-RPC credentials in gRPC, the
+// same way basic.Credentials is for HTTP Basic authentication.
+type Token string
+
+var _ credentials.PerRPCCredentials = Token("")
+
+// Parse takes a string of the format "Bearer <jwt>" and parses it into a
+// Token. The JWT itself is not verified; that happens in Interceptor.
+func Parse(s string) (Token, error) {
+	formatErr := fmt.Errorf("bearer: credentials do not match expected format %q", "Bearer <jwt>")
+
+	kind, jwt, found := strings.Cut(s, " ")
+	if !found {
+		return "", formatErr
+	}
+	if kind != "Bearer" {
+		return "", formatErr
+	}
+	if jwt == "" {
+		return "", formatErr
+	}
+	return Token(jwt), nil
+}
+
+// FromIncomingContext extracts the bearer token from the gRPC metadata
+// attached to ctx.
+func FromIncomingContext(ctx context.Context) (Token, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errors.New("bearer: no metadata in incoming context")
+	}
+	values := md.Get(auth.MetadataKey)
+	if got, want := len(values), 1; got != want {
+		return "", fmt.Errorf("bearer: metadata key %q has %d values; want exactly %d", auth.MetadataKey, got, want)
+	}
+	return Parse(values[0])
+}
+
+// HeaderValue encodes the token into the form expected by HTTP headers and
+// gRPC metadata, namely:
+//
+//	Bearer <jwt>
+func (t Token) HeaderValue() string {
+	return "Bearer " + string(t)
+}
+
+// GetRequestMetadata returns a map containing the authorization key (see
+// auth.MetadataKey) with a value of t.HeaderValue().
+func (t Token) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{
+		auth.MetadataKey: t.HeaderValue(),
+	}, nil
+}
+
+// RequireTransportSecurity always returns true -- a bearer token is
+// completely insecure without transport security (like HTTPS).
+func (t Token) RequireTransportSecurity() bool { return true }
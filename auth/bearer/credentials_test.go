@@ -0,0 +1,73 @@
+package bearer
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		s       string
+		want    Token
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			s:    "Bearer abc.def.ghi",
+			want: Token("abc.def.ghi"),
+		},
+		{
+			name:    "WrongKind",
+			s:       "Basic abc.def.ghi",
+			wantErr: true,
+		},
+		{
+			name:    "NoSpace",
+			s:       "Bearerabc.def.ghi",
+			wantErr: true,
+		},
+		{
+			name:    "EmptyToken",
+			s:       "Bearer ",
+			wantErr: true,
+		},
+		{
+			name:    "Empty",
+			s:       "",
+			wantErr: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) err = %v; wantErr = %t", tt.s, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Parse(%q) = %q; want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToken_HeaderValue(t *testing.T) {
+	t.Parallel()
+	token := Token("abc.def.ghi")
+	const want = "Bearer abc.def.ghi"
+	if got := token.HeaderValue(); got != want {
+		t.Errorf("HeaderValue() = %q; want %q", got, want)
+	}
+}
+
+func TestToken_GetRequestMetadata(t *testing.T) {
+	t.Parallel()
+	token := Token("abc.def.ghi")
+	md, err := token.GetRequestMetadata(nil)
+	if err != nil {
+		t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+	}
+	const want = "Bearer abc.def.ghi"
+	if got := md["authorization"]; got != want {
+		t.Errorf(`GetRequestMetadata()["authorization"] = %q; want %q`, got, want)
+	}
+}
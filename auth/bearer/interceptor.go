@@ -0,0 +1,72 @@
+package bearer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.saser.se/auth"
+	"google.golang.org/grpc"
+)
+
+// claimsKeyType is an unexported type so that the context key below can't
+// collide with keys set up by other packages.
+type claimsKeyType struct{}
+
+var claimsKey claimsKeyType
+
+// ClaimsFromContext returns the claims of the bearer token that Interceptor
+// verified for the current request, if any.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+// bearerScheme implements auth.Scheme for bearer tokens verified by a
+// Verifier.
+type bearerScheme struct {
+	verifier Verifier
+}
+
+// Scheme returns an auth.Scheme that verifies bearer tokens using verifier,
+// for use with auth.ChainInterceptor. On success, the token's claims are
+// attached to the context and made available to handlers via
+// ClaimsFromContext.
+func Scheme(verifier Verifier) (auth.Scheme, error) {
+	if verifier == nil {
+		return nil, errors.New("bearer: scheme: nil verifier")
+	}
+	return &bearerScheme{verifier: verifier}, nil
+}
+
+func (s *bearerScheme) Keyword() string { return "Bearer" }
+
+func (s *bearerScheme) Authenticate(ctx context.Context, value string) (context.Context, error) {
+	token, err := Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("metadata does not have expected format %q: %w", "Bearer <jwt>", err)
+	}
+	claims, err := s.verifier.Verify(string(token))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return context.WithValue(ctx, claimsKey, claims), nil
+}
+
+// Interceptor returns a gRPC unary server interceptor that requires every
+// request to carry a bearer token in the "authorization" metadata key (see
+// auth.MetadataKey), in the form "Bearer <jwt>". The token is verified using
+// verifier; on any failure -- missing metadata, malformed token, bad
+// signature, expiry -- the interceptor returns codes.Unauthenticated. On
+// success, the token's claims are attached to the context and made
+// available to handlers via ClaimsFromContext. It is a thin wrapper around
+// Scheme and auth.ChainInterceptor, kept around for backwards compatibility
+// with callers that only need bearer auth.
+func Interceptor(verifier Verifier) (grpc.UnaryServerInterceptor, error) {
+	s, err := Scheme(verifier)
+	if err != nil {
+		return nil, err
+	}
+	return auth.ChainInterceptor(s), nil
+}
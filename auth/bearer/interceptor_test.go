@@ -0,0 +1,93 @@
+package bearer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.saser.se/grpctest"
+	"go.saser.se/testing/echo"
+	echopb "go.saser.se/testing/echo_go_proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterceptor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	secret := []byte("super secret signing key")
+	verifier := NewStaticVerifier(jwt.SigningMethodHS256, secret)
+
+	i, err := Interceptor(verifier)
+	if err != nil {
+		t.Fatalf("Interceptor(%v) err = %v; want nil", verifier, err)
+	}
+	srv := grpctest.New(ctx, t, grpctest.Options{
+		ServiceDesc:    &echopb.Echo_ServiceDesc,
+		Implementation: echo.Server{},
+
+		ServerOptions: []grpc.ServerOption{
+			grpc.UnaryInterceptor(i),
+		},
+	})
+
+	validToken := signedToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	expiredToken := signedToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	wrongKeyToken := signedToken(t, jwt.SigningMethodHS256, []byte("wrong key"), jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	for _, tt := range []struct {
+		name  string
+		creds Token
+		want  codes.Code
+	}{
+		{
+			name:  "OK",
+			creds: Token(validToken),
+			want:  codes.OK,
+		},
+		{
+			name:  "Expired",
+			creds: Token(expiredToken),
+			want:  codes.Unauthenticated,
+		},
+		{
+			name:  "WrongKey",
+			creds: Token(wrongKeyToken),
+			want:  codes.Unauthenticated,
+		},
+		{
+			name:  "Malformed",
+			creds: Token("not-a-jwt"),
+			want:  codes.Unauthenticated,
+		},
+		{
+			name:  "Empty",
+			creds: Token(""),
+			want:  codes.Unauthenticated,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := echopb.NewEchoClient(srv.ClientConn)
+			req := &echopb.EchoRequest{Message: "This needs authentication"}
+			_, err := client.Echo(ctx, req, grpc.PerRPCCredentials(tt.creds))
+			if got, want := status.Code(err), tt.want; got != want {
+				t.Errorf("creds = %+v; Echo(%v) err = %v; want code %v", tt.creds, req, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package bearer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// defaultRefreshBefore is how long before expiry RefreshableCredentials
+// fetches a new token, if RefreshableCredentials.RefreshBefore is zero.
+const defaultRefreshBefore = 30 * time.Second
+
+// TokenSource supplies bearer tokens for RefreshableCredentials, along with
+// each token's expiry time. It's typically backed by an OIDC provider or
+// similar, e.g. a client_credentials exchange for short-lived CI tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (token string, expires time.Time, err error)
+}
+
+// RefreshableCredentials is a credentials.PerRPCCredentials that fetches a
+// bearer token from Source on first use, and transparently refreshes it
+// from Source once it's within RefreshBefore of expiring, instead of
+// requiring the caller to manage token lifetimes themselves.
+type RefreshableCredentials struct {
+	Source TokenSource
+	// RefreshBefore is how long before expiry the token is refreshed. If
+	// zero, defaultRefreshBefore is used.
+	RefreshBefore time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var _ credentials.PerRPCCredentials = (*RefreshableCredentials)(nil)
+
+// GetRequestMetadata returns a map containing the authorization key (see
+// auth.MetadataKey) with the current, possibly just-refreshed, token.
+func (c *RefreshableCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.currentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Token(token).GetRequestMetadata(ctx)
+}
+
+func (c *RefreshableCredentials) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	refreshBefore := c.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+	if c.token == "" || time.Now().Add(refreshBefore).After(c.expires) {
+		token, expires, err := c.Source.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("bearer: refresh token: %w", err)
+		}
+		c.token = token
+		c.expires = expires
+	}
+	return c.token, nil
+}
+
+// RequireTransportSecurity always returns true -- a bearer token is
+// completely insecure without transport security (like HTTPS).
+func (c *RefreshableCredentials) RequireTransportSecurity() bool { return true }
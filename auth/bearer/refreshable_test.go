@@ -0,0 +1,94 @@
+package bearer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeTokenSource struct {
+	calls int32
+	token string
+	ttl   time.Duration
+	err   error
+}
+
+func (s *fakeTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	n := atomic.AddInt32(&s.calls, 1)
+	if s.err != nil {
+		return "", time.Time{}, s.err
+	}
+	return s.token + "-" + string(rune('0'+n)), time.Now().Add(s.ttl), nil
+}
+
+func TestRefreshableCredentials_GetRequestMetadata(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CachesUntilNearExpiry", func(t *testing.T) {
+		t.Parallel()
+		src := &fakeTokenSource{token: "tok", ttl: time.Hour}
+		c := &RefreshableCredentials{Source: src}
+
+		md1, err := c.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+		}
+		md2, err := c.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+		}
+		if md1["authorization"] != md2["authorization"] {
+			t.Errorf("token changed across calls without nearing expiry: %q != %q", md1["authorization"], md2["authorization"])
+		}
+		if got, want := src.calls, int32(1); got != want {
+			t.Errorf("Source.Token called %d times; want %d", got, want)
+		}
+	})
+
+	t.Run("RefreshesOnFirstCall", func(t *testing.T) {
+		t.Parallel()
+		src := &fakeTokenSource{token: "tok", ttl: time.Hour}
+		c := &RefreshableCredentials{Source: src}
+		if _, err := c.GetRequestMetadata(context.Background()); err != nil {
+			t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+		}
+		if got, want := src.calls, int32(1); got != want {
+			t.Errorf("Source.Token called %d times; want %d", got, want)
+		}
+	})
+
+	t.Run("RefreshesNearExpiry", func(t *testing.T) {
+		t.Parallel()
+		src := &fakeTokenSource{token: "tok", ttl: time.Second}
+		c := &RefreshableCredentials{Source: src, RefreshBefore: time.Hour}
+
+		if _, err := c.GetRequestMetadata(context.Background()); err != nil {
+			t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+		}
+		if _, err := c.GetRequestMetadata(context.Background()); err != nil {
+			t.Fatalf("GetRequestMetadata() err = %v; want nil", err)
+		}
+		if got, want := src.calls, int32(2); got != want {
+			t.Errorf("Source.Token called %d times; want %d", got, want)
+		}
+	})
+
+	t.Run("PropagatesSourceError", func(t *testing.T) {
+		t.Parallel()
+		src := &fakeTokenSource{err: errors.New("unavailable")}
+		c := &RefreshableCredentials{Source: src}
+		if _, err := c.GetRequestMetadata(context.Background()); err == nil {
+			t.Fatalf("GetRequestMetadata() err = nil; want non-nil")
+		}
+	})
+}
+
+func TestRefreshableCredentials_RequireTransportSecurity(t *testing.T) {
+	t.Parallel()
+	c := &RefreshableCredentials{}
+	if !c.RequireTransportSecurity() {
+		t.Errorf("RequireTransportSecurity() = false; want true")
+	}
+}
@@ -0,0 +1,54 @@
+package bearer
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verifier checks the signature and expiry of a JWT bearer token and
+// returns its claims. Implementations can be backed by a single static key,
+// a JWKS URL fetcher that looks up a key by its "kid" header, or -- in
+// tests -- a fake that accepts or rejects tokens unconditionally.
+type Verifier interface {
+	// Verify parses and validates tokenString, returning its claims if it
+	// has a valid signature and has not expired. The zero value of
+	// jwt.MapClaims is returned alongside any error.
+	Verify(tokenString string) (jwt.MapClaims, error)
+}
+
+// KeyfuncVerifier adapts a jwt.Keyfunc into a Verifier. AllowedMethods
+// restricts accepted tokens to the given algorithms (e.g. "HS256",
+// "RS256"), so that a token's header can't choose its own verification
+// algorithm.
+type KeyfuncVerifier struct {
+	Keyfunc        jwt.Keyfunc
+	AllowedMethods []string
+}
+
+var _ Verifier = KeyfuncVerifier{}
+
+func (v KeyfuncVerifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, v.Keyfunc, jwt.WithValidMethods(v.AllowedMethods))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("bearer: token is not valid")
+	}
+	return claims, nil
+}
+
+// NewStaticVerifier returns a Verifier that checks every token's signature
+// against a single, fixed key for the given signing method -- for example
+// jwt.SigningMethodHS256 with a []byte secret, or jwt.SigningMethodRS256
+// with an *rsa.PublicKey.
+func NewStaticVerifier(method jwt.SigningMethod, key any) Verifier {
+	return KeyfuncVerifier{
+		Keyfunc: func(*jwt.Token) (any, error) {
+			return key, nil
+		},
+		AllowedMethods: []string{method.Alg()},
+	}
+}
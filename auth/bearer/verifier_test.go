@@ -0,0 +1,95 @@
+package bearer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signedToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign test token: %v", err)
+	}
+	return signed
+}
+
+func TestStaticVerifier_HS256(t *testing.T) {
+	t.Parallel()
+	secret := []byte("super secret signing key")
+	v := NewStaticVerifier(jwt.SigningMethodHS256, secret)
+
+	token := signedToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify(valid token) err = %v; want nil", err)
+	}
+	if got, want := claims["sub"], "alice"; got != want {
+		t.Errorf(`claims["sub"] = %v; want %v`, got, want)
+	}
+}
+
+func TestStaticVerifier_RS256(t *testing.T) {
+	t.Parallel()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	v := NewStaticVerifier(jwt.SigningMethodRS256, &priv.PublicKey)
+
+	token := signedToken(t, jwt.SigningMethodRS256, priv, jwt.MapClaims{
+		"sub": "bob",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify(valid token) err = %v; want nil", err)
+	}
+	if got, want := claims["sub"], "bob"; got != want {
+		t.Errorf(`claims["sub"] = %v; want %v`, got, want)
+	}
+}
+
+func TestStaticVerifier_Rejects(t *testing.T) {
+	t.Parallel()
+	secret := []byte("super secret signing key")
+	wrongSecret := []byte("wrong secret")
+	v := NewStaticVerifier(jwt.SigningMethodHS256, secret)
+
+	for _, tt := range []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "Expired",
+			token: signedToken(t, jwt.SigningMethodHS256, secret, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		},
+		{
+			name:  "WrongKey",
+			token: signedToken(t, jwt.SigningMethodHS256, wrongSecret, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}),
+		},
+		{
+			name:  "WrongAlgorithm",
+			token: func() string { priv, _ := rsa.GenerateKey(rand.Reader, 2048); return signedToken(t, jwt.SigningMethodRS256, priv, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}) }(),
+		},
+		{
+			name:  "Malformed",
+			token: "not.a.jwt",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := v.Verify(tt.token); err == nil {
+				t.Errorf("Verify(%q) err = nil; want non-nil", tt.token)
+			}
+		})
+	}
+}
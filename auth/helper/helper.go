@@ -0,0 +1,103 @@
+// Package helper loads HTTP Basic credentials from Docker-style credential
+// helpers: a config.json naming, per server, either a single global
+// credsStore or a per-server entry in credHelpers, plus a
+// "docker-credential-<name>" binary on PATH implementing Docker's
+// credential-helper protocol (a "get" subcommand that reads a server URL on
+// stdin and writes {"ServerURL","Username","Secret"} JSON on stdout). This
+// lets a client reuse credentials a user already has configured for, say,
+// a container registry, instead of asking them to duplicate a
+// username/password into this program's own configuration.
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"go.saser.se/auth/n/basic"
+)
+
+// Config is the subset of Docker's config.json that's relevant to locating
+// credential helpers.
+type Config struct {
+	// CredsStore, if non-empty, names the credential helper binary (minus
+	// the "docker-credential-" prefix) used for every server that doesn't
+	// have a more specific entry in CredHelpers.
+	CredsStore string `json:"credsStore,omitempty"`
+	// CredHelpers maps a server URL to the credential helper binary (minus
+	// the "docker-credential-" prefix) used for that server specifically.
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+}
+
+// LoadConfig reads and parses the config.json file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("helper: load config %q: %w", path, err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("helper: load config %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// HelperFor returns the name of the credential helper binary (minus the
+// "docker-credential-" prefix) to use for serverURL, and whether one was
+// configured at all -- either specifically in CredHelpers, or via the
+// catch-all CredsStore.
+func (cfg *Config) HelperFor(serverURL string) (string, bool) {
+	if name, ok := cfg.CredHelpers[serverURL]; ok {
+		return name, true
+	}
+	if cfg.CredsStore != "" {
+		return cfg.CredsStore, true
+	}
+	return "", false
+}
+
+// credentialHelperResponse is the JSON object a credential helper's "get"
+// subcommand writes to stdout.
+type credentialHelperResponse struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// Get invokes the "docker-credential-<helperName>" binary's "get"
+// subcommand with serverURL on stdin, and parses the resulting credentials
+// from its stdout.
+func Get(ctx context.Context, helperName, serverURL string) (basic.Credentials, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helperName, "get")
+	cmd.Stdin = bytes.NewBufferString(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return basic.Credentials{}, fmt.Errorf("helper: run docker-credential-%s get: %w: %s", helperName, err, stderr.String())
+	}
+	var resp credentialHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return basic.Credentials{}, fmt.Errorf("helper: parse docker-credential-%s output: %w", helperName, err)
+	}
+	return basic.Credentials{Username: resp.Username, Password: resp.Secret}, nil
+}
+
+// CredentialsForServer loads the config.json file at configPath, finds the
+// credential helper configured for serverURL, and runs it to get
+// credentials for that server. It returns an error if no credential helper
+// is configured for serverURL.
+func CredentialsForServer(ctx context.Context, configPath, serverURL string) (basic.Credentials, error) {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return basic.Credentials{}, err
+	}
+	name, ok := cfg.HelperFor(serverURL)
+	if !ok {
+		return basic.Credentials{}, fmt.Errorf("helper: no credential helper configured for server %q in %q", serverURL, configPath)
+	}
+	return Get(ctx, name, serverURL)
+}
@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	path := writeConfig(t, `{"credsStore":"desktop","credHelpers":{"registry.example.com":"ecr-login"}}`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig(%q) err = %v; want nil", path, err)
+	}
+	if got, want := cfg.CredsStore, "desktop"; got != want {
+		t.Errorf("CredsStore = %q; want %q", got, want)
+	}
+	if got, want := cfg.CredHelpers["registry.example.com"], "ecr-login"; got != want {
+		t.Errorf(`CredHelpers["registry.example.com"] = %q; want %q`, got, want)
+	}
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	t.Parallel()
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "does-not-exist.json")); err == nil {
+		t.Fatalf("LoadConfig(missing) err = nil; want non-nil")
+	}
+}
+
+func TestConfig_HelperFor(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name      string
+		cfg       Config
+		serverURL string
+		want      string
+		wantOK    bool
+	}{
+		{
+			name:      "SpecificHelper",
+			cfg:       Config{CredsStore: "desktop", CredHelpers: map[string]string{"registry.example.com": "ecr-login"}},
+			serverURL: "registry.example.com",
+			want:      "ecr-login",
+			wantOK:    true,
+		},
+		{
+			name:      "FallsBackToCredsStore",
+			cfg:       Config{CredsStore: "desktop", CredHelpers: map[string]string{"registry.example.com": "ecr-login"}},
+			serverURL: "other.example.com",
+			want:      "desktop",
+			wantOK:    true,
+		},
+		{
+			name:      "NoneConfigured",
+			cfg:       Config{},
+			serverURL: "other.example.com",
+			wantOK:    false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := tt.cfg.HelperFor(tt.serverURL)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("HelperFor(%q) = (%q, %t); want (%q, %t)", tt.serverURL, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -6,44 +6,57 @@ import (
 
 	"go.saser.se/auth"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
-func Interceptor(username string, password string) (grpc.UnaryServerInterceptor, error) {
+// basicScheme implements auth.Scheme for a single, statically-configured
+// username/password pair.
+type basicScheme struct {
+	username string
+	password string
+}
+
+// Scheme returns an auth.Scheme that accepts HTTP Basic credentials
+// matching username and password, for use with auth.ChainInterceptor.
+// Both username and password must be non-empty.
+func Scheme(username, password string) (auth.Scheme, error) {
 	if username == "" {
-		return nil, errors.New("basic: interceptor: empty username")
+		return nil, errors.New("basic: scheme: empty username")
 	}
 	if password == "" {
-		return nil, errors.New("basic: interceptor: empty password")
+		return nil, errors.New("basic: scheme: empty password")
 	}
+	return &basicScheme{username: username, password: password}, nil
+}
 
-	interceptor := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "basic: no metadata in incoming context")
-		}
-		values := md.Get(auth.MetadataKey)
-		if got, want := len(values), 1; got != want {
-			return nil, status.Errorf(codes.Unauthenticated, "basic: metadata key %q has %d values; want exactly %d", auth.MetadataKey, got, want)
-		}
+func (s *basicScheme) Keyword() string { return "Basic" }
 
-		creds, err := Parse(values[0])
-		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "basic: metadata key %q does not have expected format %q", auth.MetadataKey, "Basic base64(username:password)")
-		}
+func (s *basicScheme) Authenticate(ctx context.Context, value string) (context.Context, error) {
+	creds, err := Parse(value)
+	if err != nil {
+		return nil, errors.New(`metadata does not have expected format "Basic base64(username:password)"`)
+	}
+	if creds.Username == "" {
+		return nil, errors.New("credentials contains empty username")
+	}
+	if creds.Password == "" {
+		return nil, errors.New("credentials contains empty password")
+	}
+	if creds.Username != s.username || creds.Password != s.password {
+		return nil, errors.New("credentials contain mismatched username and password")
+	}
+	return ctx, nil
+}
 
-		if creds.Username == "" {
-			return nil, status.Error(codes.Unauthenticated, "basic: credentials contains empty username")
-		}
-		if creds.Password == "" {
-			return nil, status.Error(codes.Unauthenticated, "basic: credentials contains empty password")
-		}
-		if creds.Username != username || creds.Password != password {
-			return nil, status.Error(codes.Unauthenticated, "basic: credentials contain mismatched username and password")
-		}
-		return handler(ctx, req)
+// Interceptor returns a gRPC unary server interceptor that requires every
+// request to carry HTTP Basic credentials in the "authorization" metadata
+// key (see auth.MetadataKey) matching username and password. On any
+// failure the interceptor returns codes.Unauthenticated. It is a thin
+// wrapper around Scheme and auth.ChainInterceptor, kept around for
+// backwards compatibility with callers that only need Basic auth.
+func Interceptor(username string, password string) (grpc.UnaryServerInterceptor, error) {
+	s, err := Scheme(username, password)
+	if err != nil {
+		return nil, err
 	}
-	return interceptor, nil
+	return auth.ChainInterceptor(s), nil
 }
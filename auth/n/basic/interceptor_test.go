@@ -2,7 +2,12 @@ package basic
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"go.saser.se/grpctest"
 	"go.saser.se/testing/echo"
@@ -12,6 +17,11 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// hammerN is the number of concurrent goroutines TestInterceptor_Hammer
+// hammers the interceptor with. It's a flag, rather than a constant, so that
+// it can be turned up when chasing a specific race without recompiling.
+var hammerN = flag.Int("hammer.n", 50, "number of concurrent goroutines to use in TestInterceptor_Hammer")
+
 func TestInterceptor(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -101,3 +111,96 @@ func TestInterceptor(t *testing.T) {
 		})
 	}
 }
+
+// TestInterceptor_Hammer launches many goroutines making concurrent Echo
+// calls through the interceptor, alternating between correct and
+// deliberately wrong credentials, to catch races in how the interceptor
+// resolves credentials for a given call. Every response is checked against
+// the message its own goroutine sent, so that a call getting its response
+// crossed with another goroutine's wouldn't go unnoticed.
+func TestInterceptor_Hammer(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		username = "alice"
+		password = "super secret"
+		rounds   = 20
+	)
+
+	i, err := Interceptor(username, password)
+	if err != nil {
+		t.Fatalf("Interceptor(%q, %q) err = %v; want nil", username, password, err)
+	}
+	srv := grpctest.New(ctx, t, grpctest.Options{
+		ServiceDesc:    &echopb.Echo_ServiceDesc,
+		Implementation: echo.Server{},
+
+		ServerOptions: []grpc.ServerOption{
+			grpc.UnaryInterceptor(i),
+		},
+	})
+	client := echopb.NewEchoClient(srv.ClientConn)
+
+	n := *hammerN
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+	)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	start := time.Now()
+	for g := 0; g < n; g++ {
+		g := g
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				creds := Credentials{Username: username, Password: password}
+				wantCode := codes.OK
+				if r%2 == 1 {
+					creds = Credentials{Username: username, Password: "wrong"}
+					wantCode = codes.Unauthenticated
+				}
+				msg := fmt.Sprintf("goroutine %d round %d", g, r)
+
+				callStart := time.Now()
+				res, err := client.Echo(ctx, &echopb.EchoRequest{Message: msg}, grpc.PerRPCCredentials(creds))
+				elapsed := time.Since(callStart)
+
+				mu.Lock()
+				latencies = append(latencies, elapsed)
+				mu.Unlock()
+
+				if got, want := status.Code(err), wantCode; got != want {
+					t.Errorf("goroutine %d round %d: Echo(%q) err = %v; want code %v", g, r, msg, err, want)
+					continue
+				}
+				if wantCode != codes.OK {
+					continue
+				}
+				if got := res.GetMessage(); got != msg {
+					t.Errorf("goroutine %d round %d: Echo(%q) returned %q; want %q (cross-talk between concurrent calls)", g, r, msg, got, msg)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	total := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	calls := n * rounds
+	rps := float64(calls) / total.Seconds()
+	t.Logf(
+		"hammer: %d goroutines x %d calls = %d calls in %v (%.1f rps), p50 = %v, p99 = %v",
+		n, rounds, calls, total, rps, percentile(latencies, 0.50), percentile(latencies, 0.99),
+	)
+}
+
+// percentile returns the p-th percentile (0 <= p <= 1) of a slice of
+// durations already sorted in ascending order.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
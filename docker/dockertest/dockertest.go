@@ -5,9 +5,17 @@
 package dockertest
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +30,12 @@ import (
 // top of it.
 type Pool struct {
 	*dockertest.Pool
+
+	// RegistryMirrors, if non-empty, are registry hosts that Pull tries, in
+	// order, before falling back to the registry implied by the image
+	// reference itself. Each mirror is tried by substituting it in as the
+	// reference's registry host.
+	RegistryMirrors []string
 }
 
 // NewPool initializes a new Docker client connection pool. Pass an empty string
@@ -33,7 +47,7 @@ func NewPool(tb testing.TB, endpoint string) *Pool {
 	if err != nil {
 		tb.Fatalf("dockertest: create pool with endpoint %q: %v", endpoint, err)
 	}
-	return &Pool{p}
+	return &Pool{Pool: p}
 }
 
 // Load takes a path to a tarball containing a Docker image and loads it into
@@ -70,6 +84,123 @@ func (p *Pool) Load(ctx context.Context, tb testing.TB, path string) string {
 	return name
 }
 
+// PullOptions contains the registry authentication options for [*Pool.Pull].
+// At most one of Username/Password, IdentityToken, or DockerConfigPath
+// should be set; if none are, the pull is attempted anonymously.
+type PullOptions struct {
+	// Username and Password authenticate against the image's registry with
+	// a plain username/password, as with `docker login -u -p`.
+	Username string
+	Password string
+	// IdentityToken authenticates using an OAuth2 identity token obtained
+	// from a previous registry login, instead of Username/Password.
+	IdentityToken string
+	// DockerConfigPath, if set, is the path to a docker CLI config file
+	// (e.g. ~/.docker/config.json) to resolve registry credentials from,
+	// including credentials backed by a configured credential helper. Used
+	// only if neither of the above is set.
+	DockerConfigPath string
+}
+
+// auth resolves the registry credentials to use for repo (the repository
+// part of an image reference, without the tag), based on how opts is
+// populated.
+func (opts PullOptions) auth(repo string) (docker.AuthConfiguration, error) {
+	switch {
+	case opts.Username != "" || opts.Password != "":
+		return docker.AuthConfiguration{Username: opts.Username, Password: opts.Password}, nil
+	case opts.IdentityToken != "":
+		return docker.AuthConfiguration{IdentityToken: opts.IdentityToken}, nil
+	case opts.DockerConfigPath != "":
+		configs, err := docker.NewAuthConfigurationsFromFile(opts.DockerConfigPath)
+		if err != nil {
+			return docker.AuthConfiguration{}, fmt.Errorf("reading docker config %q: %w", opts.DockerConfigPath, err)
+		}
+		return configs.Configs[registryHost(repo)], nil
+	default:
+		// NewAuthConfigurationsFromDockerCfg looks in the same places the
+		// docker CLI does (including invoking a configured credential
+		// helper). A missing config file just means no credentials are
+		// available, which is fine for public images.
+		configs, err := docker.NewAuthConfigurationsFromDockerCfg()
+		if err != nil {
+			return docker.AuthConfiguration{}, nil
+		}
+		return configs.Configs[registryHost(repo)], nil
+	}
+}
+
+// registryHost returns the registry hostname that repo (the repository part
+// of an image reference) is pulled from, defaulting to Docker Hub's
+// registry key for unqualified references like "postgres".
+func registryHost(repo string) string {
+	host, _, ok := strings.Cut(repo, "/")
+	if ok && (strings.Contains(host, ".") || strings.Contains(host, ":") || host == "localhost") {
+		return host
+	}
+	return "https://index.docker.io/v1/"
+}
+
+// splitRepoTag splits an image reference like "postgres:16-alpine" into its
+// repository ("postgres") and tag ("16-alpine") parts. A reference with no
+// tag gets the implicit tag "latest".
+func splitRepoTag(ref string) (repo, tag string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || strings.Contains(ref[i+1:], "/") {
+		return ref, "latest"
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// withHost returns ref with its registry host replaced by host.
+func withHost(ref, host string) string {
+	repo, tag := splitRepoTag(ref)
+	if _, rest, ok := strings.Cut(repo, "/"); ok {
+		repo = rest
+	}
+	return host + "/" + repo + ":" + tag
+}
+
+// Pull pulls ref (e.g. "postgres:16-alpine") from its registry into the
+// Docker daemon using the Engine API's image-pull endpoint, and returns the
+// image name. Unlike Load, Pull talks to the registry directly instead of
+// requiring the image to be pre-built into a tarball, so tests can
+// reference upstream images by name.
+//
+// If p.RegistryMirrors is non-empty, each mirror is tried, in order, before
+// falling back to ref's own registry.
+func (p *Pool) Pull(ctx context.Context, tb testing.TB, ref string, opts PullOptions) string {
+	tb.Helper()
+
+	candidates := make([]string, 0, len(p.RegistryMirrors)+1)
+	for _, mirror := range p.RegistryMirrors {
+		candidates = append(candidates, withHost(ref, mirror))
+	}
+	candidates = append(candidates, ref)
+
+	var lastErr error
+	for _, candidate := range candidates {
+		repo, tag := splitRepoTag(candidate)
+		auth, err := opts.auth(repo)
+		if err != nil {
+			tb.Fatalf("dockertest: pull: resolve credentials for %q: %v", candidate, err)
+		}
+		var out strings.Builder
+		err = p.Client.PullImage(docker.PullImageOptions{
+			Context:      ctx,
+			Repository:   repo,
+			Tag:          tag,
+			OutputStream: &out,
+		}, auth)
+		if err == nil {
+			return candidate
+		}
+		lastErr = fmt.Errorf("pull %q: %w (output: %q)", candidate, err, out.String())
+	}
+	tb.Fatalf("dockertest: pull: failed to pull %q (tried %v): %v", ref, candidates, lastErr)
+	return ""
+}
+
 // RunOptions contains the options for [*Pool.Run].
 type RunOptions struct {
 	// The image to run a container with. Required.
@@ -85,6 +216,43 @@ type RunOptions struct {
 	// Whether to keep the container running after the test ends. Optional. If
 	// this is set to true then KeepContainer must also be set to true.
 	KeepRunning bool
+	// Cmd overrides the command the container is started with. Optional; if
+	// empty, the image's own entrypoint/command is used unmodified.
+	Cmd []string
+	// Mounts are bind mounts from the host into the container, each in
+	// Docker's "host-path:container-path[:ro]" format. Optional.
+	Mounts []string
+	// WaitFor, if set, determines when the container is considered ready:
+	// Run blocks on it after starting the container, and fails the test if
+	// it returns an error or ctx is done first. Optional; if nil, Run
+	// returns as soon as the container has been started. See
+	// WaitForListeningPort, WaitForHTTP, WaitForLog, WaitForSQL, and
+	// WaitForHealthcheck.
+	WaitFor WaitStrategy
+	// Reuse, if true, makes Run look for an already-running container
+	// previously started with the same Image, Environment, Mounts, and
+	// ReuseKey (identified by a content hash stored in a Docker label)
+	// before creating a new one. If one is found, Run returns its ID
+	// directly, without registering any stop/remove cleanup for it. If
+	// none is found, Run creates a new container as usual, but labels it
+	// for future reuse and forces KeepContainer and KeepRunning to true,
+	// since a container that gets removed at the end of the test can never
+	// be reused. Use Prune to clean up containers left behind this way.
+	Reuse bool
+	// ReuseKey further distinguishes containers for Reuse beyond Image,
+	// Environment, and Mounts, for cases where those alone don't uniquely
+	// identify what the container is for (e.g. two tests that happen to
+	// start the same image with the same environment but need isolated
+	// instances). Optional; ignored unless Reuse is true.
+	ReuseKey string
+}
+
+// WaitStrategy determines when a container started by [*Pool.Run] should be
+// considered ready.
+type WaitStrategy interface {
+	// WaitUntilReady blocks until the container with the given ID is ready,
+	// or ctx is done, whichever happens first.
+	WaitUntilReady(ctx context.Context, p *Pool, containerID string) error
 }
 
 // Run starts a container and keeps it alive for the duration of the test. Opts
@@ -107,17 +275,45 @@ func (p *Pool) Run(ctx context.Context, tb testing.TB, opts RunOptions) string {
 		tb.Fatal("dockertest: run: if KeepRunning is true then KeepContainer must also be true.")
 	}
 
+	var hash string
+	if opts.Reuse {
+		hash = reuseHash(opts)
+		containers, err := p.Client.ListContainers(docker.ListContainersOptions{
+			Context: ctx,
+			All:     true,
+			Filters: map[string][]string{"label": {reuseLabelKey + "=" + hash}},
+		})
+		if err != nil {
+			tb.Fatalf("dockertest: run: reuse: list containers: %v", err)
+		}
+		for _, c := range containers {
+			if c.State == "running" {
+				return c.ID
+			}
+		}
+		// No running container to reuse: fall through and create one
+		// below, but make sure it survives this test so it can actually
+		// be reused later.
+		opts.KeepContainer = true
+		opts.KeepRunning = true
+	}
+
 	// First, create the container.
 	contCfg := &docker.Config{
 		Image: opts.Image,
+		Cmd:   opts.Cmd,
 	}
 	for k, v := range opts.Environment {
 		contCfg.Env = append(contCfg.Env, k+"="+v)
 	}
+	if opts.Reuse {
+		contCfg.Labels = map[string]string{reuseLabelKey: hash}
+	}
 	hostCfg := &docker.HostConfig{
 		// As a sane default, always publish all ports. This can be revisited
 		// later if needed.
 		PublishAllPorts: true,
+		Binds:           opts.Mounts,
 	}
 	cont, err := p.Client.CreateContainer(docker.CreateContainerOptions{
 		Context:    ctx,
@@ -145,6 +341,13 @@ func (p *Pool) Run(ctx context.Context, tb testing.TB, opts RunOptions) string {
 	if err := p.Client.StartContainerWithContext(cont.ID, nil, ctx); err != nil {
 		tb.Fatalf("dockertest: run: start container: %v", err)
 	}
+	// If a wait strategy was given, block until it says the container is
+	// ready (or ctx/the test deadline is hit) before returning.
+	if opts.WaitFor != nil {
+		if err := opts.WaitFor.WaitUntilReady(ctx, p, cont.ID); err != nil {
+			tb.Fatalf("dockertest: run: wait for container to be ready: %v", err)
+		}
+	}
 	// Unless opts.KeepRunning is true, stop the container when the test ends.
 	if !opts.KeepRunning {
 		tb.Cleanup(func() {
@@ -161,23 +364,86 @@ func (p *Pool) Run(ctx context.Context, tb testing.TB, opts RunOptions) string {
 	return cont.ID
 }
 
-// Address returns the address (hostname/IP and port) on the host that the given
-// container port is bound to. The port should be given in Docker's
-// "number/protocol" format. For example, if the container image exposes port
-// 5432 over TCP, the host IP is "0.0.0.0", and the port on the host is 1337,
-// port should be given as "5432/tcp" and Address will return "0.0.0.0:1337".
-func (p *Pool) Address(ctx context.Context, tb testing.TB, id string, port string) string {
-	tb.Helper()
+// reuseLabelKey is the Docker label key set on containers started with
+// RunOptions.Reuse, so Run can find them again and Prune can find them for
+// cleanup.
+const reuseLabelKey = "saser.dockertest.reuse-hash"
+
+// reuseHash returns a deterministic hash of the parts of opts that
+// determine whether a container can be reused for it: the image, the
+// environment, the mounts, and the caller-supplied ReuseKey.
+func reuseHash(opts RunOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "image=%s\n", opts.Image)
+	envKeys := make([]string, 0, len(opts.Environment))
+	for k := range opts.Environment {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	for _, k := range envKeys {
+		fmt.Fprintf(h, "env=%s=%s\n", k, opts.Environment[k])
+	}
+	mounts := append([]string(nil), opts.Mounts...)
+	sort.Strings(mounts)
+	for _, m := range mounts {
+		fmt.Fprintf(h, "mount=%s\n", m)
+	}
+	fmt.Fprintf(h, "reusekey=%s\n", opts.ReuseKey)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Prune removes containers previously started with RunOptions.Reuse set
+// (identified by the reuseLabelKey label) that were created more than
+// olderThan ago, stopping them first if still running. It's meant to be
+// invoked from a standalone cleanup command between test runs, not from the
+// tests themselves.
+func Prune(ctx context.Context, olderThan time.Duration) error {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return fmt.Errorf("dockertest: prune: create pool: %w", err)
+	}
+	containers, err := pool.Client.ListContainers(docker.ListContainersOptions{
+		Context: ctx,
+		All:     true,
+		Filters: map[string][]string{"label": {reuseLabelKey}},
+	})
+	if err != nil {
+		return fmt.Errorf("dockertest: prune: list containers: %w", err)
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var errs []error
+	for _, c := range containers {
+		if time.Unix(c.Created, 0).After(cutoff) {
+			continue
+		}
+		if c.State == "running" {
+			if err := pool.Client.StopContainerWithContext(c.ID, uint(time.Minute.Seconds()), ctx); err != nil {
+				errs = append(errs, fmt.Errorf("stop container %s: %w", c.ID, err))
+				continue
+			}
+		}
+		if err := pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: c.ID, Context: ctx}); err != nil {
+			errs = append(errs, fmt.Errorf("remove container %s: %w", c.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	// It seems that in testing this operation sometimes fails if it is
-	// executed too soon after the container has been created. Therefore, we
-	// execute the binding lookup with exponential backoff on errors, to
-	// increase its reliability. It should rarely matter in practice.
+// errNoPortBindings is returned by portBinding when the container exists but
+// has no bindings yet for the requested port.
+var errNoPortBindings = errors.New("less than one binding")
 
+// portBinding waits for and returns the host address bound to the given
+// container port (in Docker's "number/protocol" format, e.g. "5432/tcp").
+//
+// It seems that in testing this operation sometimes fails if it is executed
+// too soon after the container has been created. Therefore, we execute the
+// binding lookup with exponential backoff on errors, to increase its
+// reliability. It should rarely matter in practice.
+func portBinding(ctx context.Context, p *Pool, containerID, port string) (string, error) {
 	var bindings []docker.PortBinding
-	noBindings := errors.New("less than one binding")
 	op := backoff.Operation(func() error {
-		info, err := p.Client.InspectContainerWithContext(id, ctx)
+		info, err := p.Client.InspectContainerWithContext(containerID, ctx)
 		if err != nil {
 			return err
 		}
@@ -186,17 +452,223 @@ func (p *Pool) Address(ctx context.Context, tb testing.TB, id string, port strin
 		}
 		bindings = info.NetworkSettings.Ports[docker.Port(port)]
 		if len(bindings) == 0 {
-			return &backoff.PermanentError{Err: noBindings}
+			return &backoff.PermanentError{Err: errNoPortBindings}
 		}
 		return nil
 	})
 	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
-		if errors.Is(err, noBindings) {
+		return "", err
+	}
+	b := bindings[0]
+	return net.JoinHostPort(b.HostIP, b.HostPort), nil
+}
+
+// Address returns the address (hostname/IP and port) on the host that the given
+// container port is bound to. The port should be given in Docker's
+// "number/protocol" format. For example, if the container image exposes port
+// 5432 over TCP, the host IP is "0.0.0.0", and the port on the host is 1337,
+// port should be given as "5432/tcp" and Address will return "0.0.0.0:1337".
+func (p *Pool) Address(ctx context.Context, tb testing.TB, id string, port string) string {
+	tb.Helper()
+	addr, err := portBinding(ctx, p, id, port)
+	if err != nil {
+		if errors.Is(err, errNoPortBindings) {
 			tb.Fatalf("dockertest: address: container %v does not have any port bindings for %q", id, port)
 		}
 		tb.Fatalf("dockertest: address: port %q is not exposed by container %v", port, id)
 	}
+	return addr
+}
 
-	b := bindings[0]
-	return net.JoinHostPort(b.HostIP, b.HostPort)
+// WaitForListeningPort returns a WaitStrategy that's ready once the
+// container has a host port binding for port (Docker's "number/protocol"
+// format, e.g. "5432/tcp") and a TCP connection to it succeeds.
+func WaitForListeningPort(port string) WaitStrategy {
+	return waitForListeningPort{port: port}
+}
+
+type waitForListeningPort struct {
+	port string
+}
+
+func (w waitForListeningPort) WaitUntilReady(ctx context.Context, p *Pool, containerID string) error {
+	addr, err := portBinding(ctx, p, containerID, w.port)
+	if err != nil {
+		return fmt.Errorf("wait for listening port %q: %w", w.port, err)
+	}
+	op := backoff.Operation(func() error {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return fmt.Errorf("wait for listening port %q at %s: %w", w.port, addr, err)
+	}
+	return nil
+}
+
+// WaitForHTTP returns a WaitStrategy that's ready once an HTTP GET to path
+// on port (Docker's "number/protocol" format, e.g. "80/tcp") succeeds and
+// returns a status code for which statusPredicate returns true.
+func WaitForHTTP(port, path string, statusPredicate func(int) bool) WaitStrategy {
+	return waitForHTTP{port: port, path: path, predicate: statusPredicate}
+}
+
+type waitForHTTP struct {
+	port      string
+	path      string
+	predicate func(int) bool
+}
+
+func (w waitForHTTP) WaitUntilReady(ctx context.Context, p *Pool, containerID string) error {
+	addr, err := portBinding(ctx, p, containerID, w.port)
+	if err != nil {
+		return fmt.Errorf("wait for http %s%s: %w", w.port, w.path, err)
+	}
+	url := "http://" + addr + w.path
+	op := backoff.Operation(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return &backoff.PermanentError{Err: err}
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if !w.predicate(res.StatusCode) {
+			return fmt.Errorf("status code %d did not satisfy the predicate", res.StatusCode)
+		}
+		return nil
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return fmt.Errorf("wait for http %q: %w", url, err)
+	}
+	return nil
+}
+
+// WaitForLog returns a WaitStrategy that's ready once the container's
+// combined stdout/stderr log output matches pattern.
+func WaitForLog(pattern *regexp.Regexp) WaitStrategy {
+	return waitForLog{pattern: pattern}
+}
+
+type waitForLog struct {
+	pattern *regexp.Regexp
+}
+
+func (w waitForLog) WaitUntilReady(ctx context.Context, p *Pool, containerID string) error {
+	op := backoff.Operation(func() error {
+		var buf bytes.Buffer
+		err := p.Client.Logs(docker.LogsOptions{
+			Context:      ctx,
+			Container:    containerID,
+			OutputStream: &buf,
+			ErrorStream:  &buf,
+			Stdout:       true,
+			Stderr:       true,
+		})
+		if err != nil {
+			return err
+		}
+		if !w.pattern.Match(buf.Bytes()) {
+			return fmt.Errorf("log output did not match pattern %q", w.pattern)
+		}
+		return nil
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return fmt.Errorf("wait for log matching %q: %w", w.pattern, err)
+	}
+	return nil
+}
+
+// WaitForSQL returns a WaitStrategy that's ready once a *sql.DB, opened with
+// driver and the DSN returned by dsnFn (given the container's host address
+// for port, in Docker's "number/protocol" format), successfully pings the
+// database. The driver must already be registered with database/sql, e.g.
+// via a blank import of its package.
+func WaitForSQL(port, driver string, dsnFn func(addr string) string) WaitStrategy {
+	return waitForSQL{port: port, driver: driver, dsnFn: dsnFn}
+}
+
+type waitForSQL struct {
+	port   string
+	driver string
+	dsnFn  func(addr string) string
+}
+
+func (w waitForSQL) WaitUntilReady(ctx context.Context, p *Pool, containerID string) error {
+	addr, err := portBinding(ctx, p, containerID, w.port)
+	if err != nil {
+		return fmt.Errorf("wait for sql on port %q: %w", w.port, err)
+	}
+	db, err := sql.Open(w.driver, w.dsnFn(addr))
+	if err != nil {
+		return fmt.Errorf("wait for sql: open %q: %w", w.driver, err)
+	}
+	defer db.Close()
+	op := backoff.Operation(func() error {
+		return db.PingContext(ctx)
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return fmt.Errorf("wait for sql: ping %q: %w", w.driver, err)
+	}
+	return nil
+}
+
+// WaitForHealthcheck returns a WaitStrategy that's ready once the
+// container's own Docker healthcheck (as configured on its image, or
+// overridden at run time) reports status "healthy". The container must
+// have a healthcheck configured, or WaitUntilReady fails immediately.
+func WaitForHealthcheck() WaitStrategy {
+	return waitForHealthcheck{}
+}
+
+type waitForHealthcheck struct{}
+
+func (waitForHealthcheck) WaitUntilReady(ctx context.Context, p *Pool, containerID string) error {
+	op := backoff.Operation(func() error {
+		info, err := p.Client.InspectContainerWithContext(containerID, ctx)
+		if err != nil {
+			return err
+		}
+		if info.State.Health.Status == "" {
+			return &backoff.PermanentError{Err: errors.New("container has no healthcheck configured")}
+		}
+		if info.State.Health.Status != "healthy" {
+			return fmt.Errorf("healthcheck status is %q", info.State.Health.Status)
+		}
+		return nil
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return fmt.Errorf("wait for healthcheck: %w", err)
+	}
+	return nil
+}
+
+// ContainerIP returns the container's own IP address on the Docker network,
+// as opposed to Address, which returns the address the container's port is
+// published to on the host. This is useful for wiring up containers that
+// need to address each other directly, such as a replica set or cluster.
+func (p *Pool) ContainerIP(ctx context.Context, tb testing.TB, id string) string {
+	tb.Helper()
+
+	var ip string
+	op := backoff.Operation(func() error {
+		info, err := p.Client.InspectContainerWithContext(id, ctx)
+		if err != nil {
+			return err
+		}
+		if info.NetworkSettings == nil || info.NetworkSettings.IPAddress == "" {
+			return errors.New("container has no IP address yet")
+		}
+		ip = info.NetworkSettings.IPAddress
+		return nil
+	})
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		tb.Fatalf("dockertest: container ip: container %v has no IP address on the Docker network: %v", id, err)
+	}
+	return ip
 }
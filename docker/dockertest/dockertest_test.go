@@ -25,6 +25,18 @@ func TestPool_Load(t *testing.T) {
 	}
 }
 
+func TestPool_Pull(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	p := NewPool(t, "")
+	got := p.Pull(ctx, t, "hello-world:latest", PullOptions{})
+	want := "hello-world:latest"
+	if got != want {
+		t.Errorf("Pull(%q) = %q; want %q", "hello-world:latest", got, want)
+	}
+}
+
 func TestPool_Run(t *testing.T) {
 	t.Parallel()
 	ctx := t.Context()
@@ -40,6 +52,143 @@ func TestPool_Run(t *testing.T) {
 	}
 }
 
+func TestReuseHash(t *testing.T) {
+	base := RunOptions{
+		Image:       "postgres:16-alpine",
+		Environment: map[string]string{"POSTGRES_PASSWORD": "secret", "POSTGRES_USER": "me"},
+		Mounts:      []string{"/host/data:/var/lib/postgresql/data"},
+		ReuseKey:    "main",
+	}
+
+	// Hashing must not depend on map iteration order.
+	same := base
+	same.Environment = map[string]string{"POSTGRES_USER": "me", "POSTGRES_PASSWORD": "secret"}
+	if got, want := reuseHash(same), reuseHash(base); got != want {
+		t.Errorf("reuseHash with reordered environment = %q; want %q (same as base)", got, want)
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts RunOptions
+	}{
+		{name: "DifferentImage", opts: func() RunOptions { o := base; o.Image = "postgres:15-alpine"; return o }()},
+		{name: "DifferentEnv", opts: func() RunOptions { o := base; o.Environment = map[string]string{"POSTGRES_USER": "someone-else"}; return o }()},
+		{name: "DifferentMounts", opts: func() RunOptions { o := base; o.Mounts = nil; return o }()},
+		{name: "DifferentReuseKey", opts: func() RunOptions { o := base; o.ReuseKey = "other"; return o }()},
+	} {
+		if got, notWant := reuseHash(tt.opts), reuseHash(base); got == notWant {
+			t.Errorf("%s: reuseHash(%+v) = %q; want a different hash than base", tt.name, tt.opts, got)
+		}
+	}
+}
+
+func TestPool_Run_Reuse(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	p := NewPool(t, "")
+	opts := RunOptions{
+		// nginx, unlike hello-world, keeps running after it starts, which
+		// is required for the second Run call below to find it still
+		// alive and reuse it.
+		Image:    p.Load(ctx, t, nginx),
+		Reuse:    true,
+		ReuseKey: t.Name(),
+	}
+	first := p.Run(ctx, t, opts)
+	second := p.Run(ctx, t, opts)
+	if first != second {
+		t.Errorf("Run(%+v) = %q, then %q; want the second call to reuse the first container", opts, first, second)
+	}
+}
+
+func TestSplitRepoTag(t *testing.T) {
+	for _, tt := range []struct {
+		ref      string
+		wantRepo string
+		wantTag  string
+	}{
+		{ref: "postgres:16-alpine", wantRepo: "postgres", wantTag: "16-alpine"},
+		{ref: "postgres", wantRepo: "postgres", wantTag: "latest"},
+		{ref: "registry:5000/postgres", wantRepo: "registry:5000/postgres", wantTag: "latest"},
+		{ref: "registry:5000/postgres:16-alpine", wantRepo: "registry:5000/postgres", wantTag: "16-alpine"},
+	} {
+		repo, tag := splitRepoTag(tt.ref)
+		if repo != tt.wantRepo || tag != tt.wantTag {
+			t.Errorf("splitRepoTag(%q) = (%q, %q); want (%q, %q)", tt.ref, repo, tag, tt.wantRepo, tt.wantTag)
+		}
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	for _, tt := range []struct {
+		repo string
+		want string
+	}{
+		{repo: "postgres", want: "https://index.docker.io/v1/"},
+		{repo: "library/postgres", want: "https://index.docker.io/v1/"},
+		{repo: "ghcr.io/owner/postgres", want: "ghcr.io"},
+		{repo: "registry:5000/postgres", want: "registry:5000"},
+		{repo: "localhost/postgres", want: "localhost"},
+	} {
+		if got := registryHost(tt.repo); got != tt.want {
+			t.Errorf("registryHost(%q) = %q; want %q", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestWithHost(t *testing.T) {
+	for _, tt := range []struct {
+		ref  string
+		host string
+		want string
+	}{
+		{ref: "postgres:16-alpine", host: "mirror.example.com", want: "mirror.example.com/postgres:16-alpine"},
+		{ref: "ghcr.io/owner/postgres:16-alpine", host: "mirror.example.com", want: "mirror.example.com/owner/postgres:16-alpine"},
+	} {
+		if got := withHost(tt.ref, tt.host); got != tt.want {
+			t.Errorf("withHost(%q, %q) = %q; want %q", tt.ref, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestPool_Run_WaitForListeningPort(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	p := NewPool(t, "")
+	opts := RunOptions{
+		Image:   p.Load(ctx, t, nginx),
+		WaitFor: WaitForListeningPort("80/tcp"),
+	}
+	// Run blocks until WaitForListeningPort succeeds, so if it returns at
+	// all, the container is already accepting TCP connections on port 80.
+	id := p.Run(ctx, t, opts)
+	if id == "" {
+		t.Errorf("Run(%+v) returned an empty string", opts)
+	}
+}
+
+func TestPool_Run_WaitForHTTP(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	p := NewPool(t, "")
+	opts := RunOptions{
+		Image: p.Load(ctx, t, nginx),
+		WaitFor: WaitForHTTP("80/tcp", "/", func(code int) bool {
+			return code == http.StatusOK
+		}),
+	}
+	id := p.Run(ctx, t, opts)
+	addr := p.Address(ctx, t, id, "80/tcp")
+	res, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("http.Get err = %v; want nil", err)
+	}
+	defer res.Body.Close()
+	if got, want := res.StatusCode, http.StatusOK; got != want {
+		t.Errorf("http.Get code = %v; want %v", got, want)
+	}
+}
+
 func TestPool_Address(t *testing.T) {
 	t.Parallel()
 	ctx := t.Context()
@@ -0,0 +1,230 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// ServiceSpec declaratively describes one container in a Stack.
+type ServiceSpec struct {
+	// Image is the image to run the service with. Required.
+	Image string
+	// Env are environment variables the container is started with.
+	// Optional.
+	Env map[string]string
+	// Ports are the container ports (Docker's "number/protocol" format,
+	// e.g. "5432/tcp") that the service listens on. Optional; only
+	// relevant if the service needs to be reached via [*Stack.Address].
+	Ports []string
+	// DependsOn lists the names of other services in the same Stack that
+	// must be up (started, and past their own WaitFor, if any) before this
+	// service is started. Optional.
+	DependsOn []string
+	// WaitFor, if set, determines when this service is considered ready:
+	// Up blocks on it before starting any services that DependsOn it, and
+	// before returning if nothing depends on it either. Optional.
+	WaitFor WaitStrategy
+	// Mounts are bind mounts from the host into the container, each in
+	// Docker's "host-path:container-path[:ro]" format. Optional.
+	Mounts []string
+}
+
+// Stack is a set of named services, started together by [*Pool.Up] on a
+// shared per-test Docker network, honoring inter-service dependencies. It
+// turns the Pool's one-container-at-a-time Run into something that can
+// express a multi-container topology (e.g. an app, a database, and a cache)
+// as a single fixture.
+type Stack struct {
+	pool        *Pool
+	networkID   string
+	networkName string
+	// containerIDs maps service name to container ID, for services that
+	// have successfully started.
+	containerIDs map[string]string
+}
+
+// Up creates a dedicated Docker network and starts every service named in
+// specs on it, in an order that honors each service's DependsOn, waiting on
+// a service's WaitFor (if any) before starting any services that depend on
+// it. It registers tb.Cleanup to tear down the containers and the network,
+// in reverse startup order, when the test ends.
+//
+// Service names are used both as DependsOn references within specs and as
+// the services' hostnames on the stack's network; see
+// [*Stack.InternalHostname].
+func (p *Pool) Up(ctx context.Context, tb testing.TB, specs map[string]ServiceSpec) *Stack {
+	tb.Helper()
+
+	order, err := topoSort(specs)
+	if err != nil {
+		tb.Fatalf("dockertest: up: %v", err)
+	}
+
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	networkName := fmt.Sprintf("dockertest-%d", time.Now().UnixNano())
+	network, err := p.Client.CreateNetwork(docker.CreateNetworkOptions{
+		Context: ctx,
+		Name:    networkName,
+	})
+	if err != nil {
+		tb.Fatalf("dockertest: up: create network %q: %v", networkName, err)
+	}
+	tb.Cleanup(func() {
+		if err := p.Client.RemoveNetwork(network.ID); err != nil {
+			tb.Errorf("dockertest: up: remove network %q after test: %v", networkName, err)
+		}
+	})
+
+	s := &Stack{
+		pool:         p,
+		networkID:    network.ID,
+		networkName:  networkName,
+		containerIDs: make(map[string]string, len(specs)),
+	}
+
+	for _, name := range order {
+		// Capture per-iteration copies: this package supports Go versions
+		// where range variables are reused across iterations, and these
+		// are closed over by the tb.Cleanup funcs below.
+		name := name
+		spec := specs[name]
+		if spec.Image == "" {
+			tb.Fatalf("dockertest: up: service %q: image is required.", name)
+		}
+
+		contCfg := &docker.Config{
+			Image: spec.Image,
+		}
+		for k, v := range spec.Env {
+			contCfg.Env = append(contCfg.Env, k+"="+v)
+		}
+		hostCfg := &docker.HostConfig{
+			PublishAllPorts: true,
+			Binds:           spec.Mounts,
+		}
+		netCfg := &docker.NetworkingConfig{
+			EndpointsConfig: map[string]*docker.EndpointConfig{
+				networkName: {
+					NetworkID: network.ID,
+					Aliases:   []string{name},
+				},
+			},
+		}
+		cont, err := p.Client.CreateContainer(docker.CreateContainerOptions{
+			Context:          ctx,
+			Name:             networkName + "-" + name,
+			Config:           contCfg,
+			HostConfig:       hostCfg,
+			NetworkingConfig: netCfg,
+		})
+		if err != nil {
+			tb.Fatalf("dockertest: up: service %q: create container: %v", name, err)
+		}
+		contID := cont.ID
+		tb.Cleanup(func() {
+			if err := p.Client.RemoveContainer(docker.RemoveContainerOptions{
+				ID:      contID,
+				Context: cleanupCtx,
+			}); err != nil {
+				tb.Errorf("dockertest: up: service %q: remove container after test: %v", name, err)
+			}
+		})
+
+		if err := p.Client.StartContainerWithContext(contID, nil, ctx); err != nil {
+			tb.Fatalf("dockertest: up: service %q: start container: %v", name, err)
+		}
+		tb.Cleanup(func() {
+			err := p.Client.StopContainerWithContext(contID, uint(time.Minute.Seconds()), cleanupCtx)
+			if e := new(docker.ContainerNotRunning); errors.As(err, &e) {
+				return
+			}
+			if err != nil {
+				tb.Errorf("dockertest: up: service %q: stop container after test: %v", name, err)
+			}
+		})
+
+		if spec.WaitFor != nil {
+			if err := spec.WaitFor.WaitUntilReady(ctx, p, contID); err != nil {
+				tb.Fatalf("dockertest: up: service %q: wait for container to be ready: %v", name, err)
+			}
+		}
+
+		s.containerIDs[name] = contID
+	}
+
+	return s
+}
+
+// Address returns the address (hostname/IP and port) on the host that the
+// given service's container port is bound to. port should be given in
+// Docker's "number/protocol" format, e.g. "5432/tcp". It fails the test if
+// service wasn't named in the specs passed to [*Pool.Up].
+func (s *Stack) Address(tb testing.TB, service, port string) string {
+	tb.Helper()
+	id, ok := s.containerIDs[service]
+	if !ok {
+		tb.Fatalf("dockertest: stack: address: no such service %q", service)
+	}
+	return s.pool.Address(context.Background(), tb, id, port)
+}
+
+// InternalHostname returns the hostname that other services on the same
+// Stack can use to reach service, over the stack's Docker network. It does
+// not resolve from the host itself; use [*Stack.Address] for that.
+func (s *Stack) InternalHostname(service string) string {
+	return service
+}
+
+// topoSort returns the names in specs ordered so that every service comes
+// after everything in its DependsOn, or an error if a dependency is missing
+// or a cycle exists.
+func topoSort(specs map[string]ServiceSpec) ([]string, error) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic order among services with no ordering constraints
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(names))
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v -> %v", path, name)
+		}
+		spec, ok := specs[name]
+		if !ok {
+			return fmt.Errorf("unknown service %q depended on by %v", name, path)
+		}
+		state[name] = visiting
+		for _, dep := range spec.DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
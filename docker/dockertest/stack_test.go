@@ -0,0 +1,111 @@
+package dockertest
+
+import (
+	"testing"
+)
+
+func TestTopoSort(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		specs map[string]ServiceSpec
+		want  []string // nil means an error is expected
+	}{
+		{
+			name: "NoDependencies",
+			specs: map[string]ServiceSpec{
+				"a": {Image: "a"},
+				"b": {Image: "b"},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "LinearChain",
+			specs: map[string]ServiceSpec{
+				"app": {Image: "app", DependsOn: []string{"db"}},
+				"db":  {Image: "db"},
+			},
+			want: []string{"db", "app"},
+		},
+		{
+			name: "Diamond",
+			specs: map[string]ServiceSpec{
+				"app":     {Image: "app", DependsOn: []string{"cache", "db"}},
+				"db":      {Image: "db", DependsOn: []string{"network"}},
+				"cache":   {Image: "cache", DependsOn: []string{"network"}},
+				"network": {Image: "network"},
+			},
+			want: []string{"network", "cache", "db", "app"},
+		},
+		{
+			name: "MissingDependency",
+			specs: map[string]ServiceSpec{
+				"app": {Image: "app", DependsOn: []string{"db"}},
+			},
+			want: nil,
+		},
+		{
+			name: "Cycle",
+			specs: map[string]ServiceSpec{
+				"a": {Image: "a", DependsOn: []string{"b"}},
+				"b": {Image: "b", DependsOn: []string{"a"}},
+			},
+			want: nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := topoSort(tt.specs)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("topoSort(%v) = %v, nil; want an error", tt.specs, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("topoSort(%v) err = %v; want nil", tt.specs, err)
+			}
+			// Verify the result is a valid topological order: every service
+			// must appear after everything in its DependsOn. This is
+			// checked directly, rather than comparing against tt.want,
+			// because services with no ordering constraints between them
+			// (e.g. "a" and "b" in NoDependencies) may legitimately come
+			// out in either relative order.
+			index := make(map[string]int, len(got))
+			for i, name := range got {
+				index[name] = i
+			}
+			if len(got) != len(tt.specs) {
+				t.Fatalf("topoSort(%v) = %v; want a permutation of %v", tt.specs, got, tt.want)
+			}
+			for name, spec := range tt.specs {
+				for _, dep := range spec.DependsOn {
+					if index[dep] >= index[name] {
+						t.Errorf("topoSort(%v) = %v; %q must come before %q", tt.specs, got, dep, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPool_Up(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	p := NewPool(t, "")
+
+	specs := map[string]ServiceSpec{
+		"web": {
+			Image:     p.Load(ctx, t, nginx),
+			Ports:     []string{"80/tcp"},
+			DependsOn: nil,
+		},
+	}
+	s := p.Up(ctx, t, specs)
+
+	addr := s.Address(t, "web", "80/tcp")
+	if addr == "" {
+		t.Fatalf("Address(%q, %q) returned an empty string", "web", "80/tcp")
+	}
+	if got, want := s.InternalHostname("web"), "web"; got != want {
+		t.Errorf("InternalHostname(%q) = %q; want %q", "web", got, want)
+	}
+}
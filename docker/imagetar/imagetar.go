@@ -4,70 +4,848 @@ package imagetar
 
 import (
 	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"reflect"
+	"strings"
 )
 
 var (
 	// ErrRepositoriesNotFound is returned from Repositories when the file
-	// "repositories" is not found at the root of the archive.
+	// "repositories" is not found at the root of the archive, and no
+	// manifest.json or index.json was found either.
 	ErrRepositoriesNotFound = errors.New("imagetar: repositories file not found")
 
 	// ErrRepositoriesInvalid is returned from Repositories when the file
 	// "repositories" is found but does not have the expected JSON structure.
 	ErrRepositoriesInvalid = errors.New("imagetar: repositories file is invalid")
+
+	// ErrRepositoriesManifestMismatch is returned from Repositories
+	// alongside a valid (non-nil) return value when the archive contains
+	// both a legacy "repositories" file and a "manifest.json", and the two
+	// disagree about which images/tags are present. It's a warning, not a
+	// fatal error: Repositories still returns the map derived from
+	// manifest.json, since that's the authoritative, more modern format.
+	// Callers that don't care about this discrepancy can ignore it, e.g.
+	// with errors.Is(err, ErrRepositoriesManifestMismatch).
+	ErrRepositoriesManifestMismatch = errors.New("imagetar: repositories file and manifest.json disagree; manifest.json takes precedence")
+
+	// ErrManifestNotFound is returned from Manifest when the file
+	// "manifest.json" is not found at the root of the archive.
+	ErrManifestNotFound = errors.New("imagetar: manifest.json file not found")
+
+	// ErrManifestInvalid is returned from Manifest when the file
+	// "manifest.json" is found but does not have the expected JSON
+	// structure.
+	ErrManifestInvalid = errors.New("imagetar: manifest.json file is invalid")
+
+	// ErrIndexNotFound is returned from Index when the file "index.json" is
+	// not found at the root of the archive.
+	ErrIndexNotFound = errors.New("imagetar: index.json file not found")
+
+	// ErrIndexInvalid is returned from Index when the file "index.json" is
+	// found but does not have the expected JSON structure.
+	ErrIndexInvalid = errors.New("imagetar: index.json file is invalid")
+
+	// ErrOCILayoutNotFound is returned from OCILayout when the archive
+	// doesn't contain an "oci-layout" marker file at its root.
+	ErrOCILayoutNotFound = errors.New("imagetar: oci-layout file not found")
+
+	// ErrImageNotFound is returned from Layers when image isn't one of the
+	// "repo:tag" strings found in the archive's manifest.json or index.json.
+	ErrImageNotFound = errors.New("imagetar: image not found")
+
+	// ErrLayersUnavailable is returned from Layers when the image was found,
+	// but the archive doesn't record its layers -- currently only images
+	// described by a Docker v1.2 manifest.json do.
+	ErrLayersUnavailable = errors.New("imagetar: layers not recorded for image")
+
+	// ErrNotSeekable is returned from Layers and OpenBlob when the given
+	// reader doesn't also implement io.Seeker, which both need for
+	// random-access extraction. VisitBlobs is the alternative for archives
+	// that are only available as a plain io.Reader.
+	ErrNotSeekable = errors.New("imagetar: reader does not support seeking")
+
+	// ErrBlobNotFound is returned from OpenBlob when no entry matching the
+	// requested digest is found in the archive.
+	ErrBlobNotFound = errors.New("imagetar: blob not found")
+
+	// ErrDigestMismatch is returned, wrapped, from a Read on the
+	// io.ReadCloser returned by OpenBlob once the stream has been read far
+	// enough to determine that its content doesn't actually hash to the
+	// digest it was opened under. Verifying a streamed blob requires
+	// reading to the end, so this surfaces from Read (on the call that
+	// reaches EOF) rather than from OpenBlob itself.
+	ErrDigestMismatch = errors.New("imagetar: blob digest mismatch")
+
+	// errMalformedDigest is wrapped into errors returned from OpenBlob and
+	// Layers when a digest string isn't of the form "algorithm:hex".
+	errMalformedDigest = errors.New("imagetar: malformed digest")
 )
 
-// Repositories reads out the "repositories" file from the root of the archive
-// and parses its contents, which is expected to be JSON, into a map. The map is
-// structed as follows to match the definition of the "repositories" file as
-// described at https://docs.docker.com/engine/api/v1.41/#operation/ImageGet.
-//
-//	repository -> tag -> layer ID
-//
-// If no "repositories" file is found, Repositories returns
-// ErrRepositoriesNotFound. If the file is found but its contents cannot be
-// parsed as JSON, Repositories returns ErrRepositoriesInvalid.
-func Repositories(r io.Reader) (map[string]map[string]string, error) {
+// ociRefNameAnnotation is the OCI annotation key used to record an image's
+// name and tag on its entry in index.json, per
+// https://github.com/opencontainers/image-spec/blob/main/annotations.md.
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// ManifestEntry is one entry in a Docker v1.2 "manifest.json" file, as
+// written by `docker save`. See
+// https://github.com/moby/moby/blob/master/image/tarexport/tarexport.go.
+type ManifestEntry struct {
+	// Config is the path, relative to the archive root, of the image's
+	// config JSON blob.
+	Config string `json:"Config"`
+	// RepoTags is the list of "repo:tag" strings this image is tagged as.
+	RepoTags []string `json:"RepoTags"`
+	// Layers is the list of paths, relative to the archive root, of the
+	// image's layer tarballs, in order from base to top.
+	Layers []string `json:"Layers"`
+}
+
+// IndexEntry is one entry in the "manifests" list of an OCI "index.json"
+// file, as written for images using the OCI image-layout format. See
+// https://github.com/opencontainers/image-spec/blob/main/image-index.md.
+type IndexEntry struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// archiveFiles holds the raw contents of the files Repositories, Manifest,
+// Index, OCILayout, and Detect care about, read from a tar archive in a
+// single pass -- r is an io.Reader, so it can only be scanned once.
+type archiveFiles struct {
+	repositories []byte // nil if not present
+	manifest     []byte // nil if not present
+	index        []byte // nil if not present
+	ociLayout    []byte // nil if not present
+}
+
+// readArchiveFiles scans the tar archive in r once, returning the contents
+// of "repositories", "manifest.json", "index.json", and "oci-layout",
+// whichever of those are present at the root of the archive.
+func readArchiveFiles(r io.Reader) (archiveFiles, error) {
+	var out archiveFiles
 	tr := tar.NewReader(r)
 	for {
 		hdr, err := tr.Next()
 		if err == io.EOF {
-			return nil, ErrRepositoriesNotFound
+			return out, nil
 		}
 		if err != nil {
-			return nil, fmt.Errorf("imagetar: read repositories: %w", err)
+			return archiveFiles{}, fmt.Errorf("imagetar: read archive: %w", err)
 		}
-		if hdr.Name != "repositories" {
+		var dst *[]byte
+		switch hdr.Name {
+		case "repositories":
+			dst = &out.repositories
+		case "manifest.json":
+			dst = &out.manifest
+		case "index.json":
+			dst = &out.index
+		case "oci-layout":
+			dst = &out.ociLayout
+		default:
 			continue
 		}
 		contents, err := io.ReadAll(tr)
 		if err != nil {
-			return nil, fmt.Errorf("imagetar: read repositories: %w", err)
+			return archiveFiles{}, fmt.Errorf("imagetar: read archive: %w", err)
+		}
+		*dst = contents
+	}
+}
+
+// splitRepoTag splits a "repo:tag" string, such as a Docker v1.2
+// ManifestEntry.RepoTags entry, into its repo and tag parts. It reports
+// false if ref doesn't contain a ':' after its last '/', which would
+// otherwise be mistaken for a registry host's port.
+func splitRepoTag(ref string) (repo, tag string, ok bool) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	if slash := strings.LastIndex(ref, "/"); slash > i {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
+
+// manifestToRepositories converts Docker v1.2 manifest.json entries into
+// the same repo -> tag -> ID shape as the legacy repositories file, using
+// each image's config blob path (minus the ".json" suffix) as the ID.
+func manifestToRepositories(entries []ManifestEntry) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+	for _, e := range entries {
+		for _, rt := range e.RepoTags {
+			repo, tag, ok := splitRepoTag(rt)
+			if !ok {
+				continue
+			}
+			if out[repo] == nil {
+				out[repo] = make(map[string]string)
+			}
+			out[repo][tag] = strings.TrimSuffix(e.Config, ".json")
+		}
+	}
+	return out
+}
+
+// indexToRepositories converts OCI index.json entries into the same
+// repo -> tag -> ID shape as the legacy repositories file, using each
+// entry's digest as the ID. If an entry's ociRefNameAnnotation doesn't
+// look like "repo:tag", it's recorded with an empty repo.
+func indexToRepositories(entries []IndexEntry) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+	for _, e := range entries {
+		refName, ok := e.Annotations[ociRefNameAnnotation]
+		if !ok || refName == "" {
+			continue
+		}
+		repo, tag, ok := splitRepoTag(refName)
+		if !ok {
+			repo, tag = "", refName
+		}
+		if out[repo] == nil {
+			out[repo] = make(map[string]string)
+		}
+		out[repo][tag] = e.Digest
+	}
+	return out
+}
+
+// Repositories reads the root of the archive and returns a map structured
+// as follows:
+//
+//	repository -> tag -> ID
+//
+// matching the definition of the legacy "repositories" file as described at
+// https://docs.docker.com/engine/api/v1.41/#operation/ImageGet. The map is
+// derived from whichever of the following formats is present in the
+// archive, in order of preference: a Docker v1.2 "manifest.json" file (ID
+// is the image's config blob name), an OCI "index.json" file (ID is each
+// manifest's digest), or the legacy "repositories" file (ID is the image's
+// top layer ID, as originally written there).
+//
+// If the archive contains both a "repositories" file and a "manifest.json"
+// and they disagree, Repositories returns the map derived from
+// manifest.json alongside ErrRepositoriesManifestMismatch; see that error's
+// documentation.
+//
+// If none of "manifest.json", "index.json", or "repositories" is found,
+// Repositories returns ErrRepositoriesNotFound. If the format that's found
+// doesn't have the expected JSON structure, Repositories returns
+// ErrManifestInvalid, ErrIndexInvalid, or ErrRepositoriesInvalid
+// respectively.
+func Repositories(r io.Reader) (map[string]map[string]string, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy map[string]map[string]string
+	if files.repositories != nil {
+		legacy = make(map[string]map[string]string)
+		if err := json.Unmarshal(files.repositories, &legacy); err != nil {
+			legacy = nil
+		}
+	}
+
+	if files.manifest != nil {
+		var entries []ManifestEntry
+		if err := json.Unmarshal(files.manifest, &entries); err != nil {
+			return nil, ErrManifestInvalid
+		}
+		repos := manifestToRepositories(entries)
+		if legacy != nil && !reflect.DeepEqual(legacy, repos) {
+			return repos, ErrRepositoriesManifestMismatch
+		}
+		return repos, nil
+	}
+
+	if files.index != nil {
+		var idx struct {
+			Manifests []IndexEntry `json:"manifests"`
+		}
+		if err := json.Unmarshal(files.index, &idx); err != nil {
+			return nil, ErrIndexInvalid
 		}
-		repositories := make(map[string]map[string]string)
-		if err := json.Unmarshal(contents, &repositories); err != nil {
+		return indexToRepositories(idx.Manifests), nil
+	}
+
+	if files.repositories != nil {
+		if legacy == nil {
 			return nil, ErrRepositoriesInvalid
 		}
-		return repositories, nil
+		return legacy, nil
+	}
+
+	return nil, ErrRepositoriesNotFound
+}
+
+// Manifest reads and parses the "manifest.json" file at the root of the
+// archive, as written by `docker save` for images using the Docker v1.2
+// manifest format. If no "manifest.json" file is found, Manifest returns
+// ErrManifestNotFound. If the file is found but doesn't have the expected
+// JSON structure, Manifest returns ErrManifestInvalid.
+func Manifest(r io.Reader) ([]ManifestEntry, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+	if files.manifest == nil {
+		return nil, ErrManifestNotFound
+	}
+	var entries []ManifestEntry
+	if err := json.Unmarshal(files.manifest, &entries); err != nil {
+		return nil, ErrManifestInvalid
+	}
+	return entries, nil
+}
+
+// Index reads and parses the "index.json" file at the root of the archive,
+// as written for images using the OCI image-layout format. If no
+// "index.json" file is found, Index returns ErrIndexNotFound. If the file
+// is found but doesn't have the expected JSON structure, Index returns
+// ErrIndexInvalid.
+func Index(r io.Reader) ([]IndexEntry, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+	if files.index == nil {
+		return nil, ErrIndexNotFound
+	}
+	var idx struct {
+		Manifests []IndexEntry `json:"manifests"`
+	}
+	if err := json.Unmarshal(files.index, &idx); err != nil {
+		return nil, ErrIndexInvalid
+	}
+	return idx.Manifests, nil
+}
+
+// OCIIndex is the parsed contents of an OCI image-layout archive's
+// "index.json", per
+// https://github.com/opencontainers/image-spec/blob/main/image-index.md.
+type OCIIndex struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Manifests     []IndexEntry `json:"manifests"`
+}
+
+// OCILayout reads and parses the "index.json" file at the root of an OCI
+// image-layout archive, i.e. one containing the "oci-layout" marker file
+// alongside it, as written by tools like `skopeo copy` or
+// `crane pull --format=oci`. Once OCILayout returns successfully, a
+// manifest's blob -- or, following it, a config or layer blob -- can be
+// fetched by digest with OpenBlob, since image-layout archives store all
+// three under content-addressed "blobs/<algorithm>/<hex>" paths.
+//
+// OCILayout returns ErrOCILayoutNotFound if the archive has no "oci-layout"
+// file, ErrIndexNotFound if it has one but no "index.json", and
+// ErrIndexInvalid if "index.json" doesn't have the expected JSON structure.
+func OCILayout(r io.Reader) (*OCIIndex, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+	if files.ociLayout == nil {
+		return nil, ErrOCILayoutNotFound
+	}
+	if files.index == nil {
+		return nil, ErrIndexNotFound
+	}
+	var idx OCIIndex
+	if err := json.Unmarshal(files.index, &idx); err != nil {
+		return nil, ErrIndexInvalid
 	}
+	return &idx, nil
 }
 
-// Images parses the "repositories" file at the root of the archive and returns
-// a list of image names contained in that archive. The strings will have the
-// format "path/to/repo:tag".
+// Format identifies which of the layouts this package understands an
+// archive uses, as reported by Detect.
+type Format int
+
+const (
+	// FormatUnknown is the zero Format, reported by Detect when an archive
+	// matches neither FormatDockerSave nor FormatOCILayout.
+	FormatUnknown Format = iota
+	// FormatDockerSave is the layout `docker save` writes: a "manifest.json"
+	// and/or legacy "repositories" file at the archive root, read by
+	// Repositories, Images, Manifest, ImageDetails, Layers, and Config.
+	FormatDockerSave
+	// FormatOCILayout is the OCI image-layout format: an "oci-layout"
+	// marker file and "index.json" at the archive root, with blobs stored
+	// under content-addressed paths, read by OCILayout and OpenBlob.
+	FormatOCILayout
+)
+
+// String returns a lowercase, hyphenated name for f, such as
+// "docker-save" or "oci-layout", or "unknown" for FormatUnknown.
+func (f Format) String() string {
+	switch f {
+	case FormatDockerSave:
+		return "docker-save"
+	case FormatOCILayout:
+		return "oci-layout"
+	default:
+		return "unknown"
+	}
+}
+
+// Detect classifies the archive in r by scanning for the marker files
+// FormatDockerSave and FormatOCILayout each require, returning
+// FormatUnknown if neither is found. It requires random access into the
+// archive so it can rewind r to the start before returning, leaving it
+// ready for a subsequent call to Repositories, Manifest, or OCILayout.
+func Detect(r io.ReadSeeker) (Format, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return FormatUnknown, fmt.Errorf("imagetar: detect: %w", err)
+	}
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return FormatUnknown, fmt.Errorf("imagetar: detect: %w", err)
+	}
+	switch {
+	case files.ociLayout != nil:
+		return FormatOCILayout, nil
+	case files.manifest != nil || files.repositories != nil:
+		return FormatDockerSave, nil
+	default:
+		return FormatUnknown, nil
+	}
+}
+
+// ImageDetail exposes the config and layer identifiers for a single image,
+// as recorded in manifest.json or index.json, so callers can inspect an
+// image without shelling out to `docker`.
+type ImageDetail struct {
+	// ConfigDigest identifies the image's config blob.
+	ConfigDigest string
+	// LayerDigests are the image's layers, in order from base to top, as
+	// recorded in the archive. This is only populated for images described
+	// by a Docker v1.2 manifest.json -- an OCI index.json doesn't list an
+	// image's layers itself, only a pointer to its manifest blob, so
+	// LayerDigests is nil in that case.
+	LayerDigests []string
+}
+
+// ImageDetails returns per-image config and layer digests for every tagged
+// image in the archive, keyed by "repo:tag" the same way Images' entries
+// are. It requires the archive to contain a "manifest.json" or
+// "index.json" file -- the legacy repositories format doesn't record this
+// information -- returning ErrManifestNotFound if neither is present.
+func ImageDetails(r io.Reader) (map[string]ImageDetail, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if files.manifest != nil {
+		var entries []ManifestEntry
+		if err := json.Unmarshal(files.manifest, &entries); err != nil {
+			return nil, ErrManifestInvalid
+		}
+		out := make(map[string]ImageDetail)
+		for _, e := range entries {
+			detail := ImageDetail{
+				ConfigDigest: strings.TrimSuffix(e.Config, ".json"),
+				LayerDigests: e.Layers,
+			}
+			for _, rt := range e.RepoTags {
+				out[rt] = detail
+			}
+		}
+		return out, nil
+	}
+
+	if files.index != nil {
+		var idx struct {
+			Manifests []IndexEntry `json:"manifests"`
+		}
+		if err := json.Unmarshal(files.index, &idx); err != nil {
+			return nil, ErrIndexInvalid
+		}
+		out := make(map[string]ImageDetail)
+		for _, e := range idx.Manifests {
+			refName, ok := e.Annotations[ociRefNameAnnotation]
+			if !ok || refName == "" {
+				continue
+			}
+			out[refName] = ImageDetail{ConfigDigest: e.Digest}
+		}
+		return out, nil
+	}
+
+	return nil, ErrManifestNotFound
+}
+
+// Images parses the archive and returns a list of image names contained in
+// it, derived the same way Repositories derives its return value. Each
+// string is built from its repo and tag by way of ParseReference and
+// Reference.Familiar, guaranteeing it parses back through ParseReference to
+// an equivalent Reference.
+//
+// If the archive's "repositories" file and "manifest.json" disagree, Images
+// returns the images derived from manifest.json alongside
+// ErrRepositoriesManifestMismatch, the same way Repositories does.
 func Images(r io.Reader) ([]string, error) {
 	repos, err := Repositories(r)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrRepositoriesManifestMismatch) {
 		return nil, err
 	}
 	var images []string
 	for repo, tags := range repos {
 		for tag := range tags {
-			images = append(images, repo+":"+tag)
+			ref, perr := ParseReference(repo + ":" + tag)
+			if perr != nil {
+				return nil, fmt.Errorf("imagetar: images: %w", perr)
+			}
+			images = append(images, ref.Familiar())
+		}
+	}
+	return images, err
+}
+
+// tarIndexEntry records where in a seekable archive a single tar entry's
+// data begins, so it can be re-opened later without rescanning the archive.
+type tarIndexEntry struct {
+	offset int64
+	size   int64
+}
+
+// indexTar scans the tar archive in rs once, recording the data offset and
+// size of every entry, keyed by name. It leaves rs positioned at EOF;
+// callers that need to read from rs afterwards must Seek first.
+func indexTar(rs io.ReadSeeker) (map[string]tarIndexEntry, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("imagetar: index archive: %w", err)
+	}
+	tr := tar.NewReader(rs)
+	out := make(map[string]tarIndexEntry)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("imagetar: index archive: %w", err)
+		}
+		offset, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("imagetar: index archive: %w", err)
+		}
+		out[hdr.Name] = tarIndexEntry{offset: offset, size: hdr.Size}
+	}
+}
+
+// openIndexEntry seeks rs to entry's data and returns a reader limited to
+// its size. Since it repositions rs, it's not safe to call concurrently
+// with another read of the same rs.
+func openIndexEntry(rs io.ReadSeeker, entry tarIndexEntry) (io.ReadCloser, error) {
+	if _, err := rs.Seek(entry.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("imagetar: seek: %w", err)
+	}
+	return io.NopCloser(io.LimitReader(rs, entry.size)), nil
+}
+
+// splitDigest splits a digest string of the form "algorithm:hex" into its
+// two parts.
+func splitDigest(digest string) (algorithm, hexPart string, err error) {
+	algorithm, hexPart, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hexPart == "" {
+		return "", "", fmt.Errorf("imagetar: %q: %w", digest, errMalformedDigest)
+	}
+	return algorithm, hexPart, nil
+}
+
+// blobPath returns the path, relative to the archive root, at which an OCI
+// image-layout archive stores the blob identified by digest.
+func blobPath(digest string) (string, error) {
+	algorithm, hexPart, err := splitDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	return "blobs/" + algorithm + "/" + hexPart, nil
+}
+
+// layerDigestFromPath returns the content digest implied by path, if path
+// itself already looks like an OCI blob path ("blobs/<algorithm>/<hex>").
+// Legacy Docker v1.2 layer paths (like "<layerID>/layer.tar") aren't
+// themselves content-addressed, so this returns "" for those; callers that
+// need a digest in that case would have to hash the layer's bytes
+// themselves.
+func layerDigestFromPath(path string) string {
+	const prefix = "blobs/"
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	algorithm, hexPart, ok := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	if !ok || algorithm == "" || hexPart == "" {
+		return ""
+	}
+	return algorithm + ":" + hexPart
+}
+
+// digestVerifyingReadCloser wraps an io.ReadCloser, hashing everything read
+// from it and comparing the result against digest once the underlying
+// reader reports io.EOF.
+type digestVerifyingReadCloser struct {
+	io.Closer
+	r       io.Reader
+	hexWant string
+	hash    hash.Hash
+}
+
+func newDigestVerifyingReadCloser(rc io.ReadCloser, digest string) (io.ReadCloser, error) {
+	_, hexPart, err := splitDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	return &digestVerifyingReadCloser{Closer: rc, r: rc, hexWant: hexPart, hash: sha256.New()}, nil
+}
+
+func (d *digestVerifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if n > 0 {
+		d.hash.Write(p[:n])
+	}
+	if err == io.EOF {
+		if got := hex.EncodeToString(d.hash.Sum(nil)); got != d.hexWant {
+			return n, fmt.Errorf("imagetar: got digest sha256:%s, want sha256:%s: %w", got, d.hexWant, ErrDigestMismatch)
+		}
+	}
+	return n, err
+}
+
+// LayerRef identifies a single layer blob within an archive, along with a
+// way to stream its contents on demand.
+type LayerRef struct {
+	// Digest is the layer's content digest, e.g. "sha256:abc...". It's
+	// empty if the archive doesn't record the layer in a content-addressed
+	// path (true for images described by a legacy Docker v1.2
+	// manifest.json whose Layers entries are "<layerID>/layer.tar" paths
+	// rather than "blobs/<algorithm>/<hex>" ones).
+	Digest string
+	// UncompressedSize is the size, in bytes, of the layer's tar stream, as
+	// recorded in the archive.
+	UncompressedSize int64
+
+	open func() (io.ReadCloser, error)
+}
+
+// Open returns a reader for the layer's tar bytes. It streams directly from
+// the archive rather than buffering the layer (or the rest of the archive)
+// in memory.
+func (l LayerRef) Open() (io.ReadCloser, error) {
+	return l.open()
+}
+
+// Layers returns a LayerRef for each layer of image (a "repo:tag" string as
+// returned by Images), in order from base to top. Opening the returned
+// LayerRefs requires random access into the archive, so r must also
+// implement io.Seeker; Layers returns ErrNotSeekable if it doesn't. For
+// archives that are only available as a single-pass io.Reader, use
+// VisitBlobs instead.
+//
+// Layers returns ErrImageNotFound if image isn't present in the archive's
+// manifest.json or index.json, and ErrLayersUnavailable if image is found
+// but the archive doesn't record its layers (currently only true for images
+// described by an index.json without an accompanying manifest.json).
+func Layers(r io.Reader, image string) ([]LayerRef, error) {
+	rws, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, fmt.Errorf("imagetar: layers: %w", ErrNotSeekable)
+	}
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("imagetar: layers: %w", err)
+	}
+
+	details, err := ImageDetails(rws)
+	if err != nil && !errors.Is(err, ErrRepositoriesManifestMismatch) {
+		return nil, fmt.Errorf("imagetar: layers: %w", err)
+	}
+	detail, ok := details[image]
+	if !ok {
+		return nil, fmt.Errorf("imagetar: layers: %q: %w", image, ErrImageNotFound)
+	}
+	if detail.LayerDigests == nil {
+		return nil, fmt.Errorf("imagetar: layers: %q: %w", image, ErrLayersUnavailable)
+	}
+
+	index, err := indexTar(rws)
+	if err != nil {
+		return nil, fmt.Errorf("imagetar: layers: %w", err)
+	}
+	refs := make([]LayerRef, 0, len(detail.LayerDigests))
+	for _, path := range detail.LayerDigests {
+		entry, ok := index[path]
+		if !ok {
+			return nil, fmt.Errorf("imagetar: layers: %q: layer %q: %w", image, path, ErrBlobNotFound)
+		}
+		refs = append(refs, LayerRef{
+			Digest:           layerDigestFromPath(path),
+			UncompressedSize: entry.size,
+			open:             func() (io.ReadCloser, error) { return openIndexEntry(rws, entry) },
+		})
+	}
+	return refs, nil
+}
+
+// ImageConfig is the parsed contents of a Docker v1.2 image config blob, as
+// referenced by a ManifestEntry's Config field. Only the fields callers have
+// needed so far are included; see
+// https://github.com/moby/moby/blob/master/image/image.go for the full
+// structure.
+type ImageConfig struct {
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	Config       ContainerConfig   `json:"config"`
+	RootFS       ImageConfigRootFS `json:"rootfs"`
+}
+
+// ContainerConfig is the "config" object of an ImageConfig, describing how
+// containers created from the image should run.
+type ContainerConfig struct {
+	Cmd        []string `json:"Cmd"`
+	Entrypoint []string `json:"Entrypoint"`
+	Env        []string `json:"Env"`
+	WorkingDir string   `json:"WorkingDir"`
+}
+
+// ImageConfigRootFS is the "rootfs" object of an ImageConfig, listing the
+// uncompressed layer digests that make up the image's filesystem, in order
+// from base to top.
+type ImageConfigRootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+// Config reads and parses the config blob for image (a "repo:tag" string as
+// returned by Images) from the archive's "manifest.json". It requires random
+// access into the archive, so r must also implement io.Seeker; Config
+// returns ErrNotSeekable if it doesn't.
+//
+// Config returns ErrManifestNotFound or ErrManifestInvalid under the same
+// conditions as Manifest, and ErrImageNotFound if image isn't one of the
+// manifest's RepoTags.
+func Config(r io.Reader, image string) (ImageConfig, error) {
+	rws, ok := r.(io.ReadSeeker)
+	if !ok {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", ErrNotSeekable)
+	}
+
+	entries, err := Manifest(rws)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", err)
+	}
+	var configPath string
+	for _, e := range entries {
+		for _, rt := range e.RepoTags {
+			if rt == image {
+				configPath = e.Config
+			}
+		}
+	}
+	if configPath == "" {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %q: %w", image, ErrImageNotFound)
+	}
+
+	index, err := indexTar(rws)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", err)
+	}
+	entry, ok := index[configPath]
+	if !ok {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %q: %w", configPath, ErrBlobNotFound)
+	}
+	body, err := openIndexEntry(rws, entry)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", err)
+	}
+	var cfg ImageConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ImageConfig{}, fmt.Errorf("imagetar: config: %w", ErrManifestInvalid)
+	}
+	return cfg, nil
+}
+
+// OpenBlob opens the blob identified by digest (e.g. "sha256:abc...") from
+// an OCI image-layout archive, which stores blobs at content-addressed
+// paths ("blobs/<algorithm>/<hex>"). It requires random access into the
+// archive, which is why it takes an io.ReadSeeker rather than a plain
+// io.Reader.
+//
+// The returned reader streams the blob's bytes directly from the archive,
+// and verifies them against digest as they're read: once the caller has
+// read to the end of the stream, a Read call returns ErrDigestMismatch,
+// wrapped, if the blob's actual content doesn't hash to digest. If no blob
+// with that digest is found in the archive, OpenBlob returns
+// ErrBlobNotFound.
+func OpenBlob(r io.ReadSeeker, digest string) (io.ReadCloser, error) {
+	path, err := blobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	index, err := indexTar(r)
+	if err != nil {
+		return nil, fmt.Errorf("imagetar: open blob: %w", err)
+	}
+	entry, ok := index[path]
+	if !ok {
+		return nil, fmt.Errorf("imagetar: open blob: %s: %w", digest, ErrBlobNotFound)
+	}
+	body, err := openIndexEntry(r, entry)
+	if err != nil {
+		return nil, fmt.Errorf("imagetar: open blob: %w", err)
+	}
+	verified, err := newDigestVerifyingReadCloser(body, digest)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return verified, nil
+}
+
+// VisitBlobs scans the tar archive in r in a single pass, calling visit
+// with the name, size, and streamed contents of every entry for which
+// match returns true. visit must fully consume body (or return an error)
+// before VisitBlobs resumes scanning, since entries are streamed directly
+// from r rather than buffered.
+//
+// Unlike Layers and OpenBlob, VisitBlobs works with a plain io.Reader, at
+// the cost of requiring a full scan of the archive and not supporting
+// random access: it's meant for archives (such as those streamed straight
+// off a registry or a pipe) that can't be wrapped in an io.ReadSeeker.
+func VisitBlobs(r io.Reader, match func(name string) bool, visit func(name string, size int64, body io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("imagetar: visit blobs: %w", err)
+		}
+		if !match(hdr.Name) {
+			continue
+		}
+		if err := visit(hdr.Name, hdr.Size, tr); err != nil {
+			return fmt.Errorf("imagetar: visit blobs: %q: %w", hdr.Name, err)
 		}
 	}
-	return images, nil
 }
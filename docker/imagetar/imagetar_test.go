@@ -3,7 +3,12 @@ package imagetar
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -164,6 +169,554 @@ func TestImages(t *testing.T) {
 	}
 }
 
+func TestManifest(t *testing.T) {
+	t.Parallel()
+	manifest := []byte(`[{"Config":"deadbeef.json","RepoTags":["bazel/docker/imagetar:testimage_hello_world"],"Layers":["layer1/layer.tar"]}]`)
+	archive := replaceFile(t, testimage, "manifest.json", manifest)
+
+	got, err := Manifest(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManifestEntry{
+		{
+			Config:   "deadbeef.json",
+			RepoTags: []string{"bazel/docker/imagetar:testimage_hello_world"},
+			Layers:   []string{"layer1/layer.tar"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Manifest: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestManifest_Error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		r    io.Reader
+		want error
+	}{
+		{
+			name: "NotFound",
+			r:    bytes.NewReader(testimage),
+			want: ErrManifestNotFound,
+		},
+		{
+			name: "Invalid",
+			r:    bytes.NewReader(replaceFile(t, testimage, "manifest.json", []byte("this is not JSON"))),
+			want: ErrManifestInvalid,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, got := Manifest(tt.r)
+			if diff := cmp.Diff(tt.want, got, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("unexpected error from Manifest (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+	index := []byte(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:deadbeef","size":123,"annotations":{"org.opencontainers.image.ref.name":"bazel/docker/imagetar:testimage_hello_world"}}]}`)
+	archive := replaceFile(t, testimage, "index.json", index)
+
+	got, err := Index(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []IndexEntry{
+		{
+			MediaType:   "application/vnd.oci.image.manifest.v1+json",
+			Digest:      "sha256:deadbeef",
+			Size:        123,
+			Annotations: map[string]string{"org.opencontainers.image.ref.name": "bazel/docker/imagetar:testimage_hello_world"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Index: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestIndex_Error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		r    io.Reader
+		want error
+	}{
+		{
+			name: "NotFound",
+			r:    bytes.NewReader(testimage),
+			want: ErrIndexNotFound,
+		},
+		{
+			name: "Invalid",
+			r:    bytes.NewReader(replaceFile(t, testimage, "index.json", []byte("this is not JSON"))),
+			want: ErrIndexInvalid,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, got := Index(tt.r)
+			if diff := cmp.Diff(tt.want, got, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("unexpected error from Index (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOCILayout(t *testing.T) {
+	t.Parallel()
+	index := []byte(`{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:deadbeef","size":123,"annotations":{"org.opencontainers.image.ref.name":"bazel/docker/imagetar:testimage_hello_world"}}]}`)
+	archive := buildTar(t, map[string][]byte{
+		"oci-layout":            []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json":            index,
+		"blobs/sha256/deadbeef": []byte("manifest contents"),
+	})
+
+	got, err := OCILayout(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &OCIIndex{
+		SchemaVersion: 2,
+		Manifests: []IndexEntry{
+			{
+				MediaType:   "application/vnd.oci.image.manifest.v1+json",
+				Digest:      "sha256:deadbeef",
+				Size:        123,
+				Annotations: map[string]string{"org.opencontainers.image.ref.name": "bazel/docker/imagetar:testimage_hello_world"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("OCILayout: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestOCILayout_Error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		r    io.Reader
+		want error
+	}{
+		{
+			name: "NotFound",
+			r:    bytes.NewReader(testimage),
+			want: ErrOCILayoutNotFound,
+		},
+		{
+			name: "IndexNotFound",
+			r:    bytes.NewReader(buildTar(t, map[string][]byte{"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`)})),
+			want: ErrIndexNotFound,
+		},
+		{
+			name: "IndexInvalid",
+			r: bytes.NewReader(buildTar(t, map[string][]byte{
+				"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+				"index.json": []byte("this is not JSON"),
+			})),
+			want: ErrIndexInvalid,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, got := OCILayout(tt.r)
+			if diff := cmp.Diff(tt.want, got, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("unexpected error from OCILayout (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	ociLayout := buildTar(t, map[string][]byte{
+		"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json": []byte(`{"schemaVersion":2,"manifests":[]}`),
+	})
+	unknown := buildTar(t, map[string][]byte{
+		"some-other-file": []byte("nothing imagetar recognizes"),
+	})
+	for _, tt := range []struct {
+		name string
+		r    []byte
+		want Format
+	}{
+		{name: "DockerSave", r: testimage, want: FormatDockerSave},
+		{name: "OCILayout", r: ociLayout, want: FormatOCILayout},
+		{name: "Unknown", r: unknown, want: FormatUnknown},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Detect(bytes.NewReader(tt.r))
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Detect: got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositories_ManifestPrecedence(t *testing.T) {
+	t.Parallel()
+	manifest := []byte(`[{"Config":"deadbeef.json","RepoTags":["bazel/docker/imagetar:fromManifest"],"Layers":["layer1/layer.tar"]}]`)
+	archive := replaceFile(t, testimage, "manifest.json", manifest)
+
+	got, err := Repositories(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"bazel/docker/imagetar": {"fromManifest": "deadbeef"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Repositories: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestRepositories_IndexFallback(t *testing.T) {
+	t.Parallel()
+	// Remove the legacy repositories file so index.json is the only format
+	// present.
+	archive := replaceFile(t, testimage, "repositories", nil)
+	index := []byte(`{"schemaVersion":2,"manifests":[{"digest":"sha256:deadbeef","annotations":{"org.opencontainers.image.ref.name":"bazel/docker/imagetar:fromIndex"}}]}`)
+	archive = replaceFile(t, archive, "index.json", index)
+
+	got, err := Repositories(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"bazel/docker/imagetar": {"fromIndex": "sha256:deadbeef"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Repositories: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestRepositories_ManifestMismatch(t *testing.T) {
+	t.Parallel()
+	// testimage's "repositories" file disagrees with this manifest.json,
+	// which tags the same image differently.
+	manifest := []byte(`[{"Config":"deadbeef.json","RepoTags":["bazel/docker/imagetar:somethingElse"],"Layers":["layer1/layer.tar"]}]`)
+	archive := replaceFile(t, testimage, "manifest.json", manifest)
+
+	got, err := Repositories(bytes.NewReader(archive))
+	if !errors.Is(err, ErrRepositoriesManifestMismatch) {
+		t.Fatalf("Repositories: err = %v; want ErrRepositoriesManifestMismatch", err)
+	}
+	want := map[string]map[string]string{
+		"bazel/docker/imagetar": {"somethingElse": "deadbeef"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Repositories: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestImageDetails(t *testing.T) {
+	t.Parallel()
+	manifest := []byte(`[{"Config":"deadbeef.json","RepoTags":["bazel/docker/imagetar:testimage_hello_world"],"Layers":["layer1/layer.tar","layer2/layer.tar"]}]`)
+	archive := replaceFile(t, testimage, "manifest.json", manifest)
+
+	got, err := ImageDetails(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ImageDetail{
+		"bazel/docker/imagetar:testimage_hello_world": {
+			ConfigDigest: "deadbeef",
+			LayerDigests: []string{"layer1/layer.tar", "layer2/layer.tar"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ImageDetails: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestImageDetails_Error(t *testing.T) {
+	t.Parallel()
+	_, err := ImageDetails(bytes.NewReader(testimage))
+	if !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("ImageDetails: err = %v; want ErrManifestNotFound", err)
+	}
+}
+
+// buildTar writes files (a map from archive path to contents) into a new
+// tar archive, in lexical order of path for determinism, and returns the
+// result.
+func buildTar(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	for _, name := range names {
+		contents := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents))}); err != nil {
+			t.Fatalf("buildTar: write header for %q: %v", name, err)
+		}
+		if _, err := tw.Write(contents); err != nil {
+			t.Fatalf("buildTar: write contents for %q: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("buildTar: close: %v", err)
+	}
+	return out.Bytes()
+}
+
+// onlyReader wraps an io.Reader so it no longer also satisfies io.Seeker,
+// for testing the ErrNotSeekable path.
+type onlyReader struct{ io.Reader }
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLayers(t *testing.T) {
+	t.Parallel()
+	layer1 := []byte("layer one contents")
+	layer2 := []byte("layer two contents")
+	layer1Path := "blobs/sha256/" + sha256Hex(layer1)
+	layer2Path := "blobs/sha256/" + sha256Hex(layer2)
+	manifest := `[{"Config":"deadbeef.json","RepoTags":["repo/image:tag"],"Layers":["` + layer1Path + `","` + layer2Path + `"]}]`
+	archive := buildTar(t, map[string][]byte{
+		"manifest.json": []byte(manifest),
+		layer1Path:      layer1,
+		layer2Path:      layer2,
+	})
+
+	refs, err := Layers(bytes.NewReader(archive), "repo/image:tag")
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	if got, want := len(refs), 2; got != want {
+		t.Fatalf("Layers: got %d refs; want %d", got, want)
+	}
+	for i, want := range [][]byte{layer1, layer2} {
+		ref := refs[i]
+		if got, wantDigest := ref.Digest, "sha256:"+sha256Hex(want); got != wantDigest {
+			t.Errorf("refs[%d].Digest = %q; want %q", i, got, wantDigest)
+		}
+		if got, wantSize := ref.UncompressedSize, int64(len(want)); got != wantSize {
+			t.Errorf("refs[%d].UncompressedSize = %d; want %d", i, got, wantSize)
+		}
+		rc, err := ref.Open()
+		if err != nil {
+			t.Fatalf("refs[%d].Open: %v", i, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("refs[%d]: read: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("refs[%d] contents = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestLayers_Error(t *testing.T) {
+	t.Parallel()
+	manifest := `[{"Config":"deadbeef.json","RepoTags":["repo/image:tag"],"Layers":["blobs/sha256/deadbeef"]}]`
+	archive := buildTar(t, map[string][]byte{
+		"manifest.json":        []byte(manifest),
+		"blobs/sha256/deadbeef": []byte("layer"),
+	})
+	indexOnly := buildTar(t, map[string][]byte{
+		"index.json": []byte(`{"schemaVersion":2,"manifests":[{"digest":"sha256:deadbeef","annotations":{"org.opencontainers.image.ref.name":"repo/image:tag"}}]}`),
+	})
+
+	t.Run("ImageNotFound", func(t *testing.T) {
+		t.Parallel()
+		_, err := Layers(bytes.NewReader(archive), "repo/image:other")
+		if !errors.Is(err, ErrImageNotFound) {
+			t.Fatalf("Layers: err = %v; want ErrImageNotFound", err)
+		}
+	})
+	t.Run("LayersUnavailable", func(t *testing.T) {
+		t.Parallel()
+		_, err := Layers(bytes.NewReader(indexOnly), "repo/image:tag")
+		if !errors.Is(err, ErrLayersUnavailable) {
+			t.Fatalf("Layers: err = %v; want ErrLayersUnavailable", err)
+		}
+	})
+	t.Run("NotSeekable", func(t *testing.T) {
+		t.Parallel()
+		_, err := Layers(onlyReader{bytes.NewReader(archive)}, "repo/image:tag")
+		if !errors.Is(err, ErrNotSeekable) {
+			t.Fatalf("Layers: err = %v; want ErrNotSeekable", err)
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+	config := []byte(`{"architecture":"amd64","os":"linux","config":{"Cmd":["/bin/sh"],"Env":["PATH=/usr/bin"]},"rootfs":{"type":"layers","diff_ids":["sha256:deadbeef"]}}`)
+	manifest := `[{"Config":"deadbeef.json","RepoTags":["repo/image:tag"],"Layers":["layer1/layer.tar"]}]`
+	archive := buildTar(t, map[string][]byte{
+		"manifest.json":    []byte(manifest),
+		"deadbeef.json":    config,
+		"layer1/layer.tar": []byte("layer one contents"),
+	})
+
+	got, err := Config(bytes.NewReader(archive), "repo/image:tag")
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	want := ImageConfig{
+		Architecture: "amd64",
+		OS:           "linux",
+		Config: ContainerConfig{
+			Cmd: []string{"/bin/sh"},
+			Env: []string{"PATH=/usr/bin"},
+		},
+		RootFS: ImageConfigRootFS{
+			Type:    "layers",
+			DiffIDs: []string{"sha256:deadbeef"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Config: unexpected return value (-want +got)\n%s", diff)
+	}
+}
+
+func TestConfig_Error(t *testing.T) {
+	t.Parallel()
+	manifest := `[{"Config":"deadbeef.json","RepoTags":["repo/image:tag"],"Layers":["layer1/layer.tar"]}]`
+	archive := buildTar(t, map[string][]byte{
+		"manifest.json":    []byte(manifest),
+		"deadbeef.json":    []byte(`{"architecture":"amd64"}`),
+		"layer1/layer.tar": []byte("layer one contents"),
+	})
+
+	t.Run("ImageNotFound", func(t *testing.T) {
+		t.Parallel()
+		_, err := Config(bytes.NewReader(archive), "repo/image:other")
+		if !errors.Is(err, ErrImageNotFound) {
+			t.Fatalf("Config: err = %v; want ErrImageNotFound", err)
+		}
+	})
+	t.Run("ManifestNotFound", func(t *testing.T) {
+		t.Parallel()
+		noManifest := buildTar(t, map[string][]byte{
+			"layer1/layer.tar": []byte("layer one contents"),
+		})
+		_, err := Config(bytes.NewReader(noManifest), "repo/image:tag")
+		if !errors.Is(err, ErrManifestNotFound) {
+			t.Fatalf("Config: err = %v; want ErrManifestNotFound", err)
+		}
+	})
+	t.Run("NotSeekable", func(t *testing.T) {
+		t.Parallel()
+		_, err := Config(onlyReader{bytes.NewReader(archive)}, "repo/image:tag")
+		if !errors.Is(err, ErrNotSeekable) {
+			t.Fatalf("Config: err = %v; want ErrNotSeekable", err)
+		}
+	})
+}
+
+func TestOpenBlob(t *testing.T) {
+	t.Parallel()
+	contents := []byte("blob contents")
+	digest := "sha256:" + sha256Hex(contents)
+	archive := buildTar(t, map[string][]byte{
+		"blobs/sha256/" + sha256Hex(contents): contents,
+	})
+
+	rc, err := OpenBlob(bytes.NewReader(archive), digest)
+	if err != nil {
+		t.Fatalf("OpenBlob: %v", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("OpenBlob: read: %v", err)
+	}
+	if !bytes.Equal(got, contents) {
+		t.Errorf("OpenBlob: contents = %q; want %q", got, contents)
+	}
+}
+
+func TestOpenBlob_Error(t *testing.T) {
+	t.Parallel()
+	t.Run("NotFound", func(t *testing.T) {
+		t.Parallel()
+		archive := buildTar(t, map[string][]byte{"manifest.json": []byte("[]")})
+		_, err := OpenBlob(bytes.NewReader(archive), "sha256:"+sha256Hex([]byte("absent")))
+		if !errors.Is(err, ErrBlobNotFound) {
+			t.Fatalf("OpenBlob: err = %v; want ErrBlobNotFound", err)
+		}
+	})
+	t.Run("DigestMismatch", func(t *testing.T) {
+		t.Parallel()
+		// Store the blob at a path named after one digest, but with
+		// contents that actually hash to something else.
+		wrongDigest := sha256Hex([]byte("not the real contents"))
+		archive := buildTar(t, map[string][]byte{
+			"blobs/sha256/" + wrongDigest: []byte("actual contents"),
+		})
+		rc, err := OpenBlob(bytes.NewReader(archive), "sha256:"+wrongDigest)
+		if err != nil {
+			t.Fatalf("OpenBlob: %v", err)
+		}
+		defer rc.Close()
+		_, err = io.ReadAll(rc)
+		if !errors.Is(err, ErrDigestMismatch) {
+			t.Fatalf("OpenBlob: read err = %v; want ErrDigestMismatch", err)
+		}
+	})
+}
+
+func TestVisitBlobs(t *testing.T) {
+	t.Parallel()
+	archive := buildTar(t, map[string][]byte{
+		"manifest.json":       []byte("[]"),
+		"blobs/sha256/first":  []byte("first contents"),
+		"blobs/sha256/second": []byte("second contents"),
+	})
+
+	visited := make(map[string]string)
+	match := func(name string) bool { return strings.HasPrefix(name, "blobs/") }
+	visit := func(name string, size int64, body io.Reader) error {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != size {
+			t.Errorf("visit %q: read %d bytes; header said %d", name, len(data), size)
+		}
+		visited[name] = string(data)
+		return nil
+	}
+	if err := VisitBlobs(bytes.NewReader(archive), match, visit); err != nil {
+		t.Fatalf("VisitBlobs: %v", err)
+	}
+	want := map[string]string{
+		"blobs/sha256/first":  "first contents",
+		"blobs/sha256/second": "second contents",
+	}
+	if diff := cmp.Diff(want, visited); diff != "" {
+		t.Errorf("VisitBlobs: unexpected visited entries (-want +got)\n%s", diff)
+	}
+}
+
 func TestImages_Error(t *testing.T) {
 	t.Parallel()
 	for _, tt := range []struct {
@@ -0,0 +1,124 @@
+package imagetar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReferenceInvalid is returned from ParseReference when s doesn't parse
+// as an image reference at all, e.g. because it's empty or names an empty
+// repository.
+var ErrReferenceInvalid = errors.New("imagetar: reference is invalid")
+
+// Reference is an image reference split into its components, following the
+// same rules as github.com/docker/distribution/reference and
+// github.com/google/go-containerregistry/pkg/name.
+type Reference struct {
+	// Registry is the reference's registry host, e.g. "docker.io" or
+	// "localhost:5000". It defaults to "docker.io" if s names no registry.
+	Registry string
+	// Repository is the reference's repository path within Registry, e.g.
+	// "library/alpine". If Registry defaults to "docker.io" and s names no
+	// path component, Repository is prefixed with "library/".
+	Repository string
+	// Tag is the reference's tag, e.g. "latest". It's empty if s names a
+	// Digest instead, and defaults to "latest" if s names neither.
+	Tag string
+	// Digest is the reference's "@algorithm:hex" digest, if s names one.
+	Digest string
+}
+
+// isHostComponent reports whether s, the first "/"-separated component of a
+// reference, looks like a registry host rather than the first path segment
+// of a repository -- i.e. it contains a "." or ":", or is "localhost".
+func isHostComponent(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == "localhost"
+}
+
+// ParseReference parses s as an image reference, splitting it into a
+// registry, repository, and tag or digest using the same rules used across
+// the Docker/OCI ecosystem: a reference's first "/"-separated component is
+// its registry host if it contains a "." or ":", or is "localhost";
+// otherwise there is no explicit registry. The remainder is split on its
+// last ":" for a tag, unless the reference instead has an "@algorithm:hex"
+// digest. A reference with neither an explicit registry nor an explicit tag
+// or digest is normalized the way `docker pull` normalizes one, e.g. "busybox"
+// becomes registry "docker.io", repository "library/busybox", tag "latest".
+//
+// ParseReference returns ErrReferenceInvalid if s is empty, or would parse
+// to an empty repository.
+func ParseReference(s string) (Reference, error) {
+	if s == "" {
+		return Reference{}, fmt.Errorf("imagetar: parse reference: %w", ErrReferenceInvalid)
+	}
+
+	name := s
+	var digest string
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		name, digest = s[:i], s[i+1:]
+		if name == "" || digest == "" {
+			return Reference{}, fmt.Errorf("imagetar: parse reference: %q: %w", s, ErrReferenceInvalid)
+		}
+	}
+
+	repo, tag := name, ""
+	if r, t, ok := splitRepoTag(name); ok {
+		repo, tag = r, t
+	}
+	if repo == "" {
+		return Reference{}, fmt.Errorf("imagetar: parse reference: %q: %w", s, ErrReferenceInvalid)
+	}
+
+	registry := ""
+	if i := strings.Index(repo, "/"); i >= 0 && isHostComponent(repo[:i]) {
+		registry, repo = repo[:i], repo[i+1:]
+	}
+	if registry == "" {
+		registry = "docker.io"
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+	}
+	if repo == "" {
+		return Reference{}, fmt.Errorf("imagetar: parse reference: %q: %w", s, ErrReferenceInvalid)
+	}
+	if tag == "" && digest == "" {
+		tag = "latest"
+	}
+
+	return Reference{Registry: registry, Repository: repo, Tag: tag, Digest: digest}, nil
+}
+
+// String returns r's canonical, fully-qualified form, e.g.
+// "docker.io/library/alpine:latest".
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Familiar returns r in the shortened form users typically type and
+// `docker` typically displays: the "docker.io" registry is dropped
+// entirely, and its "library/" repository prefix is dropped along with it;
+// any other registry is kept as part of the string.
+func (r Reference) Familiar() string {
+	repo := r.Repository
+	if r.Registry == "docker.io" {
+		repo = strings.TrimPrefix(repo, "library/")
+	} else {
+		repo = r.Registry + "/" + repo
+	}
+	if r.Tag != "" {
+		repo += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		repo += "@" + r.Digest
+	}
+	return repo
+}
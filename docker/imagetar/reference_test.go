@@ -0,0 +1,137 @@
+package imagetar
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		s    string
+		want Reference
+	}{
+		{
+			name: "Bare",
+			s:    "busybox",
+			want: Reference{Registry: "docker.io", Repository: "library/busybox", Tag: "latest"},
+		},
+		{
+			name: "BareWithTag",
+			s:    "busybox:1.36",
+			want: Reference{Registry: "docker.io", Repository: "library/busybox", Tag: "1.36"},
+		},
+		{
+			name: "PathNoHost",
+			s:    "bazel/docker/imagetar:testimage_hello_world",
+			want: Reference{Registry: "docker.io", Repository: "bazel/docker/imagetar", Tag: "testimage_hello_world"},
+		},
+		{
+			name: "ExplicitHost",
+			s:    "localhost:5000/foo:v1",
+			want: Reference{Registry: "localhost:5000", Repository: "foo", Tag: "v1"},
+		},
+		{
+			name: "DottedHost",
+			s:    "my.registry.example/team/app:v2",
+			want: Reference{Registry: "my.registry.example", Repository: "team/app", Tag: "v2"},
+		},
+		{
+			name: "Digest",
+			s:    "alpine@sha256:" + sha256Hex([]byte("x")),
+			want: Reference{Registry: "docker.io", Repository: "library/alpine", Digest: "sha256:" + sha256Hex([]byte("x"))},
+		},
+		{
+			name: "HostWithPort",
+			s:    "registry.example:5000/repo:tag",
+			want: Reference{Registry: "registry.example:5000", Repository: "repo", Tag: "tag"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseReference(tt.s)
+			if err != nil {
+				t.Fatalf("ParseReference(%q): %v", tt.s, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseReference(%q): unexpected return value (-want +got)\n%s", tt.s, diff)
+			}
+		})
+	}
+}
+
+func TestParseReference_Error(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"", "@sha256:deadbeef", "foo@"} {
+		if _, err := ParseReference(s); !errors.Is(err, ErrReferenceInvalid) {
+			t.Errorf("ParseReference(%q): err = %v; want ErrReferenceInvalid", s, err)
+		}
+	}
+}
+
+func TestReference_StringAndFamiliar(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name         string
+		ref          Reference
+		wantString   string
+		wantFamiliar string
+	}{
+		{
+			name:         "DockerLibrary",
+			ref:          Reference{Registry: "docker.io", Repository: "library/busybox", Tag: "latest"},
+			wantString:   "docker.io/library/busybox:latest",
+			wantFamiliar: "busybox:latest",
+		},
+		{
+			name:         "DockerNamespaced",
+			ref:          Reference{Registry: "docker.io", Repository: "bazel/docker/imagetar", Tag: "testimage_hello_world"},
+			wantString:   "docker.io/bazel/docker/imagetar:testimage_hello_world",
+			wantFamiliar: "bazel/docker/imagetar:testimage_hello_world",
+		},
+		{
+			name:         "OtherRegistry",
+			ref:          Reference{Registry: "localhost:5000", Repository: "foo", Tag: "v1"},
+			wantString:   "localhost:5000/foo:v1",
+			wantFamiliar: "localhost:5000/foo:v1",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.ref.String(); got != tt.wantString {
+				t.Errorf("String() = %q; want %q", got, tt.wantString)
+			}
+			if got := tt.ref.Familiar(); got != tt.wantFamiliar {
+				t.Errorf("Familiar() = %q; want %q", got, tt.wantFamiliar)
+			}
+		})
+	}
+}
+
+func TestParseReference_RoundTripsWithFamiliar(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{
+		"busybox",
+		"busybox:1.36",
+		"bazel/docker/imagetar:testimage_hello_world",
+		"localhost:5000/foo:v1",
+		"my.registry.example/team/app:v2",
+	} {
+		ref, err := ParseReference(s)
+		if err != nil {
+			t.Fatalf("ParseReference(%q): %v", s, err)
+		}
+		roundTripped, err := ParseReference(ref.Familiar())
+		if err != nil {
+			t.Fatalf("ParseReference(%q) (Familiar of %q): %v", ref.Familiar(), s, err)
+		}
+		if diff := cmp.Diff(ref, roundTripped); diff != "" {
+			t.Errorf("ParseReference(%q) did not round-trip through Familiar (-want +got)\n%s", s, diff)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package imagetar
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrWriterClosed is returned from AddLayer, AddImage, and Close when called
+// on a Writer that has already been closed.
+var ErrWriterClosed = errors.New("imagetar: writer closed")
+
+// Writer assembles a tarball in the same format Repositories, Images, and
+// Manifest read: a "manifest.json" plus legacy "repositories" file, with
+// each layer stored under a directory named by its diffID containing
+// "layer.tar" (the layer's contents), "VERSION" (the legacy format version,
+// always "1.0"), and "json" (a minimal per-layer v1-compatibility record).
+// This makes the package round-trip its own output, and produces an archive
+// `docker load` and buildkit both accept.
+//
+// Layer IDs are taken directly from the diffID passed to AddLayer, rather
+// than Docker's historical practice of chaining each layer's ID from its
+// parent's -- since nothing in this package (or its consumers) depends on
+// that chaining, using the diffID directly keeps AddLayer simple and its
+// output still valid.
+//
+// A Writer must be closed with Close to flush "manifest.json" and
+// "repositories", which can only be written once every image has been
+// added. It is not safe for concurrent use.
+type Writer struct {
+	tw     *tar.Writer
+	closed bool
+
+	layerIDs map[string]bool // diffID hex -> already written
+	entries  []ManifestEntry
+}
+
+// NewWriter returns a Writer that streams a Docker-save-compatible tarball
+// to dst as layers and images are added to it.
+func NewWriter(dst io.Writer) *Writer {
+	return &Writer{
+		tw:       tar.NewWriter(dst),
+		layerIDs: make(map[string]bool),
+	}
+}
+
+// writeEntry writes a single file entry to the archive with the given name
+// and contents.
+func (w *Writer) writeEntry(name string, contents []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(contents)),
+	}); err != nil {
+		return fmt.Errorf("imagetar: write %s: %w", name, err)
+	}
+	if _, err := w.tw.Write(contents); err != nil {
+		return fmt.Errorf("imagetar: write %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddLayer adds a single layer's tar contents, read in full from r, to the
+// archive, returning the layer ID other calls to AddLayer and AddImage
+// identify it by. size must be the exact number of bytes r will yield.
+//
+// If a layer with the same diffID (e.g. "sha256:abc...") has already been
+// added, AddLayer drains r without writing it to the archive again, and
+// returns the same ID as before -- this is what lets the same layer be
+// shared between multiple images passed to AddImage.
+func (w *Writer) AddLayer(diffID string, size int64, r io.Reader) (id string, err error) {
+	if w.closed {
+		return "", ErrWriterClosed
+	}
+	_, hexPart, err := splitDigest(diffID)
+	if err != nil {
+		return "", err
+	}
+	if w.layerIDs[hexPart] {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return "", fmt.Errorf("imagetar: add layer: %w", err)
+		}
+		return hexPart, nil
+	}
+
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name:     hexPart + "/",
+		Typeflag: tar.TypeDir,
+		Mode:     0755,
+	}); err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+	if err := w.writeEntry(hexPart+"/VERSION", []byte("1.0")); err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+	layerJSON, err := json.Marshal(struct {
+		ID string `json:"id"`
+	}{ID: hexPart})
+	if err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+	if err := w.writeEntry(hexPart+"/json", layerJSON); err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name: hexPart + "/layer.tar",
+		Mode: 0644,
+		Size: size,
+	}); err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+	if _, err := io.Copy(w.tw, r); err != nil {
+		return "", fmt.Errorf("imagetar: add layer: %w", err)
+	}
+
+	w.layerIDs[hexPart] = true
+	return hexPart, nil
+}
+
+// AddImage adds an image's config and tags to the archive: cfg is marshaled
+// and written as a blob named by its own sha256 digest, and layerIDs (the
+// IDs returned by prior AddLayer calls, in order from base to top) and tags
+// (the image's "repo:tag" strings) are recorded for manifest.json and
+// repositories, which are written out by Close.
+func (w *Writer) AddImage(cfg ImageConfig, layerIDs []string, tags []string) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	configJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("imagetar: add image: %w", err)
+	}
+	sum := sha256.Sum256(configJSON)
+	configName := hex.EncodeToString(sum[:]) + ".json"
+	if err := w.writeEntry(configName, configJSON); err != nil {
+		return fmt.Errorf("imagetar: add image: %w", err)
+	}
+
+	layers := make([]string, len(layerIDs))
+	for i, id := range layerIDs {
+		layers[i] = id + "/layer.tar"
+	}
+	w.entries = append(w.entries, ManifestEntry{
+		Config:   configName,
+		RepoTags: tags,
+		Layers:   layers,
+	})
+	return nil
+}
+
+// Close writes manifest.json and the legacy repositories file, derived from
+// every image added so far, then closes the underlying tar archive. After
+// Close, no more layers or images can be added.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+
+	manifestJSON, err := json.Marshal(w.entries)
+	if err != nil {
+		return fmt.Errorf("imagetar: close: %w", err)
+	}
+	if err := w.writeEntry("manifest.json", manifestJSON); err != nil {
+		return fmt.Errorf("imagetar: close: %w", err)
+	}
+
+	repositories := manifestToRepositories(w.entries)
+	repositoriesJSON, err := json.Marshal(repositories)
+	if err != nil {
+		return fmt.Errorf("imagetar: close: %w", err)
+	}
+	if err := w.writeEntry("repositories", repositoriesJSON); err != nil {
+		return fmt.Errorf("imagetar: close: %w", err)
+	}
+
+	if err := w.tw.Close(); err != nil {
+		return fmt.Errorf("imagetar: close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package imagetar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	t.Parallel()
+	layer1 := []byte("layer one contents")
+	layer2 := []byte("layer two contents")
+	cfg := ImageConfig{
+		Architecture: "amd64",
+		OS:           "linux",
+		Config:       ContainerConfig{Cmd: []string{"/bin/sh"}},
+		RootFS:       ImageConfigRootFS{Type: "layers", DiffIDs: []string{"sha256:" + sha256Hex(layer1), "sha256:" + sha256Hex(layer2)}},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	id1, err := w.AddLayer("sha256:"+sha256Hex(layer1), int64(len(layer1)), bytes.NewReader(layer1))
+	if err != nil {
+		t.Fatalf("AddLayer(layer1): %v", err)
+	}
+	id2, err := w.AddLayer("sha256:"+sha256Hex(layer2), int64(len(layer2)), bytes.NewReader(layer2))
+	if err != nil {
+		t.Fatalf("AddLayer(layer2): %v", err)
+	}
+	if err := w.AddImage(cfg, []string{id1, id2}, []string{"repo/image:tag"}); err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	archive := buf.Bytes()
+
+	images, err := Images(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Images: %v", err)
+	}
+	if diff := cmp.Diff([]string{"repo/image:tag"}, images); diff != "" {
+		t.Errorf("Images: unexpected return value (-want +got)\n%s", diff)
+	}
+
+	gotCfg, err := Config(bytes.NewReader(archive), "repo/image:tag")
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	if diff := cmp.Diff(cfg, gotCfg); diff != "" {
+		t.Errorf("Config: unexpected return value (-want +got)\n%s", diff)
+	}
+
+	refs, err := Layers(bytes.NewReader(archive), "repo/image:tag")
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	if got, want := len(refs), 2; got != want {
+		t.Fatalf("Layers: got %d refs; want %d", got, want)
+	}
+	for i, want := range [][]byte{layer1, layer2} {
+		rc, err := refs[i].Open()
+		if err != nil {
+			t.Fatalf("refs[%d].Open: %v", i, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("refs[%d]: read: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("refs[%d] contents = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestWriter_DedupsSharedLayer(t *testing.T) {
+	t.Parallel()
+	shared := []byte("shared layer contents")
+	diffID := "sha256:" + sha256Hex(shared)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	id1, err := w.AddLayer(diffID, int64(len(shared)), bytes.NewReader(shared))
+	if err != nil {
+		t.Fatalf("AddLayer(1): %v", err)
+	}
+	id2, err := w.AddLayer(diffID, int64(len(shared)), bytes.NewReader(shared))
+	if err != nil {
+		t.Fatalf("AddLayer(2): %v", err)
+	}
+	if id1 != id2 {
+		t.Errorf("AddLayer returned different IDs for the same diffID: %q != %q", id1, id2)
+	}
+	cfg := ImageConfig{Architecture: "amd64", OS: "linux"}
+	if err := w.AddImage(cfg, []string{id1}, []string{"repo/one:tag"}); err != nil {
+		t.Fatalf("AddImage(one): %v", err)
+	}
+	if err := w.AddImage(cfg, []string{id2}, []string{"repo/two:tag"}); err != nil {
+		t.Fatalf("AddImage(two): %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	names := 0
+	if err := VisitBlobs(bytes.NewReader(buf.Bytes()), func(name string) bool {
+		return name == id1+"/layer.tar"
+	}, func(name string, size int64, body io.Reader) error {
+		names++
+		return nil
+	}); err != nil {
+		t.Fatalf("VisitBlobs: %v", err)
+	}
+	if names != 1 {
+		t.Errorf("found %d entries for %s/layer.tar; want 1", names, id1)
+	}
+}
+
+func TestWriter_ClosedErrors(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := w.AddLayer("sha256:deadbeef", 0, bytes.NewReader(nil)); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("AddLayer after Close: err = %v; want ErrWriterClosed", err)
+	}
+	if err := w.AddImage(ImageConfig{}, nil, nil); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("AddImage after Close: err = %v; want ErrWriterClosed", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("second Close: err = %v; want ErrWriterClosed", err)
+	}
+}
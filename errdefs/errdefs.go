@@ -0,0 +1,171 @@
+// Package errdefs defines a small taxonomy of error conditions, expressed as
+// sentinel interfaces an error can implement, along with predicate functions
+// to test for them and a ToGRPCStatus function to map them to gRPC status
+// codes.
+//
+// It plays the role the Docker engine's api/errdefs package plays there:
+// code that doesn't know (or care) that it will eventually be exposed over
+// gRPC can return a plain error that merely identifies what kind of problem
+// occurred, and a transport layer like UnaryServerInterceptor below can
+// still translate that into the right response code. This avoids every
+// return site having to import codes/status and pick a code itself, and the
+// anti-pattern of callers having to string-match error messages to tell
+// error conditions apart.
+package errdefs
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotFound is implemented by errors indicating that a requested resource
+// does not exist.
+type ErrNotFound interface {
+	NotFound()
+}
+
+// ErrInvalidArgument is implemented by errors indicating that a request had
+// an invalid argument.
+type ErrInvalidArgument interface {
+	InvalidArgument()
+}
+
+// ErrAlreadyExists is implemented by errors indicating that a resource the
+// caller tried to create already exists.
+type ErrAlreadyExists interface {
+	AlreadyExists()
+}
+
+// ErrPermissionDenied is implemented by errors indicating that the caller
+// does not have permission to perform the requested operation.
+type ErrPermissionDenied interface {
+	PermissionDenied()
+}
+
+// ErrUnauthenticated is implemented by errors indicating that the request
+// does not have valid authentication credentials.
+type ErrUnauthenticated interface {
+	Unauthenticated()
+}
+
+// ErrFailedPrecondition is implemented by errors indicating that the
+// request was rejected because the system is not in a state required for
+// the operation to proceed.
+type ErrFailedPrecondition interface {
+	FailedPrecondition()
+}
+
+// ErrUnavailable is implemented by errors indicating that the service is
+// currently unavailable, and that the caller can likely retry the request.
+type ErrUnavailable interface {
+	Unavailable()
+}
+
+// ErrInternal is implemented by errors indicating that an invariant inside
+// the system was broken. These are not expected to be handled by the
+// caller.
+type ErrInternal interface {
+	Internal()
+}
+
+// is reports whether any error in err's chain (as defined by errors.Unwrap)
+// implements T.
+func is[T any](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
+
+// IsNotFound reports whether err (or any error in its chain) implements
+// ErrNotFound.
+func IsNotFound(err error) bool { return is[ErrNotFound](err) }
+
+// IsInvalidArgument reports whether err (or any error in its chain)
+// implements ErrInvalidArgument.
+func IsInvalidArgument(err error) bool { return is[ErrInvalidArgument](err) }
+
+// IsAlreadyExists reports whether err (or any error in its chain)
+// implements ErrAlreadyExists.
+func IsAlreadyExists(err error) bool { return is[ErrAlreadyExists](err) }
+
+// IsPermissionDenied reports whether err (or any error in its chain)
+// implements ErrPermissionDenied.
+func IsPermissionDenied(err error) bool { return is[ErrPermissionDenied](err) }
+
+// IsUnauthenticated reports whether err (or any error in its chain)
+// implements ErrUnauthenticated.
+func IsUnauthenticated(err error) bool { return is[ErrUnauthenticated](err) }
+
+// IsFailedPrecondition reports whether err (or any error in its chain)
+// implements ErrFailedPrecondition.
+func IsFailedPrecondition(err error) bool { return is[ErrFailedPrecondition](err) }
+
+// IsUnavailable reports whether err (or any error in its chain) implements
+// ErrUnavailable.
+func IsUnavailable(err error) bool { return is[ErrUnavailable](err) }
+
+// IsInternal reports whether err (or any error in its chain) implements
+// ErrInternal.
+func IsInternal(err error) bool { return is[ErrInternal](err) }
+
+// ToGRPCStatus maps err to a gRPC status. If err already carries a gRPC
+// status (e.g. it was created with status.Error, or wraps such an error),
+// that status is returned as-is, so callers that already construct a
+// precisely-coded status aren't second-guessed. Otherwise, the predicates
+// above are consulted, in the order listed, to pick the most specific
+// applicable code, walking err's chain via errors.Unwrap the same way
+// errors.As does, so a cause wrapped with fmt.Errorf's %w (à la
+// pkg/errors) is still classified correctly.
+//
+// If err is nil, ToGRPCStatus returns a status with code codes.OK. If none
+// of the predicates match, the returned status has code codes.Internal:
+// an error that hasn't identified itself as one of the above cases is
+// assumed to be a bug rather than something the caller can act on.
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	switch {
+	case IsNotFound(err):
+		return status.New(codes.NotFound, err.Error())
+	case IsInvalidArgument(err):
+		return status.New(codes.InvalidArgument, err.Error())
+	case IsAlreadyExists(err):
+		return status.New(codes.AlreadyExists, err.Error())
+	case IsPermissionDenied(err):
+		return status.New(codes.PermissionDenied, err.Error())
+	case IsUnauthenticated(err):
+		return status.New(codes.Unauthenticated, err.Error())
+	case IsFailedPrecondition(err):
+		return status.New(codes.FailedPrecondition, err.Error())
+	case IsUnavailable(err):
+		return status.New(codes.Unavailable, err.Error())
+	default:
+		return status.New(codes.Internal, err.Error())
+	}
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that passes
+// every error a handler returns through ToGRPCStatus, unless it's already
+// a gRPC status error. This lets handlers just `return err` for an error
+// implementing one of the interfaces above and still get a correctly-coded
+// response, instead of having to construct a *status.Status themselves at
+// every return site.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := status.FromError(err); ok {
+			return resp, err
+		}
+		return resp, ToGRPCStatus(err).Err()
+	}
+}
@@ -0,0 +1,114 @@
+package errdefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type notFoundErr struct{ msg string }
+
+func (e *notFoundErr) Error() string { return e.msg }
+func (e *notFoundErr) NotFound()     {}
+
+type invalidArgErr struct{ msg string }
+
+func (e *invalidArgErr) Error() string    { return e.msg }
+func (e *invalidArgErr) InvalidArgument() {}
+
+func TestPredicates(t *testing.T) {
+	nf := &notFoundErr{msg: "not found"}
+	ia := &invalidArgErr{msg: "invalid"}
+
+	if !IsNotFound(nf) {
+		t.Errorf("IsNotFound(%v) = false; want true", nf)
+	}
+	if IsInvalidArgument(nf) {
+		t.Errorf("IsInvalidArgument(%v) = true; want false", nf)
+	}
+	if !IsInvalidArgument(ia) {
+		t.Errorf("IsInvalidArgument(%v) = false; want true", ia)
+	}
+	if IsNotFound(ia) {
+		t.Errorf("IsNotFound(%v) = true; want false", ia)
+	}
+
+	// The predicates must see through a wrapped error chain.
+	wrapped := fmt.Errorf("while doing something: %w", nf)
+	if !IsNotFound(wrapped) {
+		t.Errorf("IsNotFound(%v) = false; want true (wrapped error)", wrapped)
+	}
+}
+
+func TestToGRPCStatus(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "Nil", err: nil, want: codes.OK},
+		{name: "NotFound", err: &notFoundErr{msg: "no such thing"}, want: codes.NotFound},
+		{name: "InvalidArgument", err: &invalidArgErr{msg: "bad input"}, want: codes.InvalidArgument},
+		{name: "WrappedNotFound", err: fmt.Errorf("context: %w", &notFoundErr{msg: "no such thing"}), want: codes.NotFound},
+		{name: "PlainError", err: errors.New("something broke"), want: codes.Internal},
+		{name: "AlreadyAStatus", err: status.Error(codes.PermissionDenied, "nope"), want: codes.PermissionDenied},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToGRPCStatus(tt.err).Code(); got != tt.want {
+				t.Errorf("ToGRPCStatus(%v).Code() = %v; want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	for _, tt := range []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		wantCode codes.Code
+	}{
+		{
+			name: "Success",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return "ok", nil
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "PlainErrorBecomesInternal",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return nil, errors.New("boom")
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name: "SentinelErrorGetsMapped",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return nil, &notFoundErr{msg: "no such thing"}
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "ExistingStatusIsPreserved",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return nil, status.Error(codes.FailedPrecondition, "nope")
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(context.Background(), nil, info, tt.handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("interceptor(...) code = %v; want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package gcp
 import (
 	"flag"
 	"fmt"
-	"strings"
 	"testing"
 
+	"github.com/bazelbuild/buildtools/build"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"go.saser.se/runfiles"
@@ -16,6 +16,13 @@ func init() {
 	klog.InitFlags(flag.CommandLine)
 }
 
+// checkValues additionally compares the RHS values of constants.bzl against
+// constants.bzl.template in TestConstants, not just the set of names. This
+// is opt-in because not every constant is expected to have the same value
+// in both files -- constants.bzl.template often has placeholder values for
+// things like project IDs that differ per environment.
+var checkValues = flag.Bool("check_values", false, "In TestConstants, also compare the RHS value of every constant against constants.bzl.template, not just its name.")
+
 // constantDeclaration represents a "CONSTANT = <value>" declaration in
 // Starlark.
 type constantDeclaration struct {
@@ -24,44 +31,41 @@ type constantDeclaration struct {
 	Value string
 }
 
-// parseConstants is a crude Starlark parser that only accepts constants of the form:
-//
-//	CONSTANT = <value>
+// parseConstants parses bzl as Starlark and returns a map from constant name
+// to its corresponding top-level "CONSTANT = <value>" declaration. Only
+// top-level assignments are recorded; assignments nested inside an if/else
+// block or a function body are not, the same way they wouldn't be visible
+// as top-level names if this were real Starlark evaluation. Everything
+// else at the top level -- load() statements, docstrings, comments,
+// function/macro definitions -- is ignored rather than rejected.
 //
-// It returns a map from constant name to its corresponding declaration, or an
-// error if it cannot parse the given Starlark code.
+// It returns an error if bzl isn't syntactically valid Starlark, or if the
+// same constant name is assigned more than once.
 func parseConstants(bzl string) (map[string]constantDeclaration, error) {
+	f, err := build.Parse("constants.bzl", []byte(bzl))
+	if err != nil {
+		return nil, fmt.Errorf("parse constants: %w", err)
+	}
 	decls := make(map[string]constantDeclaration)
-	for i, line := range strings.Split(bzl, "\n") {
-		lineNumber := i + 1
-		// We don't care about leading or trailing spaces.
-		line = strings.TrimSpace(line)
-		// If the line is empty, skip it.
-		if line == "" {
+	for _, stmt := range f.Stmt {
+		assign, ok := stmt.(*build.AssignExpr)
+		if !ok {
+			klog.V(1).Infof("Skipping non-assignment top-level statement: %T", stmt)
 			continue
 		}
-		// If the first character (after trimming spaces) is a '#' or a '"', we
-		// assume it's either a comment or a docstring so we skip it.
-		if c := line[0]; c == '#' || c == '"' {
-			klog.V(1).Infof("Assuming line %d is a comment: %q", lineNumber, line)
+		ident, ok := assign.LHS.(*build.Ident)
+		if !ok {
+			klog.V(1).Infof("Skipping assignment whose LHS isn't a plain identifier: %T", assign.LHS)
 			continue
 		}
-		// We assume the line looks like this:
-		// CONSTANT = <value>
-		// with any number of spaces surrounding the '=' character.
-		name, value, found := strings.Cut(line, "=")
-		if !found {
-			return nil, fmt.Errorf("line %d doesn't follow format %q: %q", lineNumber, "CONSTANT = <value>", line)
-		}
-		name = strings.TrimSpace(name)
-		value = strings.TrimSpace(value)
-		if d, exists := decls[name]; exists {
-			return nil, fmt.Errorf("line %d defines %q, already defined as %q = %q on line %d", lineNumber, name, d.Name, d.Value, d.Line)
+		start, _ := assign.Span()
+		if d, exists := decls[ident.Name]; exists {
+			return nil, fmt.Errorf("line %d defines %q, already defined as %q = %q on line %d", start.Line, ident.Name, d.Name, d.Value, d.Line)
 		}
-		decls[name] = constantDeclaration{
-			Line:  lineNumber,
-			Name:  name,
-			Value: value,
+		decls[ident.Name] = constantDeclaration{
+			Line:  start.Line,
+			Name:  ident.Name,
+			Value: build.FormatString(assign.RHS),
 		}
 	}
 	return decls, nil
@@ -88,6 +92,19 @@ func TestConstants(t *testing.T) {
 	if diff := cmp.Diff(template, real, cmpopts.SortSlices(less)); diff != "" {
 		t.Errorf("Unexpected diff between defined constant names (-template +real)\n%s", diff)
 	}
+
+	if !*checkValues {
+		return
+	}
+	for name, templateDecl := range templateConstants {
+		realDecl, ok := realConstants[name]
+		if !ok {
+			continue // Already reported above.
+		}
+		if templateDecl.Value != realDecl.Value {
+			t.Errorf("constants.bzl:%d: %s = %s; constants.bzl.template:%d has %s = %s", realDecl.Line, name, realDecl.Value, templateDecl.Line, name, templateDecl.Value)
+		}
+	}
 }
 
 func mapKeys[K comparable, V any](m map[K]V) []K {
@@ -97,3 +114,109 @@ func mapKeys[K comparable, V any](m map[K]V) []K {
 	}
 	return keys
 }
+
+func TestParseConstants(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		bzl     string
+		want    map[string]constantDeclaration
+		wantErr bool
+	}{
+		{
+			name: "Simple",
+			bzl: `FOO = "bar"
+BAZ = 123
+`,
+			want: map[string]constantDeclaration{
+				"FOO": {Line: 1, Name: "FOO", Value: `"bar"`},
+				"BAZ": {Line: 2, Name: "BAZ", Value: "123"},
+			},
+		},
+		{
+			name: "DocstringAndComments",
+			bzl: `"""This is a module docstring."""
+
+# This is a comment.
+FOO = "bar"  # trailing comment
+`,
+			want: map[string]constantDeclaration{
+				"FOO": {Line: 4, Name: "FOO", Value: `"bar"`},
+			},
+		},
+		{
+			name: "LoadStatement",
+			bzl: `load("@bazel_skylib//lib:paths.bzl", "paths")
+
+FOO = "bar"
+`,
+			want: map[string]constantDeclaration{
+				"FOO": {Line: 3, Name: "FOO", Value: `"bar"`},
+			},
+		},
+		{
+			name: "MultilineString",
+			bzl: `FOO = """
+multi
+line
+"""
+BAR = "baz"
+`,
+			want: map[string]constantDeclaration{
+				"FOO": {Line: 1, Name: "FOO", Value: "\"\"\"\nmulti\nline\n\"\"\""},
+				"BAR": {Line: 5, Name: "BAR", Value: `"baz"`},
+			},
+		},
+		{
+			name: "ListLiteralSpanningLines",
+			bzl: `FOO = [
+    "a",
+    "b",
+]
+`,
+			want: map[string]constantDeclaration{
+				"FOO": {Line: 1, Name: "FOO", Value: "[\n    \"a\",\n    \"b\",\n]"},
+			},
+		},
+		{
+			name: "IfElseBlockIgnored",
+			bzl: `if True:
+    FOO = "a"
+else:
+    FOO = "b"
+
+BAR = "c"
+`,
+			want: map[string]constantDeclaration{
+				"BAR": {Line: 6, Name: "BAR", Value: `"c"`},
+			},
+		},
+		{
+			name: "DuplicateWithDifferentWhitespace",
+			bzl: `FOO="a"
+FOO = "b"
+`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid",
+			bzl:     "this is not valid Starlark +++",
+			wantErr: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseConstants(tt.bzl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConstants(...) err = %v; wantErr = %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseConstants(...): unexpected return value (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 // Package klogger is a grpclog implementation for k8s.io/klog/v2. It is very
 // similar to google.golang.org/grpc/grpclog/glogger but uses klog instead.
+//
+// By default, importing this package for its init side effect installs an
+// unstructured logger that formats like glogger. Call Install(true) to
+// switch to a structured logger that routes through klog's InfoSDepth and
+// ErrorSDepth instead.
 package klogger
 
 import (
@@ -84,3 +89,116 @@ func (g *klogger) FatalDepth(depth int, args ...interface{}) {
 func (g *klogger) V(l int) bool {
 	return klog.V(klog.Level(l)).Enabled()
 }
+
+// structured is a DepthLoggerV2 that routes calls through klog's structured
+// logging APIs (InfoSDepth/ErrorSDepth) instead of the legacy
+// Sprint/Sprintf-based ones. The message is taken to be the first argument,
+// and any remaining arguments are passed through as alternating key/value
+// pairs, mirroring how klog.InfoS expects to be called.
+type structured struct{}
+
+// NewStructured returns a grpclog.DepthLoggerV2 that emits structured
+// key/value log entries via klog.InfoSDepth/ErrorSDepth rather than the
+// unstructured klogger.
+func NewStructured() grpclog.DepthLoggerV2 {
+	return &structured{}
+}
+
+// splitMsgAndKVs treats the first element of args as the log message, and
+// the rest as key/value pairs. If args is empty, the message is empty.
+func splitMsgAndKVs(args []interface{}) (string, []interface{}) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	msg := fmt.Sprint(args[0])
+	return msg, args[1:]
+}
+
+func (s *structured) Info(args ...interface{}) {
+	s.InfoDepth(0, args...)
+}
+
+func (s *structured) Infoln(args ...interface{}) {
+	s.InfoDepth(0, args...)
+}
+
+func (s *structured) Infof(format string, args ...interface{}) {
+	klog.InfoSDepth(d, fmt.Sprintf(format, args...))
+}
+
+func (s *structured) InfoDepth(depth int, args ...interface{}) {
+	msg, kvs := splitMsgAndKVs(args)
+	klog.InfoSDepth(depth+d, msg, kvs...)
+}
+
+// klog v2 has no structured equivalent of Warning, so warnings are logged
+// through InfoSDepth like everything else below Error.
+func (s *structured) Warning(args ...interface{}) {
+	s.WarningDepth(0, args...)
+}
+
+func (s *structured) Warningln(args ...interface{}) {
+	s.WarningDepth(0, args...)
+}
+
+func (s *structured) Warningf(format string, args ...interface{}) {
+	klog.InfoSDepth(d, fmt.Sprintf(format, args...))
+}
+
+func (s *structured) WarningDepth(depth int, args ...interface{}) {
+	msg, kvs := splitMsgAndKVs(args)
+	klog.InfoSDepth(depth+d, msg, kvs...)
+}
+
+func (s *structured) Error(args ...interface{}) {
+	s.ErrorDepth(0, args...)
+}
+
+func (s *structured) Errorln(args ...interface{}) {
+	s.ErrorDepth(0, args...)
+}
+
+func (s *structured) Errorf(format string, args ...interface{}) {
+	klog.ErrorSDepth(d, nil, fmt.Sprintf(format, args...))
+}
+
+func (s *structured) ErrorDepth(depth int, args ...interface{}) {
+	msg, kvs := splitMsgAndKVs(args)
+	klog.ErrorSDepth(depth+d, nil, msg, kvs...)
+}
+
+func (s *structured) Fatal(args ...interface{}) {
+	s.FatalDepth(0, args...)
+}
+
+func (s *structured) Fatalln(args ...interface{}) {
+	s.FatalDepth(0, args...)
+}
+
+func (s *structured) Fatalf(format string, args ...interface{}) {
+	klog.ErrorSDepth(d, nil, fmt.Sprintf(format, args...))
+	klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+}
+
+func (s *structured) FatalDepth(depth int, args ...interface{}) {
+	msg, kvs := splitMsgAndKVs(args)
+	klog.ErrorSDepth(depth+d, nil, msg, kvs...)
+	klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+}
+
+func (s *structured) V(l int) bool {
+	return klog.V(klog.Level(l)).Enabled()
+}
+
+// Install registers this package's grpclog.LoggerV2 implementation. When
+// structured is true, the structured (InfoSDepth/ErrorSDepth-based) logger is
+// installed instead of the default unstructured one. Binaries that merely
+// import this package for its init side effect keep getting the unstructured
+// logger, so their output format doesn't change.
+func Install(structured bool) {
+	if structured {
+		grpclog.SetLoggerV2(NewStructured())
+		return
+	}
+	grpclog.SetLoggerV2(&klogger{})
+}
@@ -0,0 +1,45 @@
+// Package grpcmetrics provides a gRPC server interceptor that records
+// Prometheus metrics for unary RPCs: a counter of requests by method and
+// status code, and a histogram of handler latency by method.
+package grpcmetrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_requests_total",
+		Help: "Total number of unary gRPC requests, by method and status code.",
+	}, []string{"method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Duration of unary gRPC handler calls, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestDuration)
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that records
+// the two metrics above for every unary RPC it handles. They're registered
+// against the default Prometheus registry, so whatever serves
+// promhttp.Handler() (typically an admin HTTP server's /metrics endpoint)
+// picks them up automatically.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		requestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		requestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
@@ -0,0 +1,50 @@
+// Package grpcrecovery provides gRPC server interceptors that recover from
+// panics in a handler and turn them into codes.Internal errors, instead of
+// letting the panic cross the RPC boundary and take down the whole process.
+package grpcrecovery
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// recover turns a recovered panic value into a codes.Internal status error,
+// logging the panic and its stack trace first since the client only ever
+// sees the generic message below.
+func recoverToStatus(r any) error {
+	klog.Errorf("panic recovered in gRPC handler: %v\n%s", r, debug.Stack())
+	return status.Error(codes.Internal, fmt.Sprintf("panic recovered in handler: %v", r))
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that recovers
+// from any panic in the handler (or in interceptors chained after it) and
+// returns it as a codes.Internal error. It should be the first interceptor
+// in a chain, so that it can recover panics from every interceptor it wraps.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverToStatus(r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming equivalent of UnaryServerInterceptor.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverToStatus(r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -12,8 +12,33 @@ import (
 	"go.saser.se/runfiles"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
 )
 
+// Transport selects the mechanism used to connect the client and server set
+// up by New.
+type Transport int
+
+const (
+	// TransportTCP serves on a loopback TCP listener on an OS-assigned port
+	// and dials it over TLS. This is the default, and is required for tests
+	// that need to dial the server's real address themselves.
+	TransportTCP Transport = iota
+	// TransportBufconn serves and dials over an in-memory bufconn.Listener,
+	// bypassing the network stack and TLS entirely. This is much cheaper to
+	// set up, which matters for tests that spin up many servers.
+	TransportBufconn
+)
+
+// bufconnAddress is the synthetic Server.Address reported when using
+// TransportBufconn, where there is no real network address.
+const bufconnAddress = "bufconn"
+
+// bufconnBufSize is the size of the in-memory buffer backing a bufconn
+// listener.
+const bufconnBufSize = 1 << 20
+
 var (
 	certFile = runfiles.MustPath("grpctest/test_cert.pem")
 	keyFile  = runfiles.MustPath("grpctest/test_key.pem")
@@ -47,6 +72,11 @@ type Server struct {
 	Address string
 	// ClientConn is pre-dialed and ready for use.
 	ClientConn *grpc.ClientConn
+	// GRPCServer is the underlying *grpc.Server, exposed so that tests can
+	// register additional services or inspect it (e.g., stats handlers)
+	// before traffic starts flowing. It is only valid to call
+	// RegisterService on it before the test using it returns.
+	GRPCServer *grpc.Server
 }
 
 // Options configures how the server and client connection should be set up.
@@ -58,6 +88,25 @@ type Options struct {
 	ServiceDesc *grpc.ServiceDesc
 	// Implementation must implement ServiceDesc.
 	Implementation any
+	// Transport selects how the client and server are connected. The zero
+	// value is TransportTCP.
+	Transport Transport
+
+	// UnaryInterceptors are chained, in order, in front of the server's
+	// unary RPC handlers.
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// StreamInterceptors are chained, in order, in front of the server's
+	// streaming RPC handlers.
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// ServerOptions are appended after the options this package sets up
+	// itself (e.g., transport credentials), so they can be used to override
+	// the defaults.
+	ServerOptions []grpc.ServerOption
+	// DialOptions are appended after the options this package sets up itself
+	// on the pre-dialed ClientConn.
+	DialOptions []grpc.DialOption
+	// PerRPCCredentials, if set, is attached to the pre-dialed ClientConn.
+	PerRPCCredentials credentials.PerRPCCredentials
 }
 
 // New sets up a Server and arranges for all associated resources to be cleaned up when the test ends.
@@ -80,12 +129,40 @@ func New(ctx context.Context, tb testing.TB, opts Options) *Server {
 		tb.FailNow()
 	}
 
-	// Only listen on localhost. Using 0 as the port number will make the
-	// operating system allocate a port for us.
-	const listenAddr = "localhost:0"
-	lis, err := net.Listen("tcp", listenAddr)
-	if err != nil {
-		tb.Fatalf("failed to create listener on %q: %v", listenAddr, err)
+	var (
+		lis      net.Listener
+		addr     string
+		srvOpts  []grpc.ServerOption
+		dialOpts []grpc.DialOption
+	)
+	switch opts.Transport {
+	case TransportBufconn:
+		bl := bufconn.Listen(bufconnBufSize)
+		lis = bl
+		addr = bufconnAddress
+		srvOpts = nil
+		dialOpts = []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return bl.Dial()
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+	default:
+		// Only listen on localhost. Using 0 as the port number will make the
+		// operating system allocate a port for us.
+		const listenAddr = "localhost:0"
+		var err error
+		lis, err = net.Listen("tcp", listenAddr)
+		if err != nil {
+			tb.Fatalf("failed to create listener on %q: %v", listenAddr, err)
+		}
+		addr = lis.Addr().String()
+		srvOpts = []grpc.ServerOption{
+			grpc.Creds(serverCredentials(tb)),
+		}
+		dialOpts = []grpc.DialOption{
+			grpc.WithTransportCredentials(clientCredentials(tb)),
+		}
 	}
 	tb.Cleanup(func() {
 		// The listener will be used for the gRPC server we will start up later.
@@ -93,13 +170,15 @@ func New(ctx context.Context, tb testing.TB, opts Options) *Server {
 		// results in a net.ErrClosed error. Therefore, we only fail the test if
 		// we get some other error.
 		if err := lis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
-			tb.Errorf("failed to close listener on %q: %v", listenAddr, err)
+			tb.Errorf("failed to close listener on %q: %v", addr, err)
 		}
 	})
 
-	srvOpts := []grpc.ServerOption{
-		grpc.Creds(serverCredentials(tb)),
-	}
+	srvOpts = append(srvOpts,
+		grpc.ChainUnaryInterceptor(opts.UnaryInterceptors...),
+		grpc.ChainStreamInterceptor(opts.StreamInterceptors...),
+	)
+	srvOpts = append(srvOpts, opts.ServerOptions...)
 	srv := grpc.NewServer(srvOpts...)
 	srv.RegisterService(opts.ServiceDesc, opts.Implementation)
 
@@ -115,9 +194,9 @@ func New(ctx context.Context, tb testing.TB, opts Options) *Server {
 	}()
 	tb.Cleanup(srv.GracefulStop)
 
-	addr := lis.Addr().String()
-	dialOpts := []grpc.DialOption{
-		grpc.WithTransportCredentials(clientCredentials(tb)),
+	dialOpts = append(dialOpts, opts.DialOptions...)
+	if opts.PerRPCCredentials != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(opts.PerRPCCredentials))
 	}
 	cc, err := grpc.DialContext(ctx, addr, dialOpts...)
 	if err != nil {
@@ -132,5 +211,6 @@ func New(ctx context.Context, tb testing.TB, opts Options) *Server {
 	return &Server{
 		Address:    addr,
 		ClientConn: cc,
+		GRPCServer: srv,
 	}
 }
@@ -58,3 +58,40 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+// TestNewBufconn exercises the TransportBufconn mode, which doesn't touch the
+// network stack and so can run with far higher concurrency than TestNew.
+func TestNewBufconn(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	for i := 0; i < concurrency; i++ {
+		t.Run(fmt.Sprintf("%06d", i), func(t *testing.T) {
+			t.Parallel()
+
+			srv := New(ctx, t, Options{
+				ServiceDesc:    &echopb.Echo_ServiceDesc,
+				Implementation: echo.Server{},
+				Transport:      TransportBufconn,
+			})
+
+			if got, want := srv.Address, bufconnAddress; got != want {
+				t.Errorf("srv.Address = %q; want %q", got, want)
+			}
+			if srv.ClientConn == nil {
+				t.Errorf("srv.ClientConn = %v; want non-nil", srv.ClientConn)
+			}
+
+			client := echopb.NewEchoClient(srv.ClientConn)
+			const msg = "I'm using servertest over bufconn"
+			req := &echopb.EchoRequest{Message: msg}
+			res, err := client.Echo(ctx, req)
+			if err != nil {
+				t.Errorf("Echo(%v) err = %v; want nil", req, err)
+			}
+			if got, want := res.GetMessage(), msg; got != want {
+				t.Errorf("Echo(%v) message = %q; want %q", req, got, want)
+			}
+		})
+	}
+}
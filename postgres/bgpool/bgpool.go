@@ -0,0 +1,72 @@
+// Package bgpool provides a small supervisor for background goroutines --
+// schema migration checks, LISTEN/NOTIFY listeners, periodic sweeps -- whose
+// lifetime is tied to a context, with graceful shutdown, panic recovery, and
+// a Wait that blocks until every worker has actually returned.
+package bgpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.saser.se/postgres/internal/timerpool"
+	"k8s.io/klog/v2"
+)
+
+// Pool supervises a set of background goroutines started via Go and
+// GoTicker. The zero value is ready to use.
+type Pool struct {
+	wg sync.WaitGroup
+}
+
+// Go runs fn in its own goroutine, tied to ctx: fn is expected to notice
+// ctx.Done() and return once it fires. A panic inside fn is recovered and
+// logged rather than crashing the process, since one background worker
+// failing shouldn't take down whatever else the process is doing. Call Wait
+// to block until every goroutine started this way has returned.
+func (p *Pool) Go(ctx context.Context, fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				klog.Errorf("bgpool: recovered from panic in background goroutine: %v", r)
+			}
+		}()
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			klog.Errorf("bgpool: background goroutine returned error: %v", err)
+		}
+	}()
+}
+
+// GoTicker runs fn every interval, tied to ctx the same way Go is, until ctx
+// is done. Unlike a loop built on a raw time.Ticker, the timer driving this
+// loop comes from the package-shared timerpool and is returned after every
+// tick instead of living for the loop's whole lifetime, which matters for a
+// service running many such loops (e.g. several independent expiry sweeps)
+// for as long as it's up.
+func (p *Pool) GoTicker(ctx context.Context, interval time.Duration, fn func(ctx context.Context) error) {
+	p.Go(ctx, func(ctx context.Context) error {
+		for {
+			timer := timerpool.Get(interval)
+			select {
+			case <-ctx.Done():
+				timerpool.Put(timer)
+				return nil
+			case <-timer.C:
+				timerpool.Put(timer)
+			}
+			if err := fn(ctx); err != nil {
+				klog.Errorf("bgpool: ticker callback returned error: %v", err)
+			}
+		}
+	})
+}
+
+// Wait blocks until every goroutine started via Go or GoTicker has
+// returned. The usual graceful-shutdown sequence is to cancel the context
+// passed to Go/GoTicker, then call Wait, so shutdown blocks until in-flight
+// work has actually finished instead of abandoning it.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,102 @@
+package bgpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_Go_RunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var running atomic.Bool
+	var p Pool
+	p.Go(ctx, func(ctx context.Context) error {
+		running.Store(true)
+		<-ctx.Done()
+		return nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for !running.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("goroutine never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after ctx was cancelled")
+	}
+}
+
+func TestPool_Go_RecoversPanic(t *testing.T) {
+	var p Pool
+	p.Go(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	})
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after fn panicked; panic was not recovered")
+	}
+}
+
+func TestPool_Go_ErrorDoesNotPanic(t *testing.T) {
+	var p Pool
+	p.Go(context.Background(), func(ctx context.Context) error {
+		return errors.New("some error")
+	})
+	p.Wait() // Must return without the test process crashing.
+}
+
+func TestPool_GoTicker_FiresRepeatedlyThenStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ticks atomic.Int32
+	var p Pool
+	p.GoTicker(ctx, time.Millisecond, func(ctx context.Context) error {
+		ticks.Add(1)
+		return nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for ticks.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("only got %d ticks in 1s; want at least 3", ticks.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after ctx was cancelled")
+	}
+
+	// No more ticks should land once Wait has returned.
+	after := ticks.Load()
+	time.Sleep(10 * time.Millisecond)
+	if got := ticks.Load(); got != after {
+		t.Errorf("ticks fired after cancellation: %d -> %d", after, got)
+	}
+}
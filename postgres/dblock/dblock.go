@@ -0,0 +1,199 @@
+// Package dblock provides distributed locking built on Postgres advisory
+// locks, letting multiple replicas of a service coordinate on a named key
+// without an external coordinator such as etcd or Zookeeper.
+package dblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.saser.se/postgres"
+)
+
+// livenessCheckInterval is how often RunLocked pings the connection pinned
+// by its held lock, to notice if it's dropped out from under it.
+const livenessCheckInterval = 5 * time.Second
+
+// reacquireBackoff is how long RunLocked waits between attempts to get its
+// lock back after its connection has dropped.
+const reacquireBackoff = 500 * time.Millisecond
+
+// Locker coordinates exclusive access to named keys across replicas of a
+// service, using Postgres advisory locks (pg_advisory_lock, pg_try_advisory_lock,
+// and pg_advisory_unlock). Advisory locks are scoped to the database session
+// that took them, so every lock obtained through a Locker pins a dedicated
+// *pgxpool.Conn for as long as the lock is held; see Lock and Held.
+type Locker struct {
+	pool *postgres.Pool
+}
+
+// New returns a Locker that takes its advisory locks against pool.
+func New(pool *postgres.Pool) *Locker {
+	return &Locker{pool: pool}
+}
+
+// lockKey converts name into the int64 key that pg_advisory_lock and its
+// relatives take, by hashing it with FNV-1a. Two different names colliding
+// onto the same key is possible but exceedingly unlikely, and would only
+// ever cause two unrelated locks to (harmlessly, if rarely) contend with
+// each other.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Held is an advisory lock obtained by Lock or TryLock, pinned to the
+// *pgxpool.Conn used to take it. Advisory locks are released automatically
+// if their owning connection closes, so that connection must stay reserved
+// to this Held, and not returned to the pool, for as long as the lock
+// matters.
+type Held struct {
+	name string
+	key  int64
+	conn *pgxpool.Conn
+}
+
+// Unlock releases the lock and returns its underlying connection to the
+// pool. It is an error to call Unlock more than once on the same Held.
+func (h *Held) Unlock(ctx context.Context) error {
+	if h.conn == nil {
+		return errors.New("dblock: unlock: already unlocked")
+	}
+	_, err := h.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", h.key)
+	h.conn.Release()
+	h.conn = nil
+	if err != nil {
+		return fmt.Errorf("dblock: unlock %q: %w", h.name, err)
+	}
+	return nil
+}
+
+// Lock blocks until the named advisory lock is acquired or ctx is done,
+// whichever happens first.
+func (l *Locker) Lock(ctx context.Context, name string) (*Held, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dblock: lock %q: acquire connection: %w", name, err)
+	}
+	key := lockKey(name)
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("dblock: lock %q: %w", name, err)
+	}
+	return &Held{name: name, key: key, conn: conn}, nil
+}
+
+// TryLock attempts to acquire the named advisory lock without blocking. If
+// the lock is already held by someone else, TryLock returns a nil Held and
+// ok == false, without error.
+func (l *Locker) TryLock(ctx context.Context, name string) (held *Held, ok bool, err error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("dblock: try lock %q: acquire connection: %w", name, err)
+	}
+	key := lockKey(name)
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("dblock: try lock %q: %w", name, err)
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+	return &Held{name: name, key: key, conn: conn}, true, nil
+}
+
+// RunLocked acquires the named advisory lock, runs fn with a context
+// derived from ctx, and releases the lock before returning, including when
+// fn panics. It returns fn's error, or the error from acquiring the lock if
+// that failed.
+//
+// While fn runs, RunLocked periodically pings the connection the lock is
+// pinned to. A dropped connection silently releases the underlying advisory
+// lock -- Postgres ties the two together -- so on a failed ping RunLocked
+// tries to reacquire the same lock on a fresh connection, retrying with
+// backoff until it succeeds or ctx is done. If ctx runs out before the lock
+// can be reacquired, fn's context is cancelled, since at that point this
+// replica can no longer vouch for holding the lock.
+func (l *Locker) RunLocked(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	held, err := l.Lock(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		l.watchLiveness(runCtx, cancel, held)
+	}()
+
+	defer func() {
+		cancel()
+		<-watchDone
+		// ctx may be the reason runCtx was cancelled, so don't reuse it for
+		// the unlock itself; the connection still needs releasing either
+		// way.
+		unlockCtx := context.WithoutCancel(ctx)
+		if uerr := held.Unlock(unlockCtx); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+
+	return fn(runCtx)
+}
+
+// watchLiveness pings held's connection every livenessCheckInterval until
+// ctx is done. A failed ping means the connection -- and with it, the
+// advisory lock tied to its session -- is gone; watchLiveness then tries to
+// reacquire the lock via reacquire, swapping held's connection for the new
+// one on success. If reacquisition doesn't succeed before ctx is done,
+// cancel is called so the in-flight callback stops.
+func (l *Locker) watchLiveness(ctx context.Context, cancel context.CancelFunc, held *Held) {
+	ticker := time.NewTicker(livenessCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := held.conn.Ping(ctx); err == nil {
+				continue
+			}
+			if !l.reacquire(ctx, held) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// reacquire retries TryLock for held.name until it succeeds or ctx is done,
+// then points held at the newly acquired connection. It reports whether
+// reacquisition succeeded before ctx ran out.
+func (l *Locker) reacquire(ctx context.Context, held *Held) bool {
+	for {
+		newHeld, ok, err := l.TryLock(ctx, held.name)
+		if err == nil && ok {
+			// held.conn's Ping just failed, so its connection is dead, but it
+			// still needs releasing -- pgxpool notices a broken connection on
+			// Release and discards it instead of pooling it, but never
+			// releasing it at all would leak it out of the pool's capacity
+			// for good.
+			held.conn.Release()
+			held.conn = newHeld.conn
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(reacquireBackoff):
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package dblock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.saser.se/postgres"
+	"go.saser.se/postgres/postgrestest"
+)
+
+func TestLocker_LockUnlock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container := postgrestest.NewContainer(ctx, t, postgrestest.Options{})
+	l := New(container.Pool)
+
+	held, err := l.Lock(ctx, "TestLocker_LockUnlock")
+	if err != nil {
+		t.Fatalf("Lock() err = %v", err)
+	}
+	if err := held.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() err = %v", err)
+	}
+}
+
+func TestLocker_TryLock_Contended(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container := postgrestest.NewContainer(ctx, t, postgrestest.Options{})
+	l := New(container.Pool)
+
+	held, ok, err := l.TryLock(ctx, "TestLocker_TryLock_Contended")
+	if err != nil || !ok {
+		t.Fatalf("first TryLock() = (_, %v, %v); want (_, true, nil)", ok, err)
+	}
+
+	if _, ok, err := l.TryLock(ctx, "TestLocker_TryLock_Contended"); err != nil || ok {
+		t.Fatalf("second TryLock() while held = (_, %v, %v); want (_, false, nil)", ok, err)
+	}
+
+	if err := held.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() err = %v", err)
+	}
+
+	again, ok, err := l.TryLock(ctx, "TestLocker_TryLock_Contended")
+	if err != nil || !ok {
+		t.Fatalf("TryLock() after Unlock() = (_, %v, %v); want (_, true, nil)", ok, err)
+	}
+	if err := again.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() err = %v", err)
+	}
+}
+
+func TestLocker_RunLocked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container := postgrestest.NewContainer(ctx, t, postgrestest.Options{})
+	l := New(container.Pool)
+
+	var ran bool
+	if err := l.RunLocked(ctx, "TestLocker_RunLocked", func(ctx context.Context) error {
+		ran = true
+		return nil
+	}); err != nil {
+		t.Fatalf("RunLocked() err = %v", err)
+	}
+	if !ran {
+		t.Error("RunLocked() did not run fn")
+	}
+
+	// RunLocked must have released the lock before returning: a fresh
+	// TryLock for the same name should succeed.
+	held, ok, err := l.TryLock(ctx, "TestLocker_RunLocked")
+	if err != nil || !ok {
+		t.Fatalf("TryLock() after RunLocked() = (_, %v, %v); want (_, true, nil)", ok, err)
+	}
+	if err := held.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() err = %v", err)
+	}
+}
+
+// TestLocker_Reacquire_ReleasesStaleConnection exercises the reconnect path
+// watchLiveness drives: it kills the backend behind a Held's connection
+// (simulating the connection drop that reacquire exists to recover from) and
+// calls reacquire directly. The pool is pinned to 2 connections, so if
+// reacquire ever fails to release the dead connection it swaps out, the pool
+// runs out of capacity by the second iteration and reacquire can't get a
+// connection to retry TryLock with.
+func TestLocker_Reacquire_ReleasesStaleConnection(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container := postgrestest.NewContainer(ctx, t, postgrestest.Options{})
+	pool, err := postgres.Open(ctx, container.DSN+"?pool_max_conns=2")
+	if err != nil {
+		t.Fatalf("open pool: %v", err)
+	}
+	defer pool.Close()
+	l := New(pool)
+
+	held, err := l.Lock(ctx, "TestLocker_Reacquire_ReleasesStaleConnection")
+	if err != nil {
+		t.Fatalf("Lock() err = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		var pid int32
+		if err := held.conn.QueryRow(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+			t.Fatalf("iteration %d: query backend pid: %v", i, err)
+		}
+		if _, err := container.Pool.Exec(ctx, "SELECT pg_terminate_backend($1)", pid); err != nil {
+			t.Fatalf("iteration %d: terminate backend: %v", i, err)
+		}
+
+		reacquireCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		ok := l.reacquire(reacquireCtx, held)
+		cancel()
+		if !ok {
+			t.Fatalf("reacquire() iteration %d did not succeed before the deadline; a leaked connection would exhaust the 2-connection pool by now", i)
+		}
+	}
+
+	if err := held.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() err = %v", err)
+	}
+}
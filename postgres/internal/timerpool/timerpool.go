@@ -0,0 +1,43 @@
+// Package timerpool provides a pool of reusable [time.Timer] values, for
+// code paths (like connection retry loops) that create and discard a timer
+// on every iteration and would otherwise pay for a fresh allocation every
+// time.
+package timerpool
+
+import (
+	"sync"
+	"time"
+)
+
+var pool = sync.Pool{
+	New: func() any { return time.NewTimer(0) },
+}
+
+// Get returns a timer that will fire after d. Any previous firing left over
+// from the timer's last use is drained first, so a timer pulled from the
+// pool never fires early because of stale state; a naive Stop-then-Reset
+// sequence doesn't do this and can leak the drain onto the caller.
+func Get(d time.Duration) *time.Timer {
+	t := pool.Get().(*time.Timer)
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+	return t
+}
+
+// Put returns t to the pool for reuse. Callers must not use t after calling
+// Put. t is stopped and drained first, since it may not have fired (or been
+// drained) yet.
+func Put(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	pool.Put(t)
+}
@@ -1,5 +1,6 @@
-// Package glogadapter contains an implementation of the pgx.Logger interface
-// for the glog package.
+// Package glogadapter contains implementations of the pgx.Logger interface
+// (Logger, for pgx v4) and the tracelog.Logger interface (TraceLogger, for
+// pgx v5) for the glog package.
 package glogadapter
 
 import (
@@ -8,7 +9,9 @@ import (
 	"strings"
 
 	"github.com/golang/glog"
-	"github.com/jackc/pgx/v4"
+	pgxv4 "github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/tracelog"
 )
 
 // Logger implements pgx.Logger.
@@ -22,7 +25,7 @@ func NewLogger() *Logger {
 // Log implements pgx.Logger.Log. LogLevelTrace and LogLevelDebug are written to
 // the INFO level with a "(level)" prefix. LogLevelNone, as well as the zero
 // value for LogLevel, are written to the ERROR level with a "(level)" prefix.
-func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+func (l *Logger) Log(ctx context.Context, level pgxv4.LogLevel, msg string, data map[string]interface{}) {
 	var sb strings.Builder
 	sb.WriteString(msg)
 	sb.WriteString(" [")
@@ -37,15 +40,65 @@ func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, msg string, data m
 	sb.WriteString("]")
 	s := sb.String()
 	switch level {
-	case pgx.LogLevelTrace, pgx.LogLevelDebug:
+	case pgxv4.LogLevelTrace, pgxv4.LogLevelDebug:
 		glog.InfoDepth(2, "("+level.String()+") "+s)
-	case pgx.LogLevelInfo:
+	case pgxv4.LogLevelInfo:
 		glog.InfoDepth(2, s)
-	case pgx.LogLevelWarn:
+	case pgxv4.LogLevelWarn:
 		glog.WarningDepth(2, s)
-	case pgx.LogLevelError:
+	case pgxv4.LogLevelError:
 		glog.ErrorDepth(2, s)
 	default:
 		glog.ErrorDepth(2, "("+level.String()+") "+s)
 	}
 }
+
+// TraceLogger implements tracelog.Logger for pgx v5, which replaced the
+// pgx.Logger interface implemented by Logger above.
+type TraceLogger struct{}
+
+// NewTraceLogger returns a new TraceLogger ready for use.
+func NewTraceLogger() *TraceLogger {
+	return &TraceLogger{}
+}
+
+// Log implements tracelog.Logger.Log. LogLevelTrace and LogLevelDebug are
+// written to the INFO level with a "(level)" prefix. LogLevelNone, as well as
+// the zero value for LogLevel, are written to the ERROR level with a
+// "(level)" prefix.
+func (l *TraceLogger) Log(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]interface{}) {
+	var sb strings.Builder
+	sb.WriteString(msg)
+	sb.WriteString(" [")
+	writeSeparator := false
+	for k, v := range data {
+		if writeSeparator {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(k + "=" + fmt.Sprint(v))
+		writeSeparator = true
+	}
+	sb.WriteString("]")
+	s := sb.String()
+	switch level {
+	case tracelog.LogLevelTrace, tracelog.LogLevelDebug:
+		glog.InfoDepth(2, "("+level.String()+") "+s)
+	case tracelog.LogLevelInfo:
+		glog.InfoDepth(2, s)
+	case tracelog.LogLevelWarn:
+		glog.WarningDepth(2, s)
+	case tracelog.LogLevelError:
+		glog.ErrorDepth(2, s)
+	default:
+		glog.ErrorDepth(2, "("+level.String()+") "+s)
+	}
+}
+
+// NewQueryTracer returns a pgx.QueryTracer that logs through a TraceLogger at
+// LogLevelTrace, for use as pgxpool.Config.ConnConfig.Tracer.
+func NewQueryTracer() pgx.QueryTracer {
+	return &tracelog.TraceLog{
+		Logger:   NewTraceLogger(),
+		LogLevel: tracelog.LogLevelTrace,
+	}
+}
@@ -0,0 +1,190 @@
+// Package metrics provides a pgx tracer and pool-stat gauges that report
+// Prometheus metrics for a postgres.Pool, so services embedding it don't
+// each have to reimplement the same instrumentation boilerplate.
+package metrics
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.saser.se/postgres"
+)
+
+// statementNamePattern extracts the name= tag from a SQL comment like
+// "/* name=ListTasks */ SELECT ...". Using a caller-supplied tag, rather
+// than the query text itself, keeps the "statement" label's cardinality
+// bounded and avoids leaking parameter values that might be inlined into a
+// query string.
+var statementNamePattern = regexp.MustCompile(`/\*\s*name=(\w+)\s*\*/`)
+
+// unknownStatement labels queries that don't carry a name= tag.
+const unknownStatement = "unknown"
+
+func statementName(sql string) string {
+	if m := statementNamePattern.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+	return unknownStatement
+}
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "postgres_query_duration_seconds",
+		Help:    "Duration of queries run through a traced postgres.Pool, by statement name.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"statement"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "postgres_query_errors_total",
+		Help: "Total number of queries run through a traced postgres.Pool that returned an error, by statement name.",
+	}, []string{"statement"})
+
+	reconnectAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "postgres_reconnect_attempts_total",
+		Help: "Total number of times postgres.Open has retried connecting after a failed attempt.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryErrors, reconnectAttempts)
+}
+
+// ReconnectAttempted records one retry of postgres.Open's connect loop.
+// Pass it as postgres.OpenOptions.OnRetry to wire it up.
+func ReconnectAttempted() {
+	reconnectAttempts.Inc()
+}
+
+// contextKey namespaces the values Tracer stashes on the context between
+// TraceQueryStart and TraceQueryEnd.
+type contextKey int
+
+const (
+	statementNameKey contextKey = iota
+	queryStartKey
+)
+
+// Tracer implements pgx.QueryTracer, pgx.BatchTracer, and pgx.ConnectTracer,
+// recording queryDuration and queryErrors for every query. Install it via
+// postgres.OpenOptions.Tracer.
+type Tracer struct {
+	// Next, if set, receives every call after this Tracer's own
+	// bookkeeping -- typically the tracelog.TraceLog that postgres.Open
+	// installs by default, so query logging keeps working alongside these
+	// metrics. Next only needs to implement pgx.QueryTracer; if it also
+	// implements pgx.BatchTracer and/or pgx.ConnectTracer, those are
+	// forwarded to as well.
+	Next pgx.QueryTracer
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx = context.WithValue(ctx, statementNameKey, statementName(data.SQL))
+	ctx = context.WithValue(ctx, queryStartKey, time.Now())
+	if t.Next != nil {
+		ctx = t.Next.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	name, _ := ctx.Value(statementNameKey).(string)
+	if name == "" {
+		name = unknownStatement
+	}
+	if start, ok := ctx.Value(queryStartKey).(time.Time); ok {
+		queryDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}
+	if data.Err != nil {
+		queryErrors.WithLabelValues(name).Inc()
+	}
+	if t.Next != nil {
+		t.Next.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// TraceBatchStart implements pgx.BatchTracer by forwarding to Next, if Next
+// implements it. Queries within a batch aren't separately timed here --
+// TraceQueryStart/TraceQueryEnd already fire for each one.
+func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if bt, ok := t.Next.(pgx.BatchTracer); ok {
+		return bt.TraceBatchStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer by forwarding to Next, if Next
+// implements it.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if bt, ok := t.Next.(pgx.BatchTracer); ok {
+		bt.TraceBatchQuery(ctx, conn, data)
+	}
+}
+
+// TraceBatchEnd implements pgx.BatchTracer by forwarding to Next, if Next
+// implements it.
+func (t *Tracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	if bt, ok := t.Next.(pgx.BatchTracer); ok {
+		bt.TraceBatchEnd(ctx, conn, data)
+	}
+}
+
+// TraceConnectStart implements pgx.ConnectTracer by forwarding to Next, if
+// Next implements it.
+func (t *Tracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	if ct, ok := t.Next.(pgx.ConnectTracer); ok {
+		return ct.TraceConnectStart(ctx, data)
+	}
+	return ctx
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer by forwarding to Next, if
+// Next implements it.
+func (t *Tracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if ct, ok := t.Next.(pgx.ConnectTracer); ok {
+		ct.TraceConnectEnd(ctx, data)
+	}
+}
+
+// RegisterPoolStats registers gauges reporting pool.Stat()'s fields --
+// acquired, idle, and total connection counts, the configured max, and the
+// cumulative acquire count -- under the "postgres_pool_" prefix. Each gauge
+// reads pool.Stat() fresh whenever Prometheus scrapes it. Calling
+// RegisterPoolStats more than once for the same pool (e.g. across table-
+// driven subtests reusing one *testing.M-scoped pool) is a no-op after the
+// first call, rather than panicking on a duplicate registration.
+func RegisterPoolStats(pool *postgres.Pool) {
+	gauges := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "postgres_pool_acquired_conns",
+			Help: "Number of connections currently acquired from the pool.",
+		}, func() float64 { return float64(pool.Stat().AcquiredConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "postgres_pool_idle_conns",
+			Help: "Number of connections currently idle in the pool.",
+		}, func() float64 { return float64(pool.Stat().IdleConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "postgres_pool_total_conns",
+			Help: "Total number of connections currently open, acquired or idle.",
+		}, func() float64 { return float64(pool.Stat().TotalConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "postgres_pool_max_conns",
+			Help: "Maximum number of connections the pool is configured to open.",
+		}, func() float64 { return float64(pool.Stat().MaxConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "postgres_pool_acquire_count",
+			Help: "Cumulative number of successful acquires from the pool.",
+		}, func() float64 { return float64(pool.Stat().AcquireCount()) }),
+	}
+	for _, g := range gauges {
+		if err := prometheus.Register(g); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
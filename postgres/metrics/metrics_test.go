@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestStatementName(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "Tagged",
+			sql:  "/* name=ListTasks */ SELECT * FROM tasks",
+			want: "ListTasks",
+		},
+		{
+			name: "TaggedNoSpaces",
+			sql:  "/*name=GetTask*/SELECT * FROM tasks WHERE id = $1",
+			want: "GetTask",
+		},
+		{
+			name: "Untagged",
+			sql:  "SELECT * FROM tasks",
+			want: unknownStatement,
+		},
+		{
+			name: "MalformedTag",
+			sql:  "/* name= */ SELECT * FROM tasks",
+			want: unknownStatement,
+		},
+		{
+			name: "EmptyQuery",
+			sql:  "",
+			want: unknownStatement,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statementName(tt.sql); got != tt.want {
+				t.Errorf("statementName(%q) = %q; want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconnectAttempted(t *testing.T) {
+	before := testutilCounterValue(t)
+	ReconnectAttempted()
+	if got, want := testutilCounterValue(t), before+1; got != want {
+		t.Errorf("reconnectAttempts after ReconnectAttempted() = %v; want %v", got, want)
+	}
+}
+
+func testutilCounterValue(t *testing.T) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := reconnectAttempts.Write(&m); err != nil {
+		t.Fatalf("read reconnectAttempts: %v", err)
+	}
+	return m.Counter.GetValue()
+}
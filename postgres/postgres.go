@@ -5,15 +5,23 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
+	"go.saser.se/postgres/internal/timerpool"
 	"go.saser.se/postgres/log/klogadapter"
 )
 
-const retryInterval = 1 * time.Second
+// defaultInitialBackoff and defaultMaxBackoff are the backoff bounds Open
+// uses when OpenOptions doesn't set its own.
+const (
+	defaultInitialBackoff = 1 * time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
 
 // StatementBuilder is ready to use for PostgreSQL queries.
 var StatementBuilder = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
@@ -23,48 +31,116 @@ type Pool struct {
 	*pgxpool.Pool
 }
 
-// Open connects using the given connection string, retrying until either the
-// connection succeeds or the context is cancelled.
+// OpenOptions configures the retry behavior of OpenWithOptions.
+type OpenOptions struct {
+	// InitialBackoff is the delay before the first retry. Defaults to 1
+	// second if zero.
+	InitialBackoff time.Duration
+	// MaxBackoff caps how large the backoff is allowed to grow to, as it
+	// doubles on every failed attempt. Defaults to 30 seconds if zero.
+	MaxBackoff time.Duration
+	// Jitter, if true, randomizes each backoff to a uniformly random value
+	// in [0, d) instead of using d itself ("full jitter"). This spreads out
+	// reconnect attempts from many replicas that started retrying at the
+	// same moment -- e.g. after a shared Postgres instance flaps -- instead
+	// of having all of them hammer the database in lockstep.
+	Jitter bool
+	// MaxAttempts caps how many times to try connecting before giving up
+	// and returning an error. Zero, the default, means retry until ctx is
+	// done.
+	MaxAttempts int
+	// Tracer, if set, is installed as the pool's pgx.QueryTracer (and, if it
+	// also implements pgx.BatchTracer and/or pgx.ConnectTracer, those too)
+	// instead of the default tracelog.TraceLog. This is how
+	// postgres/metrics's Tracer gets installed; pass
+	// &metrics.Tracer{Next: &tracelog.TraceLog{...}} to keep query logging
+	// alongside the metrics it records.
+	Tracer pgx.QueryTracer
+	// OnRetry, if set, is called once for every failed connection attempt,
+	// right before backing off to retry. This is how postgres/metrics
+	// counts reconnect attempts without this package needing to depend on
+	// it.
+	OnRetry func()
+}
+
+// Open connects using the given connection string, retrying with
+// exponential backoff until either the connection succeeds or the context
+// is cancelled. It is equivalent to OpenWithOptions with the zero
+// OpenOptions.
 func Open(ctx context.Context, connString string) (*Pool, error) {
+	return OpenWithOptions(ctx, connString, OpenOptions{})
+}
+
+// OpenWithOptions is like Open, but lets the caller tune the retry backoff
+// via opts.
+func OpenWithOptions(ctx context.Context, connString string, opts OpenOptions) (*Pool, error) {
 	cfg, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("postgres: open: %w", err)
 	}
-	cfg.ConnConfig.Tracer = &tracelog.TraceLog{
-		Logger:   klogadapter.NewLogger(),
-		LogLevel: tracelog.LogLevelTrace,
+	if opts.Tracer != nil {
+		cfg.ConnConfig.Tracer = opts.Tracer
+	} else {
+		cfg.ConnConfig.Tracer = &tracelog.TraceLog{
+			Logger:   klogadapter.NewLogger(),
+			LogLevel: tracelog.LogLevelTrace,
+		}
 	}
-	pool, err := openConfigWithRetry(ctx, cfg)
+	pool, err := openConfigWithRetry(ctx, cfg, opts)
 	if err != nil {
 		return nil, fmt.Errorf("postgres: open: %w", err)
 	}
 	return pool, err
 }
 
-// openConfigWithRetry implements linear backoff to connect with the given
-// config until either the connection succeeds or the context is cancelled.
-func openConfigWithRetry(ctx context.Context, cfg *pgxpool.Config) (*Pool, error) {
-	ticker := time.NewTicker(retryInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-ticker.C:
-			pool, err := pgxpool.NewWithConfig(ctx, cfg)
-			if err != nil {
-				continue
-			}
+// openConfigWithRetry connects with the given config, retrying with
+// exponential backoff (optionally randomized by opts.Jitter) until the
+// connection succeeds, ctx is cancelled, or opts.MaxAttempts is reached.
+func openConfigWithRetry(ctx context.Context, cfg *pgxpool.Config, opts OpenOptions) (*Pool, error) {
+	backoff := opts.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		pool, err := pgxpool.NewWithConfig(ctx, cfg)
+		if err == nil {
 			// We do a ping here to be more certain that the pool will actually
 			// be useful after this method returns. If we don't do this we get
 			// flaky tests that start up Postgres containers because the Docker
 			// version of Postgres seems to be doing something weird at startup,
 			// such as starting up and then restarting. Not sure what happens,
 			// but this seems to fix it.
-			if err := pool.Ping(ctx); err != nil {
-				continue
+			if err = pool.Ping(ctx); err == nil {
+				return &Pool{Pool: pool}, nil
 			}
-			return &Pool{Pool: pool}, nil
+		}
+		if opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry()
+		}
+
+		wait := backoff
+		if opts.Jitter {
+			wait = time.Duration(rand.Int63n(int64(backoff)))
+		}
+		timer := timerpool.Get(wait)
+		select {
+		case <-ctx.Done():
+			timerpool.Put(timer)
+			return nil, ctx.Err()
+		case <-timer.C:
+			timerpool.Put(timer)
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
 		}
 	}
 }
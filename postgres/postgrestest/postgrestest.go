@@ -5,16 +5,31 @@ package postgrestest
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver, used by WaitForSQL below
 	"go.saser.se/docker/dockertest"
 	"go.saser.se/postgres"
 	"go.saser.se/runfiles"
 )
 
+// image is the Postgres image pulled directly from its registry. Pulling it
+// by reference, rather than loading a pre-built tarball, means this package
+// doesn't need a Bazel target producing that tarball.
+const image = "postgres:16-alpine"
+
 // Open takes a runfiles path to a file containing a schema definition (i.e.,
 // "CREATE TABLE" statements and similar), and starts a new Docker container
 // running Postgres with the given schema. Open returns a connection pool to the
@@ -32,26 +47,36 @@ func Open(ctx context.Context, tb testing.TB, schemaPath string) *postgres.Pool
 	dbName := strings.TrimSuffix(schemaPath, filepath.Ext(schemaPath))
 	dbName = strings.ReplaceAll(dbName, "/", "_")
 
-	// Start a Postgres container and get the address it's listening on.
+	// dsn builds the connection string for the database at addr. It's shared
+	// between the WaitForSQL readiness check below and the actual connection
+	// pool opened further down, so both agree on exactly what they're
+	// connecting to.
+	dsn := func(addr string) string {
+		return (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(user, password),
+			Host:   addr,
+			Path:   dbName,
+		}).String()
+	}
+
+	// Start a Postgres container, and don't return control to the caller
+	// until it's actually accepting connections.
+	dpool := dockertest.NewPool(tb, "")
 	opts := dockertest.RunOptions{
-		Image: dockertest.Load(ctx, tb, "postgres/image.tar"),
+		Image: dpool.Pull(ctx, tb, image, dockertest.PullOptions{}),
 		Environment: map[string]string{
 			"POSTGRES_USER":     user,
 			"POSTGRES_PASSWORD": password,
 			"POSTGRES_DB":       dbName,
 		},
+		WaitFor: dockertest.WaitForSQL("5432/tcp", "pgx", dsn),
 	}
-	id := dockertest.Run(ctx, tb, opts)
-	addr := dockertest.Address(ctx, tb, id, "5432/tcp")
+	id := dpool.Run(ctx, tb, opts)
+	addr := dpool.Address(ctx, tb, id, "5432/tcp")
 
 	// Connect to the container.
-	connString := (&url.URL{
-		Scheme: "postgres",
-		User:   url.UserPassword(user, password),
-		Host:   addr,
-		Path:   dbName,
-	}).String()
-	pool, err := postgres.Open(ctx, connString)
+	pool, err := postgres.Open(ctx, dsn(addr))
 	if err != nil {
 		tb.Fatalf("Failed to open connection pool: %v", err)
 	}
@@ -67,3 +92,462 @@ func Open(ctx context.Context, tb testing.TB, schemaPath string) *postgres.Pool
 	// return the connection pool to the caller.
 	return pool
 }
+
+// Options configures NewContainer.
+type Options struct {
+	// Version selects the Postgres image tag to run, e.g. "16" or
+	// "16-alpine". Defaults to the same version Open and OpenMigrations
+	// use.
+	Version string
+	// InitSQL is a runfiles path to a schema script (i.e., "CREATE TABLE"
+	// statements and similar) applied once the container is ready.
+	// Optional; if empty, the container is returned with no schema.
+	InitSQL string
+}
+
+// Container is a Postgres instance started by NewContainer.
+type Container struct {
+	*postgres.Pool
+	// DSN is the connection string used to reach the container, for tests
+	// that need to open additional connections of their own (e.g. to run a
+	// subprocess against the same database) rather than going through Pool.
+	DSN string
+}
+
+// NewContainer starts a fresh Postgres container in Docker and returns a
+// connection pool to it, along with its DSN. Unlike Open and OpenMigrations,
+// which both hardcode the Postgres version, NewContainer lets callers pick
+// one via Options, which is useful for tests that need to verify behavior
+// against more than one server version. The container is removed via
+// tb.Cleanup; pass a ctx with a deadline (e.g. from a test's own timeout) to
+// bound how long NewContainer will wait for the container to become ready.
+func NewContainer(ctx context.Context, tb testing.TB, opts Options) *Container {
+	tb.Helper()
+	const (
+		user     = "postgrestest"
+		password = "some-random-password"
+		dbName   = "postgrestest"
+	)
+	version := opts.Version
+	if version == "" {
+		version = strings.TrimPrefix(image, "postgres:")
+	}
+
+	dsn := func(addr string) string {
+		return (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(user, password),
+			Host:   addr,
+			Path:   dbName,
+		}).String()
+	}
+
+	dpool := dockertest.NewPool(tb, "")
+	runOpts := dockertest.RunOptions{
+		Image: dpool.Pull(ctx, tb, "postgres:"+version, dockertest.PullOptions{}),
+		Environment: map[string]string{
+			"POSTGRES_USER":     user,
+			"POSTGRES_PASSWORD": password,
+			"POSTGRES_DB":       dbName,
+		},
+		WaitFor: dockertest.WaitForSQL("5432/tcp", "pgx", dsn),
+	}
+	id := dpool.Run(ctx, tb, runOpts)
+	addr := dpool.Address(ctx, tb, id, "5432/tcp")
+	connString := dsn(addr)
+
+	pool, err := postgres.Open(ctx, connString)
+	if err != nil {
+		tb.Fatalf("postgrestest: new container: open connection pool: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	if opts.InitSQL != "" {
+		schemaSQL := string(runfiles.ReadT(tb, opts.InitSQL))
+		if _, err := pool.Exec(ctx, schemaSQL); err != nil {
+			tb.Fatalf("postgrestest: new container: apply init SQL: %v", err)
+		}
+	}
+
+	return &Container{Pool: pool, DSN: connString}
+}
+
+// migrationFilePattern matches migration file names like
+// "0001_create_tasks.up.sql" or "0001_create_tasks.down.sql", capturing the
+// numeric version, the descriptive name, and the direction.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// migrationFile is one half (up or down) of a single migration, resolved to
+// an absolute path on disk.
+type migrationFile struct {
+	version int64
+	path    string
+}
+
+// readMigrations lists dir (a runfiles directory) and groups its contents
+// into "up" and "down" migration files, each sorted by ascending version.
+// It fails the test if dir can't be read or if a file name's numeric prefix
+// isn't a valid integer; file names that don't match migrationFilePattern
+// are silently ignored, so a README or similar can live alongside the SQL
+// files.
+func readMigrations(tb testing.TB, dir string) (ups, downs []migrationFile) {
+	tb.Helper()
+	absDir := runfiles.PathT(tb, dir)
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		tb.Fatalf("postgrestest: open migrations: read directory %q: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			tb.Fatalf("postgrestest: open migrations: %q: parse version: %v", entry.Name(), err)
+		}
+		file := migrationFile{
+			version: version,
+			path:    filepath.Join(absDir, entry.Name()),
+		}
+		switch m[3] {
+		case "up":
+			ups = append(ups, file)
+		case "down":
+			downs = append(downs, file)
+		}
+	}
+	byVersion := func(files []migrationFile) {
+		sort.Slice(files, func(i, j int) bool { return files[i].version < files[j].version })
+	}
+	byVersion(ups)
+	byVersion(downs)
+	return ups, downs
+}
+
+// schemaMigrationsSQL creates the table OpenMigrations uses to record which
+// versions have been applied, if it doesn't already exist.
+const schemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// applyUpMigration applies file inside a single transaction, alongside
+// recording it in schema_migrations, so a failure partway through a
+// migration's SQL never leaves a version marked as applied.
+func applyUpMigration(ctx context.Context, tb testing.TB, pool *postgres.Pool, file migrationFile) {
+	tb.Helper()
+	sql, err := os.ReadFile(file.path)
+	if err != nil {
+		tb.Fatalf("postgrestest: open migrations: read %s: %v", filepath.Base(file.path), err)
+	}
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("apply %s: %w", filepath.Base(file.path), err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, file.version); err != nil {
+			return fmt.Errorf("record version %d: %w", file.version, err)
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("postgrestest: open migrations: %v", err)
+	}
+}
+
+// applyDownMigration is the rollback counterpart of applyUpMigration, run
+// from tb.Cleanup. It uses tb.Errorf rather than tb.Fatalf, since Fatalf is
+// not safe to call from some cleanup contexts and a rollback failure
+// shouldn't prevent the rest of the cleanup chain from running.
+func applyDownMigration(ctx context.Context, tb testing.TB, pool *postgres.Pool, file migrationFile) {
+	tb.Helper()
+	sql, err := os.ReadFile(file.path)
+	if err != nil {
+		tb.Errorf("postgrestest: rollback migrations: read %s: %v", filepath.Base(file.path), err)
+		return
+	}
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("apply %s: %w", filepath.Base(file.path), err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, file.version); err != nil {
+			return fmt.Errorf("remove version %d record: %w", file.version, err)
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Errorf("postgrestest: rollback migrations: %v", err)
+	}
+}
+
+// migrationsConfig holds the options settable via MigrationOption.
+type migrationsConfig struct {
+	reuse bool
+}
+
+// MigrationOption configures OpenMigrations.
+type MigrationOption func(*migrationsConfig)
+
+// Reuse makes OpenMigrations share a single database across every call made
+// with the same dir, instead of starting a new container each time. This is
+// meant for a parent test that starts the database once and then calls
+// t.Run for several subtests that all want the same migrated schema: the
+// first call (typically from the parent) starts the container and owns its
+// teardown via tb.Cleanup; later calls with the same dir just return the
+// already-open pool and register no cleanup of their own, so subtests can
+// call OpenMigrations(ctx, t, dir, Reuse()) freely without each one trying
+// to tear down the database the others are still using.
+func Reuse() MigrationOption {
+	return func(c *migrationsConfig) { c.reuse = true }
+}
+
+// reuseRegistry backs Reuse, keyed by migrations directory.
+var reuseRegistry = struct {
+	mu    sync.Mutex
+	pools map[string]*postgres.Pool
+}{pools: make(map[string]*postgres.Pool)}
+
+// OpenMigrations is like Open, but instead of applying a single schema file
+// it applies a directory of versioned migrations, named like
+// "0001_create_tasks.up.sql" and "0001_create_tasks.down.sql". The ".up.sql"
+// files are applied in ascending order of their numeric prefix, each inside
+// its own transaction, and the applied version is recorded in a
+// schema_migrations table that OpenMigrations manages itself. The
+// corresponding ".down.sql" files are applied in descending order via
+// tb.Cleanup, rolling the database back to empty by the time the test
+// finishes.
+//
+// By default every call starts its own container. Pass Reuse to instead
+// share one database across calls with the same dir; see Reuse for the
+// details of how that's torn down.
+func OpenMigrations(ctx context.Context, tb testing.TB, dir string, opts ...MigrationOption) *postgres.Pool {
+	tb.Helper()
+	var cfg migrationsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.reuse {
+		reuseRegistry.mu.Lock()
+		pool, ok := reuseRegistry.pools[dir]
+		reuseRegistry.mu.Unlock()
+		if ok {
+			return pool
+		}
+	}
+
+	ups, downs := readMigrations(tb, dir)
+
+	const (
+		user     = "postgrestest"
+		password = "some-random-password"
+	)
+	dbName := strings.ReplaceAll(dir, "/", "_")
+	dsn := func(addr string) string {
+		return (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(user, password),
+			Host:   addr,
+			Path:   dbName,
+		}).String()
+	}
+
+	dpool := dockertest.NewPool(tb, "")
+	runOpts := dockertest.RunOptions{
+		Image: dpool.Pull(ctx, tb, image, dockertest.PullOptions{}),
+		Environment: map[string]string{
+			"POSTGRES_USER":     user,
+			"POSTGRES_PASSWORD": password,
+			"POSTGRES_DB":       dbName,
+		},
+		WaitFor: dockertest.WaitForSQL("5432/tcp", "pgx", dsn),
+	}
+	id := dpool.Run(ctx, tb, runOpts)
+	addr := dpool.Address(ctx, tb, id, "5432/tcp")
+
+	pool, err := postgres.Open(ctx, dsn(addr))
+	if err != nil {
+		tb.Fatalf("postgrestest: open migrations: open connection pool: %v", err)
+	}
+
+	if _, err := pool.Exec(ctx, schemaMigrationsSQL); err != nil {
+		tb.Fatalf("postgrestest: open migrations: create schema_migrations table: %v", err)
+	}
+	for _, file := range ups {
+		applyUpMigration(ctx, tb, pool, file)
+	}
+
+	tb.Cleanup(func() {
+		// ctx may already be done by the time this cleanup runs (e.g. it
+		// came from tb.Context()); use a context that isn't tied to it so
+		// the rollback still goes through.
+		downCtx := context.WithoutCancel(ctx)
+		for i := len(downs) - 1; i >= 0; i-- {
+			applyDownMigration(downCtx, tb, pool, downs[i])
+		}
+		pool.Close()
+		if cfg.reuse {
+			reuseRegistry.mu.Lock()
+			delete(reuseRegistry.pools, dir)
+			reuseRegistry.mu.Unlock()
+		}
+	})
+
+	if cfg.reuse {
+		reuseRegistry.mu.Lock()
+		reuseRegistry.pools[dir] = pool
+		reuseRegistry.mu.Unlock()
+	}
+
+	return pool
+}
+
+// Template is a Postgres database used as a `CREATE DATABASE ... TEMPLATE`
+// source, letting tests cheaply clone it via Open instead of paying for a
+// new container (and re-running the schema) per test. Create one with
+// NewTemplate.
+type Template struct {
+	admin *postgres.Pool // connected to the "postgres" maintenance database
+	dsn   func(db string) string
+	name  string // the template database's name
+}
+
+// NewTemplate starts a single Postgres container, applies the schema at
+// schemaPath once into a database, and marks that database as a template
+// that Template.Open can cheaply clone. The container is torn down via
+// tb.Cleanup on tb, so tb should outlive every call to Template.Open:
+// typically the *testing.T of a parent test whose subtests call Open, or a
+// *testing.M wrapped by TestMain.
+func NewTemplate(ctx context.Context, tb testing.TB, schemaPath string) *Template {
+	tb.Helper()
+	const (
+		user     = "postgrestest"
+		password = "some-random-password"
+		tmplName = "template"
+	)
+	dsn := func(addr, db string) string {
+		return (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(user, password),
+			Host:   addr,
+			Path:   db,
+		}).String()
+	}
+
+	// Start a Postgres container, and don't return control to the caller
+	// until it's actually accepting connections. We deliberately don't set
+	// POSTGRES_DB here: the admin pool below talks to the default
+	// "postgres" maintenance database, since the template database itself
+	// must stay free of long-lived connections.
+	dpool := dockertest.NewPool(tb, "")
+	opts := dockertest.RunOptions{
+		Image: dpool.Pull(ctx, tb, image, dockertest.PullOptions{}),
+		Environment: map[string]string{
+			"POSTGRES_USER":     user,
+			"POSTGRES_PASSWORD": password,
+		},
+		WaitFor: dockertest.WaitForSQL("5432/tcp", "pgx", func(addr string) string { return dsn(addr, "postgres") }),
+	}
+	id := dpool.Run(ctx, tb, opts)
+	addr := dpool.Address(ctx, tb, id, "5432/tcp")
+
+	admin, err := postgres.Open(ctx, dsn(addr, "postgres"))
+	if err != nil {
+		tb.Fatalf("postgrestest: new template: open admin connection: %v", err)
+	}
+	tb.Cleanup(admin.Close)
+
+	if _, err := admin.Exec(ctx, `CREATE DATABASE `+pgQuoteIdent(tmplName)); err != nil {
+		tb.Fatalf("postgrestest: new template: create template database: %v", err)
+	}
+
+	// Apply the schema over its own connection, then close it: the template
+	// database must have no active connections when Open later uses it as
+	// a CREATE DATABASE ... TEMPLATE source, so we can't keep this one open
+	// for the lifetime of tb the way Open's connection pool is.
+	schema, err := postgres.Open(ctx, dsn(addr, tmplName))
+	if err != nil {
+		tb.Fatalf("postgrestest: new template: connect to template database: %v", err)
+	}
+	schemaSQL := string(runfiles.ReadT(tb, schemaPath))
+	_, err = schema.Exec(ctx, schemaSQL)
+	schema.Close()
+	if err != nil {
+		tb.Fatalf("postgrestest: new template: apply schema: %v", err)
+	}
+
+	if _, err := admin.Exec(ctx, `ALTER DATABASE `+pgQuoteIdent(tmplName)+` WITH IS_TEMPLATE true`); err != nil {
+		tb.Fatalf("postgrestest: new template: mark database as template: %v", err)
+	}
+
+	return &Template{
+		admin: admin,
+		dsn:   func(db string) string { return dsn(addr, db) },
+		name:  tmplName,
+	}
+}
+
+// Open clones t's template database into a new, uniquely-named database,
+// via `CREATE DATABASE ... TEMPLATE`, and returns a connection pool to the
+// clone. The clone is dropped in tb.Cleanup.
+func (t *Template) Open(ctx context.Context, tb testing.TB) *postgres.Pool {
+	tb.Helper()
+
+	// CREATE DATABASE ... TEMPLATE fails if the template database has any
+	// active connections, so terminate them first. In the common case this
+	// is a no-op, since NewTemplate doesn't leave a connection open to the
+	// template database itself; it mainly guards against a previous clone's
+	// connection not having been torn down yet.
+	if _, err := t.admin.Exec(ctx, terminateBackendsSQL, t.name); err != nil {
+		tb.Fatalf("postgrestest: template: open: terminate template connections: %v", err)
+	}
+
+	name := "test_" + randomHex(8)
+	if _, err := t.admin.Exec(ctx, `CREATE DATABASE `+pgQuoteIdent(name)+` TEMPLATE `+pgQuoteIdent(t.name)); err != nil {
+		tb.Fatalf("postgrestest: template: open: clone %q from %q: %v", name, t.name, err)
+	}
+
+	pool, err := postgres.Open(ctx, t.dsn(name))
+	if err != nil {
+		tb.Fatalf("postgrestest: template: open: connect to %q: %v", name, err)
+	}
+	tb.Cleanup(func() {
+		pool.Close()
+		// ctx may already be done by the time this cleanup runs (e.g. it
+		// came from t.Context()); use a context that isn't tied to it so
+		// the drop still goes through.
+		dropCtx := context.WithoutCancel(ctx)
+		if _, err := t.admin.Exec(dropCtx, terminateBackendsSQL, name); err != nil {
+			tb.Errorf("postgrestest: template: open: cleanup: terminate connections to %q: %v", name, err)
+			return
+		}
+		if _, err := t.admin.Exec(dropCtx, `DROP DATABASE `+pgQuoteIdent(name)); err != nil {
+			tb.Errorf("postgrestest: template: open: cleanup: drop database %q: %v", name, err)
+		}
+	})
+	return pool
+}
+
+// terminateBackendsSQL disconnects every other backend connected to the
+// database named by its one parameter, so it's safe to use as a CREATE
+// DATABASE ... TEMPLATE source or to DROP.
+const terminateBackendsSQL = `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()`
+
+// pgQuoteIdent quotes name as a Postgres identifier, for statements
+// (CREATE/ALTER/DROP DATABASE) that don't accept bind parameters in place
+// of identifiers.
+func pgQuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// randomHex returns a random hex-encoded string made up of n random bytes.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("postgrestest: read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -2,12 +2,15 @@ package postgrestest
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 )
 
 const schemaPath = "postgres/postgrestest/schema.sql"
 
+const migrationsDir = "postgres/postgrestest/testdata/migrations"
+
 func TestOpen(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -23,3 +26,82 @@ VALUES            ($1, $2   )
 		t.Fatal(err)
 	}
 }
+
+func TestTemplate_Open(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tmpl := NewTemplate(ctx, t, schemaPath)
+
+	sql := strings.TrimSpace(`
+INSERT INTO tasks (id, title)
+VALUES            ($1, $2   )
+`)
+
+	// Two clones of the same template must be independent: a row inserted
+	// into one must not be visible in the other.
+	first := tmpl.Open(ctx, t)
+	if _, err := first.Exec(ctx, sql, 1, "A title"); err != nil {
+		t.Fatal(err)
+	}
+
+	second := tmpl.Open(ctx, t)
+	var count int
+	if err := second.QueryRow(ctx, "SELECT count(*) FROM tasks").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, 0; got != want {
+		t.Errorf("second clone has %d rows in tasks; want %d (clones must not see each other's data)", got, want)
+	}
+}
+
+func TestOpenMigrations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	pool := OpenMigrations(ctx, t, migrationsDir)
+
+	// Both migrations should have applied: the table exists, and has the
+	// column added by the second migration.
+	if _, err := pool.Exec(ctx, `INSERT INTO widgets (id, name, color) VALUES ($1, $2, $3)`, 1, "sprocket", "red"); err != nil {
+		t.Fatalf("insert into widgets: %v", err)
+	}
+
+	var versions []int64
+	rows, err := pool.Query(ctx, `SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatalf("query schema_migrations: %v", err)
+	}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("scan version: %v", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("scan schema_migrations: %v", err)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("schema_migrations versions = %v; want %v", versions, want)
+	}
+}
+
+func TestOpenMigrations_Reuse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	parent := OpenMigrations(ctx, t, migrationsDir, Reuse())
+
+	t.Run("First", func(t *testing.T) {
+		t.Parallel()
+		pool := OpenMigrations(ctx, t, migrationsDir, Reuse())
+		if _, err := pool.Exec(ctx, `INSERT INTO widgets (id, name) VALUES ($1, $2)`, 1, "sprocket"); err != nil {
+			t.Fatalf("insert into widgets: %v", err)
+		}
+	})
+	t.Run("Second", func(t *testing.T) {
+		t.Parallel()
+		pool := OpenMigrations(ctx, t, migrationsDir, Reuse())
+		if pool != parent {
+			t.Errorf("OpenMigrations(..., Reuse()) returned a different pool than the parent call")
+		}
+	})
+}
@@ -2,8 +2,12 @@ package redistest
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/go-redis/redis/v9"
 	"go.saser.se/docker/dockertest"
 )
@@ -25,3 +29,201 @@ func Open(ctx context.Context, tb testing.TB) *redis.Client {
 	}
 	return client
 }
+
+// Options configures the topology set up by OpenSentinel and OpenCluster.
+type Options struct {
+	// Replicas is the number of replicas of the master to start, in
+	// OpenSentinel.
+	Replicas int
+	// Sentinels is the number of Sentinel processes to start, in
+	// OpenSentinel. Must be at least 1.
+	Sentinels int
+	// Shards is the number of master nodes (and therefore hash slot owners)
+	// to start, in OpenCluster. Must be at least 1.
+	Shards int
+}
+
+const (
+	redisImagePath = "redis/image.tar"
+	redisPort      = "6379/tcp"
+	sentinelPort   = "26379/tcp"
+	masterName     = "redistest"
+)
+
+// redisNode is a single running redis-server container.
+type redisNode struct {
+	id   string
+	ip   string
+	addr string
+}
+
+// runRedis starts a single redis-server container with the given extra
+// command-line arguments, and waits for it to report a port binding.
+func runRedis(ctx context.Context, tb testing.TB, pool *dockertest.Pool, image string, args ...string) redisNode {
+	tb.Helper()
+	id := pool.Run(ctx, tb, dockertest.RunOptions{
+		Image: image,
+		Cmd:   append([]string{"redis-server"}, args...),
+	})
+	return redisNode{
+		id:   id,
+		ip:   pool.ContainerIP(ctx, tb, id),
+		addr: pool.Address(ctx, tb, id, redisPort),
+	}
+}
+
+// pinger is implemented by both *redis.Client and *redis.ClusterClient.
+type pinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// waitForPing retries Ping against client until it succeeds or ctx is done.
+// This gives a freshly wired-up topology -- sentinel failover configuration,
+// cluster slot assignment -- time to settle before handing the client to the
+// test, the same way Open waits implicitly by virtue of there being only a
+// single node to come up.
+func waitForPing(ctx context.Context, tb testing.TB, client pinger) {
+	tb.Helper()
+	op := func() error { return client.Ping(ctx).Err() }
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		tb.Fatalf("redistest: pinging Redis failed: %v", err)
+	}
+}
+
+// OpenSentinel boots a Sentinel-fronted Redis replica set: one master,
+// opts.Replicas replicas of it, and opts.Sentinels Sentinel processes
+// monitoring it, all wired together. It returns a *redis.Client built with
+// redis.NewFailoverClient, so that failover between the master and its
+// replicas is transparent to callers. As with Open, cleanup of all
+// containers is registered so that it happens even if the test calls
+// tb.Fatal.
+func OpenSentinel(ctx context.Context, tb testing.TB, opts Options) *redis.Client {
+	tb.Helper()
+	if opts.Replicas < 0 {
+		tb.Fatalf("redistest: OpenSentinel: Replicas must be >= 0, got %d", opts.Replicas)
+	}
+	if opts.Sentinels < 1 {
+		tb.Fatalf("redistest: OpenSentinel: Sentinels must be >= 1, got %d", opts.Sentinels)
+	}
+
+	pool := dockertest.NewPool(tb, "")
+	image := pool.Load(ctx, tb, redisImagePath)
+
+	master := runRedis(ctx, tb, pool, image)
+	for i := 0; i < opts.Replicas; i++ {
+		runRedis(ctx, tb, pool, image, "--replicaof", master.ip, "6379")
+	}
+
+	// A majority of the sentinels must agree that the master is down before
+	// a failover is triggered.
+	quorum := opts.Sentinels/2 + 1
+	var sentinelAddrs []string
+	for i := 0; i < opts.Sentinels; i++ {
+		id := pool.Run(ctx, tb, dockertest.RunOptions{
+			Image: image,
+			Cmd: []string{
+				"redis-server",
+				"--sentinel",
+				"--sentinel", "monitor", masterName, master.ip, "6379", fmt.Sprint(quorum),
+				"--sentinel", "down-after-milliseconds", masterName, "5000",
+				"--sentinel", "failover-timeout", masterName, "10000",
+			},
+		})
+		sentinelAddrs = append(sentinelAddrs, pool.Address(ctx, tb, id, sentinelPort))
+	}
+
+	client := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    masterName,
+		SentinelAddrs: sentinelAddrs,
+	})
+	waitForPing(ctx, tb, client)
+	return client
+}
+
+// OpenCluster provisions a cluster-enabled Redis topology with opts.Shards
+// master nodes. It runs CLUSTER MEET to introduce every node to every other
+// node and CLUSTER ADDSLOTS to divide up all 16384 hash slots evenly between
+// them, waits for the cluster state to become "ok", and returns a
+// *redis.ClusterClient. As with Open, cleanup of all containers is
+// registered so that it happens even if the test calls tb.Fatal.
+func OpenCluster(ctx context.Context, tb testing.TB, opts Options) *redis.ClusterClient {
+	tb.Helper()
+	if opts.Shards < 1 {
+		tb.Fatalf("redistest: OpenCluster: Shards must be >= 1, got %d", opts.Shards)
+	}
+
+	pool := dockertest.NewPool(tb, "")
+	image := pool.Load(ctx, tb, redisImagePath)
+
+	nodes := make([]redisNode, opts.Shards)
+	for i := range nodes {
+		nodes[i] = runRedis(ctx, tb, pool, image, "--cluster-enabled", "yes")
+	}
+
+	for _, n := range nodes {
+		c := redis.NewClient(&redis.Options{Addr: n.addr})
+		for _, other := range nodes {
+			if other.id == n.id {
+				continue
+			}
+			if err := c.ClusterMeet(ctx, other.ip, "6379").Err(); err != nil {
+				tb.Fatalf("redistest: OpenCluster: CLUSTER MEET %s from %s: %v", other.ip, n.addr, err)
+			}
+		}
+		c.Close()
+	}
+
+	const totalSlots = 16384
+	slotsPerShard := totalSlots / len(nodes)
+	for i, n := range nodes {
+		start := i * slotsPerShard
+		end := start + slotsPerShard - 1
+		if i == len(nodes)-1 {
+			// The last shard absorbs any remainder from the division above,
+			// so that every one of the 16384 slots is owned by someone.
+			end = totalSlots - 1
+		}
+		slots := make([]int, 0, end-start+1)
+		for s := start; s <= end; s++ {
+			slots = append(slots, s)
+		}
+		c := redis.NewClient(&redis.Options{Addr: n.addr})
+		err := c.ClusterAddSlots(ctx, slots...).Err()
+		c.Close()
+		if err != nil {
+			tb.Fatalf("redistest: OpenCluster: CLUSTER ADDSLOTS on %s: %v", n.addr, err)
+		}
+	}
+
+	waitForClusterState(ctx, tb, redis.NewClient(&redis.Options{Addr: nodes[0].addr}))
+
+	addrs := make([]string, len(nodes))
+	for i, n := range nodes {
+		addrs[i] = n.addr
+	}
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: addrs,
+	})
+	waitForPing(ctx, tb, client)
+	return client
+}
+
+// waitForClusterState waits until CLUSTER INFO reports cluster_state:ok on
+// client, which only happens once every hash slot has an owner.
+func waitForClusterState(ctx context.Context, tb testing.TB, client *redis.Client) {
+	tb.Helper()
+	defer client.Close()
+	op := func() error {
+		info, err := client.ClusterInfo(ctx).Result()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(info, "cluster_state:ok") {
+			return errors.New("redistest: cluster state is not yet ok")
+		}
+		return nil
+	}
+	if err := backoff.Retry(op, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		tb.Fatalf("redistest: waiting for cluster state to become ok: %v", err)
+	}
+}
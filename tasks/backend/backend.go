@@ -0,0 +1,65 @@
+// Package backend lets the tasks server binary (see tasks/server) pick, at
+// startup and without recompiling, which storage layer backs the `tasks`
+// gRPC service.
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"go.saser.se/postgres"
+	"go.saser.se/tasks/fake"
+	"go.saser.se/tasks/service"
+	pb "go.saser.se/tasks/tasks_go_proto"
+)
+
+// Server is a complete implementation of the tasks gRPC service, backed by
+// some storage layer. Close releases whatever resources the backend is
+// holding (e.g. database connections) and is called once during graceful
+// shutdown, mirroring how tasks/server already closes its connection pool.
+type Server interface {
+	pb.TasksServer
+	Close(ctx context.Context) error
+}
+
+// Names of the backends recognized by New. Only Postgres and Fake are
+// actually implemented; the others are reserved names that New currently
+// rejects with an explicit "not implemented" error, so that choosing them
+// fails loudly instead of silently falling back to something else.
+const (
+	Postgres          = "postgres"
+	Fake              = "fake"
+	SQLite            = "sqlite"
+	InMemoryPersisted = "inmemory-persisted"
+)
+
+// Config selects and configures a backend for New.
+type Config struct {
+	// Name is one of the constants above.
+	Name string
+	// DSN is the data source name/connection string used by backends that
+	// need one. Currently only Postgres does.
+	DSN string
+}
+
+// New constructs the backend named by cfg.Name. The caller is responsible for
+// calling Close on the returned Server during shutdown.
+func New(ctx context.Context, cfg Config) (Server, error) {
+	switch cfg.Name {
+	case Postgres:
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("backend %q requires a non-empty DSN", cfg.Name)
+		}
+		pool, err := postgres.Open(ctx, cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("open %q backend: %w", cfg.Name, err)
+		}
+		return service.New(pool), nil
+	case Fake:
+		return fake.New(), nil
+	case SQLite, InMemoryPersisted:
+		return nil, fmt.Errorf("backend %q is not implemented yet", cfg.Name)
+	default:
+		return nil, fmt.Errorf("unknown backend %q; must be one of %q, %q, %q, %q", cfg.Name, Postgres, Fake, SQLite, InMemoryPersisted)
+	}
+}
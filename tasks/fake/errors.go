@@ -1,7 +1,15 @@
 package fake
 
-import "fmt"
+import (
+	"fmt"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// invalidNameError indicates that a resource name failed the package's own
+// format validation; see validateTaskName and friends. It implements
+// go.saser.se/errdefs.ErrInvalidArgument.
 type invalidNameError struct {
 	Name   string
 	Reason string
@@ -11,6 +19,11 @@ func (e *invalidNameError) Error() string {
 	return fmt.Sprintf(`fake: internal: name %q doesn't have format "tasks/{task}": %v`, e.Name, e.Reason)
 }
 
+// InvalidArgument implements go.saser.se/errdefs.ErrInvalidArgument.
+func (e *invalidNameError) InvalidArgument() {}
+
+// notFoundError indicates that no task exists with the given name. It
+// implements go.saser.se/errdefs.ErrNotFound.
 type notFoundError struct {
 	Name string
 }
@@ -18,3 +31,20 @@ type notFoundError struct {
 func (e *notFoundError) Error() string {
 	return fmt.Sprintf(`fake: internal: task not found: %q`, e.Name)
 }
+
+// NotFound implements go.saser.se/errdefs.ErrNotFound.
+func (e *notFoundError) NotFound() {}
+
+// ErrTaskIDConflict is returned by CreateTask when the caller supplies a
+// task_id that is already in use, including by a task that is soft-deleted
+// but not yet expired.
+var ErrTaskIDConflict = status.Error(codes.AlreadyExists, "A task with the given task_id already exists.")
+
+// ErrProjectIDConflict is returned by CreateProject when the caller supplies
+// a project_id that is already in use, including by a project that is
+// soft-deleted but not yet expired.
+var ErrProjectIDConflict = status.Error(codes.AlreadyExists, "A project with the given project_id already exists.")
+
+// ErrLabelIDConflict is returned by CreateLabel when the caller supplies a
+// label_id that is already in use.
+var ErrLabelIDConflict = status.Error(codes.AlreadyExists, "A label with the given label_id already exists.")
@@ -5,15 +5,22 @@ package fake
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jonboulle/clockwork"
+	"go.saser.se/tasks/service/filter"
+	"go.saser.se/tasks/service/orderby"
 	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -28,10 +35,111 @@ import (
 // (at most) maxPageSize tasks.
 const maxPageSize = 1000
 
+// maxBatchSize is the maximum number of sub-requests accepted by a single
+// call to any Batch*Tasks RPC.
+const maxBatchSize = 1000
+
+// CascadePolicy controls what a Delete* RPC does when the resource being
+// deleted is still referenced by other resources.
+type CascadePolicy int
+
+const (
+	// CascadePolicyReject rejects the delete with FailedPrecondition,
+	// naming the referencing resources.
+	CascadePolicyReject CascadePolicy = iota
+	// CascadePolicyDetach removes the reference without otherwise
+	// touching the referencing resource -- e.g., stripping a deleted
+	// label from the tasks that carried it, leaving those tasks
+	// otherwise untouched.
+	CascadePolicyDetach
+	// CascadePolicySoftDelete soft-deletes every referencing resource
+	// along with the resource being deleted.
+	CascadePolicySoftDelete
+)
+
 // labelRE matches all valid label strings. It is based on the proto definition of
 // what valid characters are.
 var labelRE = regexp.MustCompile(`^[a-zA-Z0-9\:\-\_\@]+$`)
 
+// colorRE matches a Label's color field: a 6-character hex string, without a
+// leading "#", following the Gitea/Vikunja convention.
+var colorRE = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// defaultLabelColor is used as a Label's color when CreateLabel isn't given
+// one.
+const defaultLabelColor = "808080"
+
+// maxLabelDescriptionLen is the maximum number of bytes a Label's
+// description may contain.
+const maxLabelDescriptionLen = 500
+
+// customIDRE matches the client-supplied ID suffix accepted by the task_id,
+// project_id, and label_id fields on the Create* RPCs.
+var customIDRE = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// computeEtag derives a stable, opaque etag for a project from its name and
+// its mutable timestamps, mirroring the SQL-backed service's computeEtag so
+// that if_match (AIP-154) behaves the same against either backend.
+func computeEtag(name string, updateTime, deleteTime, archiveTime *timestamppb.Timestamp) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00%d\x00%d\x00%d\x00%d",
+		name,
+		updateTime.GetSeconds(), updateTime.GetNanos(),
+		deleteTime.GetSeconds(), deleteTime.GetNanos(),
+		archiveTime.GetSeconds(), archiveTime.GetNanos(),
+	)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkIfMatch compares ifMatch (a request's if_match field) against a
+// resource's current etag. An empty ifMatch always succeeds, preserving
+// last-writer-wins behavior for callers that don't opt in.
+func checkIfMatch(ifMatch, currentEtag string) error {
+	if ifMatch == "" || ifMatch == currentEtag {
+		return nil
+	}
+	st, detailErr := status.New(codes.Aborted, "The if_match etag does not match the resource's current etag.").
+		WithDetails(&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        "etag",
+				Subject:     currentEtag,
+				Description: "The resource has been modified since the given etag was read.",
+			}},
+		})
+	if detailErr != nil {
+		return status.Error(codes.Aborted, "The if_match etag does not match the resource's current etag.")
+	}
+	return st.Err()
+}
+
+// newBatchError builds the error returned by a Batch*Tasks RPC when one or
+// more sub-requests, keyed by their index in the request, fail validation.
+// No changes are made to the underlying state in that case; the returned
+// error carries a BatchError detail so callers can tell exactly which
+// sub-requests failed and why.
+func newBatchError(errs map[int]error) error {
+	indices := make([]int, 0, len(errs))
+	for i := range errs {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	entries := make([]*pb.BatchError_Entry, 0, len(indices))
+	for _, i := range indices {
+		st := status.Convert(errs[i])
+		entries = append(entries, &pb.BatchError_Entry{
+			Index:   int32(i),
+			Code:    int32(st.Code()),
+			Message: st.Message(),
+		})
+	}
+	st, detailErr := status.New(codes.Aborted, "One or more requests in the batch failed; no changes were made.").
+		WithDetails(&pb.BatchError{Errors: entries})
+	if detailErr != nil {
+		return status.Error(codes.Aborted, "One or more requests in the batch failed; no changes were made.")
+	}
+	return st.Err()
+}
+
 // taskUpdatableMask contains the fields that can be updated by UpdateTask. It must
 // be kept in sync with the proto definition.
 var taskUpdatableMask *fieldmaskpb.FieldMask
@@ -40,6 +148,7 @@ func init() {
 	m, err := fieldmaskpb.New(&pb.Task{},
 		"title",
 		"description",
+		"retention",
 	)
 	if err != nil {
 		klog.Exit(err)
@@ -69,6 +178,8 @@ var labelUpdatableMask *fieldmaskpb.FieldMask
 func init() {
 	m, err := fieldmaskpb.New(&pb.Label{},
 		"label",
+		"color",
+		"description",
 	)
 	if err != nil {
 		klog.Exit(err)
@@ -76,9 +187,180 @@ func init() {
 	labelUpdatableMask = m
 }
 
+// projectFilterSchema describes the fields that may be used in the `filter`
+// field of ListProjectsRequest. It must be kept in sync with projectFields
+// and with the fields populated by CreateProject/UpdateProject/ArchiveProject/etc.
+//
+// Unlike service.projectFilterSchema, timestamp fields have a Convert so
+// that filter.Match can compare them as time.Time; the SQL-backed schema
+// instead leaves that to Postgres's implicit casting.
+var projectFilterSchema = filter.Schema{
+	"title": {
+		Ops: map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpHas: true},
+	},
+	"description": {
+		Ops: map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpHas: true},
+	},
+	"create_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpLt: true, filter.OpLte: true, filter.OpGt: true, filter.OpGte: true},
+		Convert: convertFilterTime,
+	},
+	"update_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpLt: true, filter.OpLte: true, filter.OpGt: true, filter.OpGte: true},
+		Convert: convertFilterTime,
+	},
+	"archive_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true},
+		Convert: convertFilterTime,
+	},
+	"delete_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true},
+		Convert: convertFilterTime,
+	},
+}
+
+// convertFilterTime parses an RFC 3339 timestamp literal out of a filter
+// expression, for use as a filter.FieldSchema.Convert function.
+func convertFilterTime(value string) (any, error) {
+	return time.Parse(time.RFC3339, value)
+}
+
+// taskFilterSchema describes the fields of a Task that can be used in the
+// filter field of ListTasksRequest, and with the fields populated by
+// CreateTask/UpdateTask/DeleteTask/CompleteTask/etc.
+var taskFilterSchema = filter.Schema{
+	"title": {
+		Ops: map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpHas: true},
+	},
+	"description": {
+		Ops: map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpHas: true},
+	},
+	"parent": {
+		Ops: map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true},
+	},
+	"create_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpLt: true, filter.OpLte: true, filter.OpGt: true, filter.OpGte: true},
+		Convert: convertFilterTime,
+	},
+	"update_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpLt: true, filter.OpLte: true, filter.OpGt: true, filter.OpGte: true},
+		Convert: convertFilterTime,
+	},
+	"complete_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true, filter.OpLt: true, filter.OpLte: true, filter.OpGt: true, filter.OpGte: true},
+		Convert: convertFilterTime,
+	},
+	"delete_time": {
+		Ops:     map[filter.Op]bool{filter.OpEq: true, filter.OpNeq: true},
+		Convert: convertFilterTime,
+	},
+}
+
+// taskFields returns task's fields as filter.Fields, for matching against
+// taskFilterSchema.
+func taskFields(task *pb.Task) filter.Fields {
+	return filter.Fields{
+		"title":         task.GetTitle(),
+		"description":   task.GetDescription(),
+		"parent":        task.GetParent(),
+		"create_time":   task.GetCreateTime().AsTime(),
+		"update_time":   task.GetUpdateTime().AsTime(),
+		"complete_time": optionalTime(task.GetCompleteTime()),
+		"delete_time":   optionalTime(task.GetDeleteTime()),
+	}
+}
+
+// optionalTime returns ts.AsTime() as an any, or untyped nil if ts isn't
+// set, so that filter.Match's NULL-literal handling (which compares the
+// field's value against untyped nil) works for optional timestamps such as
+// archive_time and delete_time.
+func optionalTime(ts *timestamppb.Timestamp) any {
+	if !ts.IsValid() {
+		return nil
+	}
+	return ts.AsTime()
+}
+
+// projectFields returns project's fields as filter.Fields, for matching
+// against projectFilterSchema.
+func projectFields(project *pb.Project) filter.Fields {
+	return filter.Fields{
+		"title":        project.GetTitle(),
+		"description":  project.GetDescription(),
+		"create_time":  project.GetCreateTime().AsTime(),
+		"update_time":  project.GetUpdateTime().AsTime(),
+		"archive_time": optionalTime(project.GetArchiveTime()),
+		"delete_time":  optionalTime(project.GetDeleteTime()),
+	}
+}
+
+// projectOrderByColumns describes the fields that may be used in the
+// order_by field of ListProjectsRequest. It's only used to validate
+// order_by via orderby.ToSQL; the "columns" it maps to are never used to
+// build SQL here, since sorting is done in memory by projectLess.
+var projectOrderByColumns = map[string]string{
+	"id":           "id",
+	"title":        "title",
+	"create_time":  "create_time",
+	"update_time":  "update_time",
+	"delete_time":  "delete_time",
+	"archive_time": "archive_time",
+}
+
+// projectLess reports whether a should sort before b according to terms,
+// falling through to later terms on ties. It never reports a tie itself
+// (returning false for equal projects), leaving the final tiebreak to
+// sort.SliceStable preserving the original (creation) order, mirroring how
+// id ASC breaks ties in the SQL-backed implementation.
+func projectLess(terms []orderby.Term, a, b *pb.Project) bool {
+	for _, term := range terms {
+		var less, greater bool
+		switch term.Field {
+		case "title":
+			less, greater = a.GetTitle() < b.GetTitle(), a.GetTitle() > b.GetTitle()
+		case "create_time":
+			at, bt := a.GetCreateTime().AsTime(), b.GetCreateTime().AsTime()
+			less, greater = at.Before(bt), at.After(bt)
+		case "update_time":
+			at, bt := a.GetUpdateTime().AsTime(), b.GetUpdateTime().AsTime()
+			less, greater = at.Before(bt), at.After(bt)
+		case "delete_time":
+			at, bt := a.GetDeleteTime().AsTime(), b.GetDeleteTime().AsTime()
+			less, greater = at.Before(bt), at.After(bt)
+		case "archive_time":
+			at, bt := a.GetArchiveTime().AsTime(), b.GetArchiveTime().AsTime()
+			less, greater = at.Before(bt), at.After(bt)
+		default:
+			continue
+		}
+		if term.Desc {
+			less, greater = greater, less
+		}
+		if less {
+			return true
+		}
+		if greater {
+			return false
+		}
+	}
+	return false
+}
+
 type pageToken struct {
 	MinimumIndex int
 	ShowDeleted  bool
+	// Filter is the filter (if any) the request that produced this token was
+	// made with. Checked by ListTasks and ListProjects; it's the zero value,
+	// and ignored, for labels.
+	Filter string
+	// OrderBy is the order_by (if any) the request that produced this token
+	// was made with. Only ListProjects checks it; it's the zero value, and
+	// ignored, for tasks and labels.
+	OrderBy string
+	// StateFilter is the state_filter (if any) the request that produced this
+	// token was made with, stored as its String() form. Only ListProjects
+	// checks it; it's the zero value, and ignored, for tasks and labels.
+	StateFilter string
 }
 
 // Fake implements the Tasks service using only in-memory data structures.
@@ -99,12 +381,109 @@ type Fake struct {
 	nextLabelID     int
 	labels          []*pb.Label
 	labelIndices    map[string]int       // label name -> index in `labels`
+	labelStrings    map[string]int       // label string (the `label` field) -> index in `labels`, for O(1) uniqueness checks
 	labelPageTokens map[string]pageToken // token (UUID) -> minimum label ID and whether to show deleted
 
+	// taskLabels is the fake's analogue of the task_labels join table: it
+	// associates a task name with the set of label names attached to it.
+	taskLabels map[string]map[string]struct{}
+
+	// MaxResultBytes is the largest result payload that SetTaskResult will
+	// accept. Zero means defaultMaxResultBytes.
+	MaxResultBytes int
+
+	// CascadeOnLabelDelete controls what DeleteLabel does when the label
+	// being deleted is still attached to one or more tasks. The zero
+	// value is CascadePolicyReject. A request's own force field, if set
+	// to true, always overrides this for that one call and behaves like
+	// CascadePolicyDetach.
+	CascadeOnLabelDelete CascadePolicy
+
+	// taskEvents is a bounded ring buffer of the most recent task mutation
+	// events, used to let WatchTasks replay events a reconnecting client
+	// missed. The oldest event is at index 0.
+	taskEvents   []*pb.TaskEvent
+	taskEventSeq uint64
+	taskWatchers []*taskWatcher
+
 	// Only used in testing. Nil otherwise.
 	clock clockwork.FakeClock
 }
 
+// taskEventBufferSize is how many recent task events WatchTasks keeps around
+// for resume_token-based replay.
+const taskEventBufferSize = 1024
+
+// taskWatcherBufferSize is how many unsent events a single WatchTasks
+// subscriber may have queued before it's considered too slow and
+// disconnected.
+const taskWatcherBufferSize = 16
+
+// taskWatcher is a single WatchTasks subscriber.
+type taskWatcher struct {
+	events  chan *pb.TaskEvent
+	dropped chan struct{} // closed when the subscriber is disconnected for being too slow
+
+	labelFilterNames []string
+	labelFilterOp    pb.TaskLabelFilter_Operator
+}
+
+// matches reports whether the watcher's filter accepts an event about task.
+func (w *taskWatcher) matches(f *Fake, task string) bool {
+	if len(w.labelFilterNames) == 0 {
+		return true
+	}
+	return f.taskMatchesLabelFilter(task, w.labelFilterNames, w.labelFilterOp)
+}
+
+// publishTaskEvent records a task event and fans it out to every matching
+// subscriber. Callers must hold f.mu.
+func (f *Fake) publishTaskEvent(evType pb.TaskEvent_Type, task *pb.Task) {
+	f.taskEventSeq++
+	ev := &pb.TaskEvent{
+		Type:        evType,
+		Task:        proto.Clone(task).(*pb.Task),
+		ResumeToken: f.taskEventSeq,
+	}
+	f.taskEvents = append(f.taskEvents, ev)
+	if len(f.taskEvents) > taskEventBufferSize {
+		f.taskEvents = f.taskEvents[len(f.taskEvents)-taskEventBufferSize:]
+	}
+
+	live := f.taskWatchers[:0]
+	for _, w := range f.taskWatchers {
+		if !w.matches(f, task.GetName()) {
+			live = append(live, w)
+			continue
+		}
+		select {
+		case w.events <- ev:
+			live = append(live, w)
+		default:
+			close(w.dropped)
+		}
+	}
+	f.taskWatchers = live
+}
+
+// defaultMaxResultBytes is the value of MaxResultBytes used when it is left
+// at its zero value.
+const defaultMaxResultBytes = 1 << 20 // 1 MiB
+
+// maxResultBytes returns the effective result-size limit for f.
+func (f *Fake) maxResultBytes() int {
+	if f.MaxResultBytes > 0 {
+		return f.MaxResultBytes
+	}
+	return defaultMaxResultBytes
+}
+
+// Close is a no-op: Fake holds no resources that need releasing. It exists so
+// that *Fake satisfies backend.Server.
+func (f *Fake) Close(ctx context.Context) error {
+	return nil
+}
+
 // New creates a new Fake ready to use.
 func New() *Fake {
 	return &Fake{
@@ -121,7 +500,10 @@ func New() *Fake {
 		nextLabelID:     1,
 		labels:          nil,
 		labelIndices:    make(map[string]int),
+		labelStrings:    make(map[string]int),
 		labelPageTokens: make(map[string]pageToken),
+
+		taskLabels: make(map[string]map[string]struct{}),
 	}
 }
 
@@ -210,6 +592,20 @@ func (f *Fake) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.Lis
 	if pageSize == 0 || pageSize > maxPageSize {
 		pageSize = maxPageSize
 	}
+	labelFilterNames, labelFilterOp, err := parseTaskLabelFilter(req.GetLabelFilter())
+	if err != nil {
+		return nil, err
+	}
+	filterExpr, err := filter.Parse(req.GetFilter())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "The filter %q is invalid: %v", req.GetFilter(), err)
+	}
+	// Validate the filter against the schema up front, so that an invalid
+	// field or operator is rejected even if there are no tasks to match it
+	// against yet.
+	if _, err := filter.Match(filterExpr, taskFilterSchema, taskFields(&pb.Task{})); err != nil {
+		return nil, err
+	}
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -220,7 +616,7 @@ func (f *Fake) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.Lis
 		if !ok {
 			return nil, status.Errorf(codes.InvalidArgument, "The page token %q is invalid.", req.GetPageToken())
 		}
-		if req.GetShowDeleted() != pt.ShowDeleted {
+		if req.GetShowDeleted() != pt.ShowDeleted || req.GetFilter() != pt.Filter {
 			return nil, status.Errorf(codes.InvalidArgument, "The page token %q is invalid.", req.GetPageToken())
 		}
 		minIndex = pt.MinimumIndex
@@ -237,6 +633,14 @@ func (f *Fake) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.Lis
 		if task.GetDeleteTime().IsValid() && !req.GetShowDeleted() {
 			continue
 		}
+		if len(labelFilterNames) > 0 && !f.taskMatchesLabelFilter(task.GetName(), labelFilterNames, labelFilterOp) {
+			continue
+		}
+		if ok, err := filter.Match(filterExpr, taskFilterSchema, taskFields(task)); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
 		res.Tasks = append(res.GetTasks(), proto.Clone(task).(*pb.Task))
 	}
 
@@ -250,6 +654,7 @@ func (f *Fake) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.Lis
 		f.taskPageTokens[token] = pageToken{
 			MinimumIndex: nextMinIndex,
 			ShowDeleted:  req.GetShowDeleted(),
+			Filter:       req.GetFilter(),
 		}
 		res.NextPageToken = token
 	}
@@ -264,6 +669,10 @@ func (f *Fake) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.T
 	if task.GetCompleteTime().IsValid() {
 		return nil, status.Error(codes.InvalidArgument, "The task must not already be completed.")
 	}
+	taskID := req.GetTaskId()
+	if taskID != "" && !customIDRE.MatchString(taskID) {
+		return nil, status.Errorf(codes.InvalidArgument, "The task_id %q contains invalid characters.", taskID)
+	}
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -278,12 +687,38 @@ func (f *Fake) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.T
 	}
 
 	created := proto.Clone(task).(*pb.Task)
-	id := f.nextTaskID
-	f.nextTaskID++
-	created.Name = "tasks/" + fmt.Sprint(id)
+	if taskID == "" {
+		id := f.nextTaskID
+		f.nextTaskID++
+		created.Name = "tasks/" + fmt.Sprint(id)
+	} else {
+		name := "tasks/" + taskID
+		if idx, ok := f.taskIndices[name]; ok {
+			existing := f.tasks[idx]
+			if expire := existing.GetExpireTime(); !expire.IsValid() || !f.now().After(expire.AsTime()) {
+				return nil, ErrTaskIDConflict
+			}
+		}
+		created.Name = name
+		// A client-supplied numeric ID must not be handed out again by the
+		// auto-increment path, so skip nextTaskID past it.
+		if n, err := strconv.Atoi(taskID); err == nil && n >= f.nextTaskID {
+			f.nextTaskID = n + 1
+		}
+	}
+	// Result, ResultType, and CompletedAt can only be set via SetTaskResult
+	// and CompleteTask respectively, never directly by the client.
+	created.Result = nil
+	created.ResultType = ""
+	created.CompletedAt = nil
 	created.CreateTime = timestamppb.New(f.now())
-	f.tasks = append(f.tasks, created)
-	f.taskIndices[created.Name] = len(f.tasks) - 1
+	if idx, ok := f.taskIndices[created.Name]; ok {
+		f.tasks[idx] = created
+	} else {
+		f.tasks = append(f.tasks, created)
+		f.taskIndices[created.Name] = len(f.tasks) - 1
+	}
+	f.publishTaskEvent(pb.TaskEvent_CREATED, created)
 	return created, nil
 }
 
@@ -317,12 +752,15 @@ func (f *Fake) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.T
 		if v := patch.GetDescription(); v != "" {
 			updateMask.Paths = append(updateMask.GetPaths(), "description")
 		}
+		if v := patch.GetRetention(); v != nil {
+			updateMask.Paths = append(updateMask.GetPaths(), "retention")
+		}
 	case len(paths) == 1 && paths[0] == "*":
 		updateMask = proto.Clone(taskUpdatableMask).(*fieldmaskpb.FieldMask)
 	}
 	for _, path := range updateMask.GetPaths() {
 		switch path {
-		case "parent", "completed", "create_time", "name":
+		case "parent", "completed", "create_time", "name", "result", "result_type", "completed_at":
 			return nil, status.Errorf(codes.InvalidArgument, "The field %q cannot be updated with UpdateTask.", path)
 		case "*":
 			// We handled the only valid case of giving a wildcard path above,
@@ -355,12 +793,15 @@ func (f *Fake) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.T
 			updated.Title = patch.GetTitle()
 		case "description":
 			updated.Description = patch.GetDescription()
+		case "retention":
+			updated.Retention = patch.GetRetention()
 		}
 	}
 	if !proto.Equal(task, updated) {
 		updated.UpdateTime = timestamppb.New(f.now())
 	}
 	f.tasks[idx] = updated
+	f.publishTaskEvent(pb.TaskEvent_UPDATED, updated)
 	return updated, nil
 }
 
@@ -399,6 +840,7 @@ func (f *Fake) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.T
 		}
 		deleted.DeleteTime = timestamppb.New(now)
 		deleted.ExpireTime = timestamppb.New(now.AddDate(0 /*years*/, 0 /*months*/, 30 /*days*/))
+		f.publishTaskEvent(pb.TaskEvent_DELETED, deleted)
 	}
 	return proto.Clone(root).(*pb.Task), nil
 }
@@ -439,6 +881,7 @@ func (f *Fake) UndeleteTask(ctx context.Context, req *pb.UndeleteTaskRequest) (*
 		task := f.tasks[i]
 		task.DeleteTime = nil
 		task.ExpireTime = nil
+		f.publishTaskEvent(pb.TaskEvent_UNDELETED, task)
 	}
 	return proto.Clone(f.tasks[idx]).(*pb.Task), nil
 }
@@ -483,6 +926,16 @@ func (f *Fake) CompleteTask(ctx context.Context, req *pb.CompleteTaskRequest) (*
 		completed := f.tasks[idx]
 		completed.CompleteTime = timestamppb.New(now)
 		completed.UpdateTime = timestamppb.New(now)
+		// CompletedAt records the first time the task was ever completed, and
+		// survives a later UncompleteTask, unlike CompleteTime, so that
+		// auditing can tell a task was once done even after it's reopened.
+		if completed.GetCompletedAt() == nil {
+			completed.CompletedAt = timestamppb.New(now)
+		}
+		if retention := completed.GetRetention(); retention != nil {
+			completed.ExpireTime = timestamppb.New(now.Add(retention.AsDuration()))
+		}
+		f.publishTaskEvent(pb.TaskEvent_COMPLETED, completed)
 	}
 	return proto.Clone(task).(*pb.Task), nil
 }
@@ -530,10 +983,476 @@ func (f *Fake) UncompleteTask(ctx context.Context, req *pb.UncompleteTaskRequest
 		uncompleted := f.tasks[idx]
 		uncompleted.CompleteTime = nil
 		uncompleted.UpdateTime = timestamppb.New(now)
+		// The retention-derived expire_time only makes sense while the task
+		// is completed; reopening it cancels the pending expiry.
+		uncompleted.ExpireTime = nil
+		f.publishTaskEvent(pb.TaskEvent_UNCOMPLETED, uncompleted)
 	}
 	return proto.Clone(task).(*pb.Task), nil
 }
 
+// SetTaskResult sets the result and result_type of a completed task. It
+// rejects tasks that haven't been completed yet, and payloads larger than
+// f.maxResultBytes().
+func (f *Fake) SetTaskResult(ctx context.Context, req *pb.SetTaskResultRequest) (*pb.Task, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+	}
+	if err := validateTaskName(name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+	}
+	if limit := f.maxResultBytes(); len(req.GetResult()) > limit {
+		return nil, status.Errorf(codes.InvalidArgument, "The result is %d bytes, which exceeds the maximum of %d bytes.", len(req.GetResult()), limit)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx, ok := f.taskIndices[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	task := f.tasks[idx]
+	if task.GetDeleteTime().IsValid() {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	if !task.GetCompleteTime().IsValid() {
+		return nil, status.Errorf(codes.FailedPrecondition, "Task %q must be completed before a result can be set on it.", name)
+	}
+	updated := proto.Clone(task).(*pb.Task)
+	updated.Result = req.GetResult()
+	updated.ResultType = req.GetResultType()
+	updated.UpdateTime = timestamppb.New(f.now())
+	f.tasks[idx] = updated
+	f.publishTaskEvent(pb.TaskEvent_UPDATED, updated)
+	return proto.Clone(updated).(*pb.Task), nil
+}
+
+// WatchTasks streams task mutation events to the caller. If req.GetResumeToken()
+// is non-zero, it first replays buffered events newer than the token before
+// switching to live events; if the token is older than the buffered window
+// it returns codes.OutOfRange. A subscriber that can't keep up with the
+// event rate is disconnected with codes.ResourceExhausted.
+func (f *Fake) WatchTasks(req *pb.WatchTasksRequest, stream pb.Tasks_WatchTasksServer) error {
+	labelFilterNames, labelFilterOp, err := parseTaskLabelFilter(req.GetFilter())
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	var replay []*pb.TaskEvent
+	if token := req.GetResumeToken(); token != 0 {
+		if token > f.taskEventSeq {
+			f.mu.Unlock()
+			return status.Errorf(codes.InvalidArgument, "The resume_token %d is from the future.", token)
+		}
+		if len(f.taskEvents) > 0 && token < f.taskEvents[0].GetResumeToken()-1 {
+			f.mu.Unlock()
+			return status.Errorf(codes.OutOfRange, "The resume_token %d is too old; events have already been discarded.", token)
+		}
+		for _, ev := range f.taskEvents {
+			if ev.GetResumeToken() > token {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	w := &taskWatcher{
+		events:           make(chan *pb.TaskEvent, taskWatcherBufferSize),
+		dropped:          make(chan struct{}),
+		labelFilterNames: labelFilterNames,
+		labelFilterOp:    labelFilterOp,
+	}
+	f.taskWatchers = append(f.taskWatchers, w)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, candidate := range f.taskWatchers {
+			if candidate == w {
+				f.taskWatchers = append(f.taskWatchers[:i], f.taskWatchers[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	for _, ev := range replay {
+		if !w.matches(f, ev.GetTask().GetName()) {
+			continue
+		}
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-w.dropped:
+			return status.Error(codes.ResourceExhausted, "The subscriber fell too far behind and was disconnected.")
+		case ev := <-w.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// BatchCreateTasks creates up to maxBatchSize tasks in one call. Every
+// sub-request is validated before any task is created, so the batch either
+// creates all of them or none of them. The response preserves request
+// order.
+func (f *Fake) BatchCreateTasks(ctx context.Context, req *pb.BatchCreateTasksRequest) (*pb.BatchCreateTasksResponse, error) {
+	reqs := req.GetRequests()
+	if len(reqs) == 0 {
+		return &pb.BatchCreateTasksResponse{}, nil
+	}
+	if len(reqs) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d requests are allowed per batch, but got %d.", maxBatchSize, len(reqs))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, len(reqs)) // "" means auto-generate on apply
+	batchErrs := map[int]error{}
+	seen := map[string]bool{}
+	for i, r := range reqs {
+		task := r.GetTask()
+		if task.GetTitle() == "" {
+			batchErrs[i] = status.Error(codes.InvalidArgument, "The task must have a title.")
+			continue
+		}
+		if task.GetCompleteTime().IsValid() {
+			batchErrs[i] = status.Error(codes.InvalidArgument, "The task must not already be completed.")
+			continue
+		}
+		taskID := r.GetTaskId()
+		if taskID != "" && !customIDRE.MatchString(taskID) {
+			batchErrs[i] = status.Errorf(codes.InvalidArgument, "The task_id %q contains invalid characters.", taskID)
+			continue
+		}
+		if parent := task.GetParent(); parent != "" {
+			if err := validateTaskName(parent); err != nil {
+				batchErrs[i] = status.Errorf(codes.InvalidArgument, `The name of the parent must follow the format "tasks/{task}", but it was %q.`, parent)
+				continue
+			}
+			if _, ok := f.taskIndices[parent]; !ok {
+				batchErrs[i] = status.Errorf(codes.NotFound, "A parent task with name %q does not exist.", parent)
+				continue
+			}
+		}
+		if taskID == "" {
+			continue
+		}
+		name := "tasks/" + taskID
+		if seen[name] {
+			batchErrs[i] = status.Errorf(codes.AlreadyExists, "Duplicate task_id %q within the same batch.", taskID)
+			continue
+		}
+		if idx, ok := f.taskIndices[name]; ok {
+			existing := f.tasks[idx]
+			if expire := existing.GetExpireTime(); !expire.IsValid() || !f.now().After(expire.AsTime()) {
+				batchErrs[i] = ErrTaskIDConflict
+				continue
+			}
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	if len(batchErrs) > 0 {
+		return nil, newBatchError(batchErrs)
+	}
+
+	res := &pb.BatchCreateTasksResponse{}
+	now := f.now()
+	for i, r := range reqs {
+		created := proto.Clone(r.GetTask()).(*pb.Task)
+		created.Result = nil
+		created.ResultType = ""
+		created.CompletedAt = nil
+		if names[i] == "" {
+			id := f.nextTaskID
+			f.nextTaskID++
+			created.Name = "tasks/" + fmt.Sprint(id)
+		} else {
+			created.Name = names[i]
+			if n, err := strconv.Atoi(r.GetTaskId()); err == nil && n >= f.nextTaskID {
+				f.nextTaskID = n + 1
+			}
+		}
+		created.CreateTime = timestamppb.New(now)
+		if idx, ok := f.taskIndices[created.Name]; ok {
+			f.tasks[idx] = created
+		} else {
+			f.tasks = append(f.tasks, created)
+			f.taskIndices[created.Name] = len(f.tasks) - 1
+		}
+		f.publishTaskEvent(pb.TaskEvent_CREATED, created)
+		res.Tasks = append(res.Tasks, created)
+	}
+	return res, nil
+}
+
+// BatchUpdateTasks updates up to maxBatchSize tasks in one call. Every
+// sub-request is validated before any task is updated, so the batch either
+// applies all of them or none of them. Unlike UpdateTask, the same task
+// name may not appear twice in one batch.
+func (f *Fake) BatchUpdateTasks(ctx context.Context, req *pb.BatchUpdateTasksRequest) (*pb.BatchUpdateTasksResponse, error) {
+	reqs := req.GetRequests()
+	if len(reqs) == 0 {
+		return &pb.BatchUpdateTasksResponse{}, nil
+	}
+	if len(reqs) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d requests are allowed per batch, but got %d.", maxBatchSize, len(reqs))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	updates := make([]*pb.Task, len(reqs))
+	indices := make([]int, len(reqs))
+	batchErrs := map[int]error{}
+	seen := map[string]bool{}
+	for i, r := range reqs {
+		patch := r.GetTask()
+		name := patch.GetName()
+		if name == "" {
+			batchErrs[i] = status.Error(codes.InvalidArgument, "The name of the task is required.")
+			continue
+		}
+		if err := validateTaskName(name); err != nil {
+			batchErrs[i] = status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+			continue
+		}
+		if seen[name] {
+			batchErrs[i] = status.Errorf(codes.InvalidArgument, "Task %q appears more than once in the same batch.", name)
+			continue
+		}
+		seen[name] = true
+		updateMask := r.GetUpdateMask()
+		if updateMask == nil {
+			updateMask = &fieldmaskpb.FieldMask{}
+		}
+		switch paths := updateMask.GetPaths(); {
+		case len(paths) == 0:
+			if v := patch.GetTitle(); v != "" {
+				updateMask.Paths = append(updateMask.GetPaths(), "title")
+			}
+			if v := patch.GetDescription(); v != "" {
+				updateMask.Paths = append(updateMask.GetPaths(), "description")
+			}
+			if v := patch.GetRetention(); v != nil {
+				updateMask.Paths = append(updateMask.GetPaths(), "retention")
+			}
+		case len(paths) == 1 && paths[0] == "*":
+			updateMask = proto.Clone(taskUpdatableMask).(*fieldmaskpb.FieldMask)
+		}
+		invalid := false
+		for _, path := range updateMask.GetPaths() {
+			switch path {
+			case "parent", "completed", "create_time", "name", "result", "result_type", "completed_at":
+				batchErrs[i] = status.Errorf(codes.InvalidArgument, "The field %q cannot be updated with BatchUpdateTasks.", path)
+				invalid = true
+			case "*":
+				batchErrs[i] = status.Error(codes.InvalidArgument, "A wildcard can only be used if it is the single path in the update mask.")
+				invalid = true
+			}
+			if invalid {
+				break
+			}
+		}
+		if invalid {
+			continue
+		}
+		if !updateMask.IsValid(&pb.Task{}) {
+			batchErrs[i] = status.Error(codes.InvalidArgument, "The given update mask is invalid.")
+			continue
+		}
+		idx, ok := f.taskIndices[name]
+		if !ok {
+			batchErrs[i] = status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+			continue
+		}
+		task := f.tasks[idx]
+		if task.GetDeleteTime().IsValid() {
+			batchErrs[i] = status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+			continue
+		}
+		updated := proto.Clone(task).(*pb.Task)
+		for _, path := range updateMask.GetPaths() {
+			switch path {
+			case "title":
+				updated.Title = patch.GetTitle()
+			case "description":
+				updated.Description = patch.GetDescription()
+			case "retention":
+				updated.Retention = patch.GetRetention()
+			}
+		}
+		indices[i] = idx
+		updates[i] = updated
+	}
+	if len(batchErrs) > 0 {
+		return nil, newBatchError(batchErrs)
+	}
+
+	res := &pb.BatchUpdateTasksResponse{}
+	now := f.now()
+	for i, updated := range updates {
+		if !proto.Equal(f.tasks[indices[i]], updated) {
+			updated.UpdateTime = timestamppb.New(now)
+		}
+		f.tasks[indices[i]] = updated
+		f.publishTaskEvent(pb.TaskEvent_UPDATED, updated)
+		res.Tasks = append(res.Tasks, updated)
+	}
+	return res, nil
+}
+
+// BatchDeleteTasks soft-deletes up to maxBatchSize tasks in one call. Every
+// sub-request is validated before any task is deleted, so the batch either
+// deletes all of them or none of them. Unlike DeleteTask, batch deletes
+// don't cascade to children; a task with children always fails validation.
+func (f *Fake) BatchDeleteTasks(ctx context.Context, req *pb.BatchDeleteTasksRequest) (*emptypb.Empty, error) {
+	names := req.GetNames()
+	if len(names) == 0 {
+		return &emptypb.Empty{}, nil
+	}
+	if len(names) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d requests are allowed per batch, but got %d.", maxBatchSize, len(names))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	indices := make([]int, len(names))
+	batchErrs := map[int]error{}
+	seen := map[string]bool{}
+	for i, name := range names {
+		if name == "" {
+			batchErrs[i] = status.Error(codes.InvalidArgument, "The name of the task is required.")
+			continue
+		}
+		if err := validateTaskName(name); err != nil {
+			batchErrs[i] = status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+			continue
+		}
+		if seen[name] {
+			batchErrs[i] = status.Errorf(codes.InvalidArgument, "Task %q appears more than once in the same batch.", name)
+			continue
+		}
+		seen[name] = true
+		idx, ok := f.taskIndices[name]
+		if !ok {
+			batchErrs[i] = status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+			continue
+		}
+		if f.tasks[idx].GetDeleteTime().IsValid() {
+			batchErrs[i] = status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+			continue
+		}
+		if len(f.descendantIndices(name)) > 0 {
+			batchErrs[i] = status.Errorf(codes.FailedPrecondition, "Task %q has children; batch deletes don't cascade.", name)
+			continue
+		}
+		indices[i] = idx
+	}
+	if len(batchErrs) > 0 {
+		return nil, newBatchError(batchErrs)
+	}
+
+	now := f.now()
+	for _, idx := range indices {
+		deleted := f.tasks[idx]
+		deleted.DeleteTime = timestamppb.New(now)
+		deleted.ExpireTime = timestamppb.New(now.AddDate(0 /*years*/, 0 /*months*/, 30 /*days*/))
+		f.publishTaskEvent(pb.TaskEvent_DELETED, deleted)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// BatchCompleteTasks completes up to maxBatchSize tasks in one call.
+// Following AIP-231, a single bad item does not abort the whole batch: each
+// name gets its own *pb.BatchTaskResult with a status code, mirroring the
+// real service's BatchCompleteTasks. A malformed task name still fails the
+// whole call with InvalidArgument, since there's no task to attach a
+// per-item result to; a task not existing, a task with uncompleted children
+// and no force, and completing an already-completed task (a no-op, same as
+// CompleteTask) are all per-item outcomes instead.
+func (f *Fake) BatchCompleteTasks(ctx context.Context, req *pb.BatchCompleteTasksRequest) (*pb.BatchCompleteTasksResponse, error) {
+	names := req.GetNames()
+	if len(names) == 0 {
+		return &pb.BatchCompleteTasksResponse{}, nil
+	}
+	if len(names) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d requests are allowed per batch, but got %d.", maxBatchSize, len(names))
+	}
+	for _, name := range names {
+		if name == "" {
+			return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+		}
+		if err := validateTaskName(name); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results := make([]*pb.BatchTaskResult, len(names))
+	toComplete := map[int]bool{} // index into f.tasks
+	pending := map[int]int{}    // index into names/results -> index into f.tasks, filled in once completion is applied below
+	for i, name := range names {
+		idx, ok := f.taskIndices[name]
+		if !ok || f.tasks[idx].GetDeleteTime().IsValid() {
+			results[i] = &pb.BatchTaskResult{Name: name, Code: int32(codes.NotFound), Message: fmt.Sprintf("A task with name %q does not exist.", name)}
+			continue
+		}
+		task := f.tasks[idx]
+		if task.GetCompleteTime().IsValid() {
+			results[i] = &pb.BatchTaskResult{Name: name, Task: proto.Clone(task).(*pb.Task)}
+			continue
+		}
+		var uncompletedDescendants []int
+		for _, di := range f.descendantIndices(name) {
+			if !f.tasks[di].GetCompleteTime().IsValid() {
+				uncompletedDescendants = append(uncompletedDescendants, di)
+			}
+		}
+		if len(uncompletedDescendants) > 0 && !req.GetForce() {
+			results[i] = &pb.BatchTaskResult{Name: name, Code: int32(codes.FailedPrecondition), Message: fmt.Sprintf("Task %q has uncompleted children but `force` was not set to true.", name)}
+			continue
+		}
+		toComplete[idx] = true
+		for _, di := range uncompletedDescendants {
+			toComplete[di] = true
+		}
+		pending[i] = idx
+	}
+
+	now := f.now()
+	for idx := range toComplete {
+		task := f.tasks[idx]
+		task.CompleteTime = timestamppb.New(now)
+		task.UpdateTime = timestamppb.New(now)
+		// CompletedAt records the first time the task was ever completed, and
+		// survives a later UncompleteTask, unlike CompleteTime.
+		if task.GetCompletedAt() == nil {
+			task.CompletedAt = timestamppb.New(now)
+		}
+		if retention := task.GetRetention(); retention != nil {
+			task.ExpireTime = timestamppb.New(now.Add(retention.AsDuration()))
+		}
+		f.publishTaskEvent(pb.TaskEvent_COMPLETED, task)
+	}
+	for i, idx := range pending {
+		results[i] = &pb.BatchTaskResult{Name: names[i], Task: proto.Clone(f.tasks[idx]).(*pb.Task)}
+	}
+	return &pb.BatchCompleteTasksResponse{Results: results}, nil
+}
+
 func (f *Fake) GetProject(ctx context.Context, req *pb.GetProjectRequest) (*pb.Project, error) {
 	name := req.GetName()
 	if name == "" {
@@ -566,6 +1485,53 @@ func (f *Fake) ListProjects(ctx context.Context, req *pb.ListProjectsRequest) (*
 		pageSize = maxPageSize
 	}
 
+	filterExpr, err := filter.Parse(req.GetFilter())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "The filter %q is invalid: %v", req.GetFilter(), err)
+	}
+	// Validate the filter against the schema up front, so that an invalid
+	// field or operator is rejected even if there are no projects to match it
+	// against yet.
+	if _, err := filter.Match(filterExpr, projectFilterSchema, projectFields(&pb.Project{})); err != nil {
+		return nil, err
+	}
+
+	orderByTerms, err := orderby.Parse(req.GetOrderBy())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "The order_by %q is invalid: %v", req.GetOrderBy(), err)
+	}
+	// ARCHIVED listings default to sorting by archive_time, rather than the
+	// usual creation order, unless the caller asked for an explicit order_by.
+	if len(orderByTerms) == 0 && req.GetStateFilter() == pb.ListProjectsRequest_ARCHIVED {
+		orderByTerms = []orderby.Term{{Field: "archive_time"}}
+	}
+	// Validate the order_by fields up front, the same way the SQL-backed
+	// implementation does via orderby.ToSQL; the generated SQL itself is
+	// unused, since sorting here is done in memory by projectLess.
+	if _, err := orderby.ToSQL(orderByTerms, projectOrderByColumns, ""); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "The order_by %q is invalid: %v", req.GetOrderBy(), err)
+	}
+
+	showDeleted := req.GetShowDeleted()
+	showArchived := req.GetShowArchived()
+	// state_filter takes precedence over show_deleted/show_archived when
+	// set; STATE_UNSPECIFIED preserves the legacy behavior of those two
+	// booleans, for backward compatibility.
+	var trashedOnly, archivedOnly bool
+	switch req.GetStateFilter() {
+	case pb.ListProjectsRequest_STATE_UNSPECIFIED:
+	case pb.ListProjectsRequest_ACTIVE:
+		showDeleted, showArchived = false, false
+	case pb.ListProjectsRequest_TRASHED:
+		showDeleted, trashedOnly = true, true
+	case pb.ListProjectsRequest_ARCHIVED:
+		showArchived, archivedOnly = true, true
+	case pb.ListProjectsRequest_ALL:
+		showDeleted, showArchived = true, true
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown state_filter %v.", req.GetStateFilter())
+	}
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -575,36 +1541,78 @@ func (f *Fake) ListProjects(ctx context.Context, req *pb.ListProjectsRequest) (*
 		if !ok {
 			return nil, status.Errorf(codes.InvalidArgument, "The page token %q is invalid.", token)
 		}
-		if req.GetShowDeleted() != pt.ShowDeleted {
+		if req.GetShowDeleted() != pt.ShowDeleted || req.GetFilter() != pt.Filter || req.GetOrderBy() != pt.OrderBy || req.GetStateFilter().String() != pt.StateFilter {
 			return nil, status.Errorf(codes.InvalidArgument, "The page token %q is invalid.", token)
 		}
 		minIndex = pt.MinimumIndex
 		delete(f.projectPageTokens, token)
 	}
 
-	// Start adding projects that we will return.
-	res := &pb.ListProjectsResponse{}
-	for idx := minIndex; idx < len(f.projects) && len(res.GetProjects()) <= int(pageSize); idx++ {
+	// Collect every matching project from minIndex onwards, keeping track of
+	// each one's original index in f.projects so that a next page token can
+	// be derived after sorting.
+	type match struct {
+		idx     int
+		project *pb.Project
+	}
+	var matches []match
+	for idx := minIndex; idx < len(f.projects); idx++ {
 		project := f.projects[idx]
 		if expire := project.GetExpireTime(); expire.IsValid() && f.now().After(expire.AsTime()) {
 			continue
 		}
-		if project.GetDeleteTime().IsValid() && !req.GetShowDeleted() {
+		deleted := project.GetDeleteTime().IsValid()
+		if deleted && !showDeleted {
+			continue
+		}
+		if trashedOnly && !deleted {
+			continue
+		}
+		archived := project.GetArchiveTime().IsValid()
+		if archived && !showArchived {
+			continue
+		}
+		if archivedOnly && !archived {
+			continue
+		}
+		if ok, err := filter.Match(filterExpr, projectFilterSchema, projectFields(project)); err != nil {
+			return nil, err
+		} else if !ok {
 			continue
 		}
-		res.Projects = append(res.GetProjects(), proto.Clone(project).(*pb.Project))
+		matches = append(matches, match{idx: idx, project: project})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return projectLess(orderByTerms, matches[i].project, matches[j].project)
+	})
+
+	res := &pb.ListProjectsResponse{}
+	for i := 0; i < len(matches) && i <= int(pageSize); i++ {
+		res.Projects = append(res.GetProjects(), proto.Clone(matches[i].project).(*pb.Project))
 	}
 
-	// If there is one extra project, use it to create a new page token.
+	// If there is one extra project, use it (and every other project we
+	// looked at) to create a new page token.
 	if len(res.GetProjects()) == int(pageSize)+1 {
-		nextProject := res.GetProjects()[len(res.GetProjects())-1]
 		res.Projects = res.GetProjects()[:pageSize]
 
-		nextMinIndex := f.projectIndices[nextProject.GetName()]
+		// Mirror the "id-windowed" scheme used by ListTasks/ListLabels: the
+		// next minimum index is the maximum original index among everything
+		// we looked at on this page, not merely the cutoff project's index,
+		// since sorting may have reordered them relative to f.projects.
+		nextMinIndex := matches[0].idx
+		for _, m := range matches[:pageSize+1] {
+			if m.idx > nextMinIndex {
+				nextMinIndex = m.idx
+			}
+		}
 		token := uuid.NewString()
 		f.projectPageTokens[token] = pageToken{
 			MinimumIndex: nextMinIndex,
 			ShowDeleted:  req.GetShowDeleted(),
+			Filter:       req.GetFilter(),
+			OrderBy:      req.GetOrderBy(),
+			StateFilter:  req.GetStateFilter().String(),
 		}
 		res.NextPageToken = token
 	}
@@ -616,17 +1624,40 @@ func (f *Fake) CreateProject(ctx context.Context, req *pb.CreateProjectRequest)
 	if project.GetTitle() == "" {
 		return nil, status.Error(codes.InvalidArgument, "The project must have a title.")
 	}
+	projectID := req.GetProjectId()
+	if projectID != "" && !customIDRE.MatchString(projectID) {
+		return nil, status.Errorf(codes.InvalidArgument, "The project_id %q contains invalid characters.", projectID)
+	}
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	created := proto.Clone(project).(*pb.Project)
-	id := f.nextProjectID
-	f.nextProjectID++
-	created.Name = "projects/" + fmt.Sprint(id)
+	if projectID == "" {
+		id := f.nextProjectID
+		f.nextProjectID++
+		created.Name = "projects/" + fmt.Sprint(id)
+	} else {
+		name := "projects/" + projectID
+		if idx, ok := f.projectIndices[name]; ok {
+			existing := f.projects[idx]
+			if expire := existing.GetExpireTime(); !expire.IsValid() || !f.now().After(expire.AsTime()) {
+				return nil, ErrProjectIDConflict
+			}
+		}
+		created.Name = name
+		if n, err := strconv.Atoi(projectID); err == nil && n >= f.nextProjectID {
+			f.nextProjectID = n + 1
+		}
+	}
 	created.CreateTime = timestamppb.New(f.now())
-	f.projects = append(f.projects, created)
-	f.projectIndices[created.Name] = len(f.projects) - 1
+	created.Etag = computeEtag(created.GetName(), created.GetUpdateTime(), created.GetDeleteTime(), created.GetArchiveTime())
+	if idx, ok := f.projectIndices[created.Name]; ok {
+		f.projects[idx] = created
+	} else {
+		f.projects = append(f.projects, created)
+		f.projectIndices[created.Name] = len(f.projects) - 1
+	}
 	return created, nil
 }
 
@@ -691,6 +1722,9 @@ func (f *Fake) UpdateProject(ctx context.Context, req *pb.UpdateProjectRequest)
 	if project.GetDeleteTime().IsValid() {
 		return nil, status.Errorf(codes.NotFound, "A project with name %q does not exist.", name)
 	}
+	if err := checkIfMatch(req.GetIfMatch(), project.GetEtag()); err != nil {
+		return nil, err
+	}
 	updated := proto.Clone(project).(*pb.Project)
 	for _, path := range updateMask.GetPaths() {
 		switch path {
@@ -702,6 +1736,7 @@ func (f *Fake) UpdateProject(ctx context.Context, req *pb.UpdateProjectRequest)
 	}
 	if !proto.Equal(project, updated) {
 		updated.UpdateTime = timestamppb.New(f.now())
+		updated.Etag = computeEtag(updated.GetName(), updated.GetUpdateTime(), updated.GetDeleteTime(), updated.GetArchiveTime())
 	}
 	f.projects[idx] = updated
 	return updated, nil
@@ -897,24 +1932,51 @@ func (f *Fake) CreateLabel(ctx context.Context, req *pb.CreateLabelRequest) (*pb
 	if !labelRE.MatchString(labelString) {
 		return nil, status.Errorf(codes.InvalidArgument, "Label string %q contains invalid characters.", labelString)
 	}
+	labelID := req.GetLabelId()
+	if labelID != "" && !customIDRE.MatchString(labelID) {
+		return nil, status.Errorf(codes.InvalidArgument, "The label_id %q contains invalid characters.", labelID)
+	}
+	color := label.GetColor()
+	if color != "" && !colorRE.MatchString(color) {
+		return nil, status.Errorf(codes.InvalidArgument, "The color %q must be a 6-character hex string, without a leading '#'.", color)
+	}
+	if len(label.GetDescription()) > maxLabelDescriptionLen {
+		return nil, status.Errorf(codes.InvalidArgument, "The description must be at most %d characters; was %d.", maxLabelDescriptionLen, len(label.GetDescription()))
+	}
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	for _, existing := range f.labels {
-		s := label.GetLabel()
-		if s == existing.GetLabel() {
-			return nil, status.Errorf(codes.AlreadyExists, "The label %q already exists as %q.", s, existing.GetName())
-		}
+	if idx, ok := f.labelStrings[labelString]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "The label %q already exists as %q.", labelString, f.labels[idx].GetName())
 	}
 
 	created := proto.Clone(label).(*pb.Label)
-	id := f.nextLabelID
-	f.nextLabelID++
-	created.Name = "labels/" + fmt.Sprint(id)
+	if created.GetColor() == "" {
+		created.Color = defaultLabelColor
+	}
+	if labelID == "" {
+		id := f.nextLabelID
+		f.nextLabelID++
+		created.Name = "labels/" + fmt.Sprint(id)
+	} else {
+		name := "labels/" + labelID
+		if idx, ok := f.labelIndices[name]; ok && f.labels[idx] != nil {
+			return nil, ErrLabelIDConflict
+		}
+		created.Name = name
+		if n, err := strconv.Atoi(labelID); err == nil && n >= f.nextLabelID {
+			f.nextLabelID = n + 1
+		}
+	}
 	created.CreateTime = timestamppb.New(f.now())
-	f.labels = append(f.labels, created)
-	f.labelIndices[created.Name] = len(f.labels) - 1
+	if idx, ok := f.labelIndices[created.Name]; ok {
+		f.labels[idx] = created
+	} else {
+		f.labels = append(f.labels, created)
+		f.labelIndices[created.Name] = len(f.labels) - 1
+	}
+	f.labelStrings[created.GetLabel()] = f.labelIndices[created.Name]
 	return created, nil
 }
 
@@ -945,6 +2007,12 @@ func (f *Fake) UpdateLabel(ctx context.Context, req *pb.UpdateLabelRequest) (*pb
 		if v := patch.GetLabel(); v != "" {
 			updateMask.Paths = append(updateMask.GetPaths(), "label")
 		}
+		if v := patch.GetColor(); v != "" {
+			updateMask.Paths = append(updateMask.GetPaths(), "color")
+		}
+		if v := patch.GetDescription(); v != "" {
+			updateMask.Paths = append(updateMask.GetPaths(), "description")
+		}
 	case len(paths) == 1 && paths[0] == "*":
 		updateMask = proto.Clone(labelUpdatableMask).(*fieldmaskpb.FieldMask)
 	}
@@ -980,29 +2048,43 @@ func (f *Fake) UpdateLabel(ctx context.Context, req *pb.UpdateLabelRequest) (*pb
 	for _, path := range updateMask.GetPaths() {
 		switch path {
 		case "label":
-			// If the new label string already exists, the update should fail.
+			// If the new label string already exists under a different
+			// name, the update should fail; setting the same string the
+			// label already has is a no-op update.
 			newLabel := patch.GetLabel()
-			for _, existing := range f.labels {
-				// We will eventually let be iterating over the label we're
-				// trying to update, and in that case setting an identical label
-				// string is okay (it's a no-op update).
-				if existing.GetName() == patch.GetName() {
-					continue
-				}
-				if newLabel == existing.GetLabel() {
-					return nil, status.Errorf(codes.AlreadyExists, "The label %q already exists as %q.", patch.GetLabel(), existing.GetName())
-				}
+			if existingIdx, ok := f.labelStrings[newLabel]; ok && f.labels[existingIdx].GetName() != name {
+				return nil, status.Errorf(codes.AlreadyExists, "The label %q already exists as %q.", newLabel, f.labels[existingIdx].GetName())
 			}
 			updated.Label = newLabel
+		case "color":
+			newColor := patch.GetColor()
+			if newColor != "" && !colorRE.MatchString(newColor) {
+				return nil, status.Errorf(codes.InvalidArgument, "The color %q must be a 6-character hex string, without a leading '#'.", newColor)
+			}
+			updated.Color = newColor
+		case "description":
+			newDescription := patch.GetDescription()
+			if len(newDescription) > maxLabelDescriptionLen {
+				return nil, status.Errorf(codes.InvalidArgument, "The description must be at most %d characters; was %d.", maxLabelDescriptionLen, len(newDescription))
+			}
+			updated.Description = newDescription
 		}
 	}
 	if !proto.Equal(label, updated) {
 		updated.UpdateTime = timestamppb.New(f.now())
 	}
+	if updated.GetLabel() != label.GetLabel() {
+		delete(f.labelStrings, label.GetLabel())
+		f.labelStrings[updated.GetLabel()] = idx
+	}
 	f.labels[idx] = updated
 	return updated, nil
 }
 
+// DeleteLabel deletes a label. If the label is still attached to one or
+// more tasks, what happens next is governed by f.CascadeOnLabelDelete (see
+// CascadePolicy), unless the request sets force: true, which always
+// detaches the label from those tasks instead of rejecting the delete.
 func (f *Fake) DeleteLabel(ctx context.Context, req *pb.DeleteLabelRequest) (*emptypb.Empty, error) {
 	name := req.GetName()
 	if name == "" {
@@ -1022,10 +2104,309 @@ func (f *Fake) DeleteLabel(ctx context.Context, req *pb.DeleteLabelRequest) (*em
 	if f.labels[idx] == nil {
 		return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", name)
 	}
+	var attachedTo []string
+	for taskName, set := range f.taskLabels {
+		if _, ok := set[name]; ok {
+			attachedTo = append(attachedTo, taskName)
+		}
+	}
+	sort.Strings(attachedTo)
+
+	policy := f.CascadeOnLabelDelete
+	if req.GetForce() {
+		policy = CascadePolicyDetach
+	}
+	if len(attachedTo) > 0 && policy == CascadePolicyReject {
+		return nil, status.Errorf(codes.FailedPrecondition, "Label %q is still attached to one or more tasks (%s); not deleting without `force: true`.", name, strings.Join(attachedTo, ", "))
+	}
+	for _, taskName := range attachedTo {
+		delete(f.taskLabels[taskName], name)
+		taskIdx, ok := f.taskIndices[taskName]
+		if !ok {
+			continue
+		}
+		task := f.tasks[taskIdx]
+		f.populateTaskLabels(task)
+		if policy == CascadePolicySoftDelete && !task.GetDeleteTime().IsValid() {
+			now := f.now()
+			task.DeleteTime = timestamppb.New(now)
+			task.ExpireTime = timestamppb.New(now.AddDate(0 /* years */, 0 /* months */, 30 /* days */))
+			f.publishTaskEvent(pb.TaskEvent_DELETED, task)
+		} else {
+			f.publishTaskEvent(pb.TaskEvent_LABELS_CHANGED, task)
+		}
+	}
+	delete(f.labelStrings, f.labels[idx].GetLabel())
 	f.labels[idx] = nil
 	return &emptypb.Empty{}, nil
 }
 
+// labelExists reports whether name refers to a label that exists and hasn't
+// been soft-deleted. f.mu must be held.
+func (f *Fake) labelExists(name string) bool {
+	idx, ok := f.labelIndices[name]
+	return ok && f.labels[idx] != nil
+}
+
+// labelID extracts the numeric ID from a label name of the form
+// "labels/{id}", returning 0 if it doesn't parse. That can't happen for
+// names this package itself generated.
+func labelID(name string) int {
+	id, _ := strconv.Atoi(strings.TrimPrefix(name, "labels/"))
+	return id
+}
+
+// sortedLabelNames returns names sorted the same way the SQL-backed
+// service orders a task's labels: ascending by label ID.
+func sortedLabelNames(names map[string]struct{}) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return labelID(sorted[i]) < labelID(sorted[j])
+	})
+	return sorted
+}
+
+// populateTaskLabels re-derives task.Labels from f.taskLabels, the source
+// of truth, mirroring how the SQL-backed service's populateTaskLabels
+// re-derives it from the task_labels table. f.mu must be held.
+func (f *Fake) populateTaskLabels(task *pb.Task) {
+	task.Labels = nil
+	for _, name := range sortedLabelNames(f.taskLabels[task.GetName()]) {
+		task.Labels = append(task.Labels, name)
+	}
+}
+
+// parseTaskLabelFilter validates the label names in filter, mirroring
+// service.parseLabelFilter, but returning the names themselves rather than
+// resolving them to IDs, since the fake keys everything by name. A nil
+// filter, or one with no labels, matches every task, reported by returning
+// a nil slice of names.
+func parseTaskLabelFilter(filter *pb.TaskLabelFilter) ([]string, pb.TaskLabelFilter_Operator, error) {
+	if len(filter.GetLabels()) == 0 {
+		return nil, pb.TaskLabelFilter_OPERATOR_UNSPECIFIED, nil
+	}
+	for _, name := range filter.GetLabels() {
+		if err := validateLabelName(name); err != nil {
+			return nil, 0, status.Errorf(codes.InvalidArgument, `The name of the label must have format "labels/{label}", but it was %q.`, name)
+		}
+	}
+	op := filter.GetOperator()
+	if op == pb.TaskLabelFilter_OPERATOR_UNSPECIFIED {
+		// OR is the least restrictive interpretation, so it's the default for
+		// callers that don't care to specify one.
+		op = pb.TaskLabelFilter_OR
+	}
+	return filter.GetLabels(), op, nil
+}
+
+// taskMatchesLabelFilter reports whether task's attached labels satisfy
+// names under op: AND requires every name to be attached, OR requires at
+// least one. f.mu must be held.
+func (f *Fake) taskMatchesLabelFilter(task string, names []string, op pb.TaskLabelFilter_Operator) bool {
+	set := f.taskLabels[task]
+	switch op {
+	case pb.TaskLabelFilter_AND:
+		for _, name := range names {
+			if _, ok := set[name]; !ok {
+				return false
+			}
+		}
+		return true
+	default: // pb.TaskLabelFilter_OR
+		for _, name := range names {
+			if _, ok := set[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AddLabelsToTask associates each given label with a task, ignoring labels
+// that are already associated. It mirrors the SQL-backed service's RPC of
+// the same name.
+func (f *Fake) AddLabelsToTask(ctx context.Context, req *pb.AddLabelsToTaskRequest) (*pb.Task, error) {
+	name := req.GetTask()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+	}
+	if err := validateTaskName(name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+	}
+	for _, label := range req.GetLabels() {
+		if err := validateLabelName(label); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, `The name of the label must have format "labels/{label}", but it was %q.`, label)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx, ok := f.taskIndices[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	task := f.tasks[idx]
+	if task.GetDeleteTime().IsValid() {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	for _, label := range req.GetLabels() {
+		if !f.labelExists(label) {
+			return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", label)
+		}
+	}
+	set := f.taskLabels[name]
+	if set == nil {
+		set = make(map[string]struct{})
+		f.taskLabels[name] = set
+	}
+	for _, label := range req.GetLabels() {
+		set[label] = struct{}{}
+	}
+	f.populateTaskLabels(task)
+	f.publishTaskEvent(pb.TaskEvent_LABELS_CHANGED, task)
+	return proto.Clone(task).(*pb.Task), nil
+}
+
+// RemoveLabelsFromTask removes the association between each given label and
+// a task, ignoring labels that aren't currently associated. It mirrors the
+// SQL-backed service's RPC of the same name.
+func (f *Fake) RemoveLabelsFromTask(ctx context.Context, req *pb.RemoveLabelsFromTaskRequest) (*pb.Task, error) {
+	name := req.GetTask()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+	}
+	if err := validateTaskName(name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+	}
+	for _, label := range req.GetLabels() {
+		if err := validateLabelName(label); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, `The name of the label must have format "labels/{label}", but it was %q.`, label)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx, ok := f.taskIndices[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	task := f.tasks[idx]
+	if task.GetDeleteTime().IsValid() {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	if set := f.taskLabels[name]; set != nil {
+		for _, label := range req.GetLabels() {
+			delete(set, label)
+		}
+	}
+	f.populateTaskLabels(task)
+	f.publishTaskEvent(pb.TaskEvent_LABELS_CHANGED, task)
+	return proto.Clone(task).(*pb.Task), nil
+}
+
+// ChangeTaskLabels adds and removes label associations for a task in a
+// single call, the way AddLabelsToTask followed by RemoveLabelsFromTask
+// would, but atomically and without firing two separate events. Labels in
+// added are attached if they aren't already; labels in removed are
+// detached if they are. If the net effect is empty -- e.g. every added
+// label was already attached and every removed one wasn't -- the task's
+// UpdateTime is left untouched, mirroring how UpdateTask only bumps it
+// when the patch actually changes something.
+func (f *Fake) ChangeTaskLabels(ctx context.Context, req *pb.ChangeTaskLabelsRequest) (*pb.Task, error) {
+	name := req.GetTask()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+	}
+	if err := validateTaskName(name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+	}
+	for _, label := range req.GetAdded() {
+		if err := validateLabelName(label); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, `The name of the label must have format "labels/{label}", but it was %q.`, label)
+		}
+	}
+	for _, label := range req.GetRemoved() {
+		if err := validateLabelName(label); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, `The name of the label must have format "labels/{label}", but it was %q.`, label)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx, ok := f.taskIndices[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	task := f.tasks[idx]
+	if task.GetDeleteTime().IsValid() {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	// Mirroring AddLabelsToTask, every added label must exist. Mirroring
+	// RemoveLabelsFromTask, removed labels that aren't currently attached
+	// (or don't exist at all) are silently ignored.
+	for _, label := range req.GetAdded() {
+		if !f.labelExists(label) {
+			return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", label)
+		}
+	}
+
+	before := proto.Clone(task).(*pb.Task)
+
+	set := f.taskLabels[name]
+	if set == nil {
+		set = make(map[string]struct{})
+		f.taskLabels[name] = set
+	}
+	for _, label := range req.GetAdded() {
+		set[label] = struct{}{}
+	}
+	for _, label := range req.GetRemoved() {
+		delete(set, label)
+	}
+	f.populateTaskLabels(task)
+	if !proto.Equal(before, task) {
+		task.UpdateTime = timestamppb.New(f.now())
+	}
+	f.publishTaskEvent(pb.TaskEvent_LABELS_CHANGED, task)
+	return proto.Clone(task).(*pb.Task), nil
+}
+
+// ListLabelsForTask returns every label currently associated with a task,
+// in ascending label ID order. It mirrors the SQL-backed service's RPC of
+// the same name.
+func (f *Fake) ListLabelsForTask(ctx context.Context, req *pb.ListLabelsForTaskRequest) (*pb.ListLabelsForTaskResponse, error) {
+	name := req.GetTask()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name of the task is required.")
+	}
+	if err := validateTaskName(name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, `The name of the task must have format "tasks/{task}", but it was %q.`, name)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx, ok := f.taskIndices[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	if f.tasks[idx].GetDeleteTime().IsValid() {
+		return nil, status.Errorf(codes.NotFound, "A task with name %q does not exist.", name)
+	}
+	res := &pb.ListLabelsForTaskResponse{}
+	for _, labelName := range sortedLabelNames(f.taskLabels[name]) {
+		label := f.labels[f.labelIndices[labelName]]
+		res.Labels = append(res.Labels, proto.Clone(label).(*pb.Label))
+	}
+	return res, nil
+}
+
 // now returns time.Now() except if f.clock is non-nil, then that clock is used
 // instead. now assumes that the mutex is held when called.
 func (f *Fake) now() time.Time {
@@ -2,13 +2,18 @@ package fake
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/suite"
 	"go.saser.se/grpctest"
 	pb "go.saser.se/tasks/tasks_go_proto"
 	"go.saser.se/tasks/testsuite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // truncater implements testsuite.Truncater to clean out state between tests or
@@ -56,3 +61,105 @@ func TestService(t *testing.T) {
 	s := testsuite.New(client, &truncater{s: svc}, clock, maxPageSize)
 	suite.Run(t, s)
 }
+
+func TestService_Hammer(t *testing.T) {
+	ctx := context.Background()
+	svc := New()
+	clock := clockwork.NewFakeClock()
+	svc.clock = clock
+	srv := grpctest.New(ctx, t, grpctest.Options{
+		ServiceDesc:    &pb.Tasks_ServiceDesc,
+		Implementation: svc,
+	})
+	client := pb.NewTasksClient(srv.ClientConn)
+	s := testsuite.New(client, &truncater{s: svc}, clock, maxPageSize)
+	s.SetT(t)
+	s.HammerListTasks()
+}
+
+// TestReap exercises the Reap lifecycle end-to-end: deleting a project
+// leaves it merely soft-deleted, advancing the fake clock 31 days past
+// its 30-day ExpireTime makes it eligible, and Reap then removes it for
+// good.
+func TestReap(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+	clock := clockwork.NewFakeClock()
+	f.clock = clock
+
+	created, err := f.CreateProject(ctx, &pb.CreateProjectRequest{Project: &pb.Project{Title: "reap me"}})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if _, err := f.DeleteProject(ctx, &pb.DeleteProjectRequest{Name: created.GetName()}); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+
+	if err := f.Reap(ctx); err != nil {
+		t.Fatalf("Reap (before expiry): %v", err)
+	}
+	if _, ok := f.projectIndices[created.GetName()]; !ok {
+		t.Errorf("project %q was reaped before its ExpireTime passed", created.GetName())
+	}
+
+	clock.Advance(31 * 24 * time.Hour)
+	if err := f.Reap(ctx); err != nil {
+		t.Fatalf("Reap (after expiry): %v", err)
+	}
+
+	if len(f.projects) != 0 {
+		t.Errorf("len(f.projects) = %d, want 0", len(f.projects))
+	}
+	if _, ok := f.projectIndices[created.GetName()]; ok {
+		t.Errorf("f.projectIndices still contains %q after reaping", created.GetName())
+	}
+	if _, err := f.UndeleteProject(ctx, &pb.UndeleteProjectRequest{Name: created.GetName()}); status.Code(err) != codes.NotFound {
+		t.Errorf("UndeleteProject after reap: err = %v, want NotFound", err)
+	}
+}
+
+// BenchmarkCreateLabel creates b.N labels against a single Fake, each with a
+// distinct label string. With f.labelStrings doing the uniqueness check,
+// each call should cost about the same regardless of how many labels
+// already exist.
+func BenchmarkCreateLabel(b *testing.B) {
+	ctx := context.Background()
+	f := New()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.CreateLabel(ctx, &pb.CreateLabelRequest{
+			Label: &pb.Label{Label: fmt.Sprintf("label-%d", i)},
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUpdateLabel pre-populates a Fake with 10k labels, then
+// repeatedly renames one of them. Like BenchmarkCreateLabel, this should
+// cost about the same per call whether the Fake holds 10 labels or 10k.
+func BenchmarkUpdateLabel(b *testing.B) {
+	ctx := context.Background()
+	f := New()
+	const n = 10000
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		created, err := f.CreateLabel(ctx, &pb.CreateLabelRequest{
+			Label: &pb.Label{Label: fmt.Sprintf("label-%d", i)},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		names[i] = created.GetName()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		name := names[i%n]
+		_, err := f.UpdateLabel(ctx, &pb.UpdateLabelRequest{
+			Label:      &pb.Label{Name: name, Label: fmt.Sprintf("label-%d-v%d", i%n, i)},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"label"}},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package fake
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Reap permanently removes every project that is soft-deleted and whose
+// ExpireTime has passed. Until a project is reaped, soft-deleting it only
+// affects reads -- GetProject, ListProjects, and CreateProject's project_id
+// reuse check already treat an expired-but-unreaped project as gone -- but
+// it still sits in f.projects forever, and its name can't actually be
+// reused by CreateProject's auto-assigned IDs. Reap is what actually frees
+// that memory and, crucially, causes UndeleteProject to start returning
+// NotFound for the project, since at that point its name is gone from
+// f.projectIndices entirely.
+func (f *Fake) Reap(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	minRemoved := -1
+	kept := f.projects[:0]
+	for i, p := range f.projects {
+		if p.GetDeleteTime().IsValid() {
+			if expire := p.GetExpireTime(); expire.IsValid() && !now.Before(expire.AsTime()) {
+				if minRemoved < 0 {
+					minRemoved = i
+				}
+				continue
+			}
+		}
+		kept = append(kept, p)
+	}
+	f.projects = kept
+
+	f.projectIndices = make(map[string]int, len(f.projects))
+	for i, p := range f.projects {
+		f.projectIndices[p.GetName()] = i
+	}
+
+	// Every page token whose MinimumIndex pointed at or past the first
+	// removed project is now stale: reaping shifted everything after it
+	// down. Tokens pointing earlier are unaffected and stay valid.
+	if minRemoved >= 0 {
+		for token, pt := range f.projectPageTokens {
+			if pt.MinimumIndex >= minRemoved {
+				delete(f.projectPageTokens, token)
+			}
+		}
+	}
+	return nil
+}
+
+// StartReaper starts a goroutine that calls Reap every interval, until ctx
+// is canceled or the returned stop function is called, and returns that
+// stop function. If f.clock is set (as it is in tests), the goroutine
+// waits on the fake clock instead of real time, so that tests can advance
+// the clock to trigger a reap deterministically instead of sleeping.
+func (f *Fake) StartReaper(ctx context.Context, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var after <-chan time.Time
+			stopTimer := func() {}
+			if f.clock != nil {
+				after = f.clock.After(interval)
+			} else {
+				timer := time.NewTimer(interval)
+				after = timer.C
+				stopTimer = func() { timer.Stop() }
+			}
+			select {
+			case <-ctx.Done():
+				stopTimer()
+				return
+			case <-after:
+				if err := f.Reap(ctx); err != nil {
+					klog.Errorf("fake: reap: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,251 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// snapshotVersion identifies the shape of the data Snapshot produces.
+// Restore rejects any snapshot whose version it doesn't recognize, rather
+// than guessing at how to interpret unfamiliar fields.
+const snapshotVersion = 1
+
+// snapshotEnvelope is the serialized form of a Fake's entire state, as
+// produced by Snapshot and consumed by Restore. The full protobuf build
+// pipeline isn't available in this tree, so the envelope itself is JSON
+// rather than a generated proto message; each task, project, and label is
+// still encoded with protojson, so the wire format of the individual
+// resources matches their proto definitions.
+type snapshotEnvelope struct {
+	Version int `json:"version"`
+
+	NextTaskID     int                  `json:"next_task_id"`
+	Tasks          []json.RawMessage    `json:"tasks"`
+	TaskPageTokens map[string]pageToken `json:"task_page_tokens"`
+	TaskLabels     map[string][]string  `json:"task_labels"`
+
+	NextProjectID     int                  `json:"next_project_id"`
+	Projects          []json.RawMessage    `json:"projects"`
+	ProjectPageTokens map[string]pageToken `json:"project_page_tokens"`
+
+	NextLabelID     int                  `json:"next_label_id"`
+	Labels          []json.RawMessage    `json:"labels"`
+	LabelPageTokens map[string]pageToken `json:"label_page_tokens"`
+
+	// Now is the fake clock's current time, in Unix nanoseconds, at the
+	// time of the snapshot. Zero if f.clock is nil.
+	Now int64 `json:"now"`
+}
+
+// Snapshot serializes f's entire state -- every task, project, and label,
+// their index maps, next-ID counters, page tokens, and label associations,
+// plus the fake clock's current time -- into an opaque, versioned byte
+// slice. A later call to Restore on any Fake reconstructs that exact state,
+// which gives tests a cheap way to fork state, run a scenario, and then
+// roll back to exactly where they started.
+func (f *Fake) Snapshot() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tasks := make([]json.RawMessage, len(f.tasks))
+	for i, t := range f.tasks {
+		b, err := protojson.Marshal(t)
+		if err != nil {
+			// f.tasks only ever holds messages we built ourselves, so this
+			// can't actually fail.
+			panic(fmt.Sprintf("fake: marshal task snapshot: %v", err))
+		}
+		tasks[i] = b
+	}
+	projects := make([]json.RawMessage, len(f.projects))
+	for i, p := range f.projects {
+		b, err := protojson.Marshal(p)
+		if err != nil {
+			panic(fmt.Sprintf("fake: marshal project snapshot: %v", err))
+		}
+		projects[i] = b
+	}
+	// Deleted labels leave a nil gap in f.labels (unlike tasks and
+	// projects, which are soft-deleted in place), so skip those rather
+	// than trying to marshal a nil message. The gap itself carries no
+	// meaning Restore needs to reconstruct.
+	labels := make([]json.RawMessage, 0, len(f.labels))
+	for _, l := range f.labels {
+		if l == nil {
+			continue
+		}
+		b, err := protojson.Marshal(l)
+		if err != nil {
+			panic(fmt.Sprintf("fake: marshal label snapshot: %v", err))
+		}
+		labels = append(labels, b)
+	}
+	taskLabels := make(map[string][]string, len(f.taskLabels))
+	for task, set := range f.taskLabels {
+		names := make([]string, 0, len(set))
+		for name := range set {
+			names = append(names, name)
+		}
+		taskLabels[task] = names
+	}
+
+	env := snapshotEnvelope{
+		Version: snapshotVersion,
+
+		NextTaskID:     f.nextTaskID,
+		Tasks:          tasks,
+		TaskPageTokens: f.taskPageTokens,
+		TaskLabels:     taskLabels,
+
+		NextProjectID:     f.nextProjectID,
+		Projects:          projects,
+		ProjectPageTokens: f.projectPageTokens,
+
+		NextLabelID:     f.nextLabelID,
+		Labels:          labels,
+		LabelPageTokens: f.labelPageTokens,
+	}
+	if f.clock != nil {
+		env.Now = f.clock.Now().UnixNano()
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		panic(fmt.Sprintf("fake: marshal snapshot: %v", err))
+	}
+	return b
+}
+
+// Restore replaces f's entire state with the state encoded in b, as
+// produced by a prior call to Snapshot. It rejects snapshots with an
+// unknown version, and rebuilds every index map from scratch rather than
+// trusting the serialized ones, so that a hand-edited or otherwise
+// inconsistent snapshot can't leave an index pointing at the wrong slice
+// element.
+func (f *Fake) Restore(b []byte) error {
+	var env snapshotEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return fmt.Errorf("fake: restore: invalid snapshot: %w", err)
+	}
+	if env.Version != snapshotVersion {
+		return fmt.Errorf("fake: restore: unknown snapshot version %d (want %d)", env.Version, snapshotVersion)
+	}
+
+	tasks := make([]*pb.Task, len(env.Tasks))
+	taskIndices := make(map[string]int, len(env.Tasks))
+	for i, raw := range env.Tasks {
+		t := &pb.Task{}
+		if err := protojson.Unmarshal(raw, t); err != nil {
+			return fmt.Errorf("fake: restore: invalid task at index %d: %w", i, err)
+		}
+		tasks[i] = t
+		taskIndices[t.GetName()] = i
+	}
+	projects := make([]*pb.Project, len(env.Projects))
+	projectIndices := make(map[string]int, len(env.Projects))
+	for i, raw := range env.Projects {
+		p := &pb.Project{}
+		if err := protojson.Unmarshal(raw, p); err != nil {
+			return fmt.Errorf("fake: restore: invalid project at index %d: %w", i, err)
+		}
+		projects[i] = p
+		projectIndices[p.GetName()] = i
+	}
+	labels := make([]*pb.Label, len(env.Labels))
+	labelIndices := make(map[string]int, len(env.Labels))
+	labelStrings := make(map[string]int, len(env.Labels))
+	for i, raw := range env.Labels {
+		l := &pb.Label{}
+		if err := protojson.Unmarshal(raw, l); err != nil {
+			return fmt.Errorf("fake: restore: invalid label at index %d: %w", i, err)
+		}
+		labels[i] = l
+		labelIndices[l.GetName()] = i
+		labelStrings[l.GetLabel()] = i
+	}
+	taskLabels := make(map[string]map[string]struct{}, len(env.TaskLabels))
+	for task, names := range env.TaskLabels {
+		set := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			set[name] = struct{}{}
+		}
+		taskLabels[task] = set
+	}
+	taskPageTokens := env.TaskPageTokens
+	if taskPageTokens == nil {
+		taskPageTokens = make(map[string]pageToken)
+	}
+	projectPageTokens := env.ProjectPageTokens
+	if projectPageTokens == nil {
+		projectPageTokens = make(map[string]pageToken)
+	}
+	labelPageTokens := env.LabelPageTokens
+	if labelPageTokens == nil {
+		labelPageTokens = make(map[string]pageToken)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextTaskID = env.NextTaskID
+	f.tasks = tasks
+	f.taskIndices = taskIndices
+	f.taskPageTokens = taskPageTokens
+	f.taskLabels = taskLabels
+
+	f.nextProjectID = env.NextProjectID
+	f.projects = projects
+	f.projectIndices = projectIndices
+	f.projectPageTokens = projectPageTokens
+
+	f.nextLabelID = env.NextLabelID
+	f.labels = labels
+	f.labelIndices = labelIndices
+	f.labelStrings = labelStrings
+	f.labelPageTokens = labelPageTokens
+
+	f.taskEvents = nil
+	f.taskWatchers = nil
+
+	if f.clock != nil && env.Now != 0 {
+		if delta := time.Unix(0, env.Now).Sub(f.clock.Now()); delta > 0 {
+			f.clock.Advance(delta)
+		}
+	}
+	return nil
+}
+
+// Reset clears f back to the same empty state produced by New, discarding
+// every task, project, and label, their index maps and page tokens, and
+// all label associations. The clock, if any was installed for testing, is
+// left untouched.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextTaskID = 1
+	f.tasks = nil
+	f.taskIndices = make(map[string]int)
+	f.taskPageTokens = make(map[string]pageToken)
+
+	f.nextProjectID = 1
+	f.projects = nil
+	f.projectIndices = make(map[string]int)
+	f.projectPageTokens = make(map[string]pageToken)
+
+	f.nextLabelID = 1
+	f.labels = nil
+	f.labelIndices = make(map[string]int)
+	f.labelStrings = make(map[string]int)
+	f.labelPageTokens = make(map[string]pageToken)
+
+	f.taskLabels = make(map[string]map[string]struct{})
+
+	f.taskEvents = nil
+	f.taskEventSeq = 0
+	f.taskWatchers = nil
+}
@@ -0,0 +1,159 @@
+// Package grpcserver wraps the tasks gRPC service in a Server type with a
+// synchronous Start/Stop lifecycle: Start binds its listener before
+// returning, so callers (integration tests in particular) know the server
+// is ready to accept connections without sleeping or guessing, and can read
+// back the bound address via Addr when -port=0 leaves the OS to pick one.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"go.saser.se/errdefs"
+	"go.saser.se/tasks/backend"
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Config configures a Server. Backend is required; every other field is
+// optional.
+type Config struct {
+	// Backend implements the tasks service. Stop calls its Close method.
+	Backend backend.Server
+	// TransportCreds are the transport credentials the gRPC server is
+	// created with. If nil, insecure.NewCredentials() is used.
+	TransportCreds credentials.TransportCredentials
+	// UnaryInterceptors and StreamInterceptors are chained, in order, in
+	// front of Backend's handlers. errdefs.UnaryServerInterceptor is always
+	// chained in last, after these, so individual RPC implementations don't
+	// each have to map their own errors to a gRPC status.
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// ShutdownTimeout bounds how long Stop waits for in-flight RPCs to
+	// finish gracefully before forcing the server to stop. The zero value
+	// means wait forever.
+	ShutdownTimeout time.Duration
+}
+
+// Server serves the tasks gRPC service (and the standard grpc.health.v1
+// service) with an explicit Start/Stop lifecycle, instead of the serving
+// goroutine and the graceful-stop race being inlined into a main function.
+type Server struct {
+	cfg    Config
+	grpc   *grpc.Server
+	health *health.Server
+
+	lis  net.Listener
+	errc chan error
+}
+
+// New constructs a Server. It does not bind a listener or start serving;
+// call Start for that.
+func New(cfg Config) (*Server, error) {
+	if cfg.Backend == nil {
+		return nil, errors.New("grpcserver: Config.Backend must not be nil")
+	}
+	transportCreds := cfg.TransportCreds
+	if transportCreds == nil {
+		transportCreds = insecure.NewCredentials()
+	}
+
+	unary := append(append([]grpc.UnaryServerInterceptor{}, cfg.UnaryInterceptors...), errdefs.UnaryServerInterceptor())
+
+	srv := grpc.NewServer(
+		grpc.Creds(transportCreds),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(cfg.StreamInterceptors...),
+	)
+	pb.RegisterTasksServer(srv, cfg.Backend)
+
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	return &Server{
+		cfg:    cfg,
+		grpc:   srv,
+		health: healthSrv,
+	}, nil
+}
+
+// Listen opens a listener on unixSocket if it's non-empty, removing any
+// stale socket file left behind by a previous run first, and otherwise on
+// network/address.
+func Listen(network, address, unixSocket string) (net.Listener, error) {
+	if unixSocket == "" {
+		return net.Listen(network, address)
+	}
+	if err := os.RemoveAll(unixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale unix socket %q: %w", unixSocket, err)
+	}
+	return net.Listen("unix", unixSocket)
+}
+
+// Start binds a listener on network/address (or unixSocket, if non-empty,
+// per Listen) and returns once that succeeds or fails: it does not wait for
+// the server to stop serving. Once the listener is bound, Start serves on
+// it in a background goroutine and marks the health service SERVING.
+func (s *Server) Start(network, address, unixSocket string) error {
+	lis, err := Listen(network, address, unixSocket)
+	if err != nil {
+		return fmt.Errorf("grpcserver: start: %w", err)
+	}
+	s.lis = lis
+	s.errc = make(chan error, 1)
+	go func() {
+		s.errc <- s.grpc.Serve(lis)
+	}()
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	return nil
+}
+
+// Addr returns the address Start bound its listener on. It panics if called
+// before a successful Start.
+func (s *Server) Addr() net.Addr {
+	if s.lis == nil {
+		panic("grpcserver: Addr called before a successful Start")
+	}
+	return s.lis.Addr()
+}
+
+// Stop marks the health service NOT_SERVING, then races a graceful stop
+// (which waits for in-flight RPCs to finish) against cfg.ShutdownTimeout,
+// falling back to an immediate stop if the timeout is reached first.
+// Finally it closes Backend, passing it ctx. Stop is a no-op if called
+// before a successful Start.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.lis == nil {
+		return nil
+	}
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	if s.cfg.ShutdownTimeout > 0 {
+		select {
+		case <-stopped:
+		case <-time.After(s.cfg.ShutdownTimeout):
+			s.grpc.Stop()
+			<-stopped
+		}
+	} else {
+		<-stopped
+	}
+
+	if err := <-s.errc; err != nil {
+		return fmt.Errorf("grpcserver: serve: %w", err)
+	}
+	return s.cfg.Backend.Close(ctx)
+}
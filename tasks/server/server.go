@@ -1,20 +1,38 @@
 // Command server serves gRPC requests for the `tasks` service. It is configured
 // to use static HTTP Basic authentication. This command is suitable to put into
 // a container image intended for Google Cloud Run.
+//
+// Besides the gRPC listener, it can also run an admin HTTP listener exposing
+// Prometheus metrics and /healthz and /readyz endpoints. The two listeners
+// (and the process's signal handling) are wired together with an
+// oklog/run.Group, so that any one of them failing or being told to stop
+// cleanly tears down the others instead of leaving the process half-up.
 package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
-	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/oklog/run"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.saser.se/accesslog"
 	"go.saser.se/auth/n/basic"
-	"go.saser.se/postgres"
+	"go.saser.se/grpcmetrics"
+	"go.saser.se/grpcrecovery"
+	"go.saser.se/tasks/backend"
+	"go.saser.se/tasks/grpcserver"
 	"go.saser.se/tasks/service"
 	pb "go.saser.se/tasks/tasks_go_proto"
 	"google.golang.org/grpc"
@@ -32,17 +50,37 @@ func init() {
 
 var (
 	portFlag           = flag.Int("port", -1, "Port to serve gRPC requests on. If negative, use the PORT environment variable instead. If zero, use whatever the operating system gives us.")
-	postgresConnString = flag.String("postgres_conn_string", "", "Connection string to backing Postgres database.")
+	grpcUnixSocket     = flag.String("grpc_unix_socket", "", "If non-empty, serve gRPC requests on this UNIX socket instead of -port.")
+	backendFlag        = flag.String("backend", backend.Postgres, fmt.Sprintf("Storage backend for the tasks service: %q, %q, %q, or %q.", backend.Postgres, backend.Fake, backend.SQLite, backend.InMemoryPersisted))
+	postgresConnString = flag.String("postgres_conn_string", "", "Connection string to backing Postgres database. Equivalent to -dsn; kept for backwards compatibility.")
+	dsn                = flag.String("dsn", "", "Data source name/connection string for -backend, if it needs one. Falls back to -postgres_conn_string for the postgres backend.")
+	migrateOnStartup   = flag.Bool("migrate_on_startup", false, "Whether to apply pending schema migrations before serving. Not yet supported by any backend.")
 	username           = flag.String("username", "", "Username to be used for basic authentication.")
 	password           = flag.String("password", "", "Password to be used for basic authentication.")
 	certFile           = flag.String("cert_file", "", "Path to TLS certificate. If empty then no transport security will be used. If this flag is set then -key_file must also be set.")
 	keyFile            = flag.String("key_file", "", "Path to TLS certificate private key. If empty then no transport security will be used. If this flag is set then -cert_file must also be set.")
+	clientCAFile       = flag.String("client_ca_file", "", "Path to a PEM file of CA certificates trusted to sign client certificates. If set, enables mTLS: clients must present a certificate signed by one of these CAs. Requires -cert_file and -key_file to also be set.")
+	labelTemplateFile  = flag.String("label_template_file", "", "Path to a label template file to apply on startup via service.EnsureLabels. If empty, no labels are bootstrapped.")
+	shutdownTimeout    = flag.Duration("shutdown_timeout", 30*time.Second, "How long to wait for in-flight RPCs to finish during a graceful stop before forcibly closing all connections.")
+	otlpEndpoint       = flag.String("otlp_endpoint", "", "OTLP endpoint to export gRPC request traces to. Not yet supported.")
+
+	adminEnabled    = flag.Bool("admin_enabled", true, "Whether to serve an admin HTTP server exposing /metrics, /healthz, and /readyz.")
+	adminAddr       = flag.String("admin_addr", ":9090", "Address to serve the admin HTTP server on.")
+	adminUnixSocket = flag.String("admin_unix_socket", "", "If non-empty, serve the admin HTTP server on this UNIX socket instead of -admin_addr.")
 )
 
+// labelEnsurer is implemented by backends that support bootstrapping a set of
+// predeclared labels via -label_template_file. Not every backend.Server
+// necessarily does (e.g. fake does not), so it's checked with a type
+// assertion rather than being part of backend.Server itself.
+type labelEnsurer interface {
+	EnsureLabels(ctx context.Context, labels []*pb.Label) error
+}
+
 func errmain() error {
 	flag.Parse()
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	port := *portFlag
@@ -57,10 +95,18 @@ func errmain() error {
 	}
 	klog.Infof("Will listen on port %d.", port)
 
-	if *postgresConnString == "" {
-		return errors.New("-postgres_conn_string is empty")
+	resolvedDSN := *dsn
+	if resolvedDSN == "" {
+		resolvedDSN = *postgresConnString
+	}
+
+	if *migrateOnStartup {
+		return errors.New("-migrate_on_startup is set, but no backend currently supports migrating on startup")
+	}
+
+	if *otlpEndpoint != "" {
+		return errors.New("-otlp_endpoint is set, but OpenTelemetry tracing export is not yet supported")
 	}
-	klog.Infof("Will connect to Postgres with connection string: %q", *postgresConnString)
 
 	if *username == "" || *password == "" {
 		return fmt.Errorf("-username=%q and -password=%q; both must be non-empty", *username, *password)
@@ -71,70 +117,211 @@ func errmain() error {
 	if hasCert != hasKey {
 		return fmt.Errorf("-cert_file=%q and -key_file=%q; cannot set only one of them", *certFile, *keyFile)
 	}
+	if *clientCAFile != "" && !hasCert {
+		return errors.New("-client_ca_file is set, but -cert_file and -key_file are not; mTLS requires a server certificate too")
+	}
 	var transportCreds credentials.TransportCredentials
 	if !hasCert {
 		klog.Infof("No certificate was given in -cert_file and -key_file; will serve WITHOUT transport security.")
 		transportCreds = insecure.NewCredentials()
-	} else {
+	} else if *clientCAFile == "" {
 		creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
 		if err != nil {
 			return fmt.Errorf("-cert_file=%q and -key_file=%q is invalid: %w", *certFile, *keyFile, err)
 		}
 		klog.Infof("Will serve WITH transport security loaded from -cert_file=%q and -key_file=%q", *certFile, *keyFile)
 		transportCreds = creds
+	} else {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return fmt.Errorf("-cert_file=%q and -key_file=%q is invalid: %w", *certFile, *keyFile, err)
+		}
+		pem, err := os.ReadFile(*clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read -client_ca_file=%q: %w", *clientCAFile, err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("-client_ca_file=%q contains no usable PEM-encoded certificates", *clientCAFile)
+		}
+		transportCreds = credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		})
+		klog.Infof("Will serve WITH mutual TLS: server certificate from -cert_file=%q and -key_file=%q, client CAs from -client_ca_file=%q", *certFile, *keyFile, *clientCAFile)
 	}
 
 	listenAddr := ":" + strconv.Itoa(port)
-	lis, err := net.Listen("tcp", listenAddr)
-	if err != nil {
-		return fmt.Errorf("failed to create listener on address %q: %w", listenAddr, err)
-	}
-	addr := lis.Addr().String()
-	defer func() {
-		// If we successfully serve and subsequently stop the gRPC server on
-		// this listener, the listener will already have been closed. So we only
-		// log the error if it is something else.
-		if err := lis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
-			klog.Errorf("Failed to close listener on address %q: %v", addr, err)
-		}
-	}()
-	klog.Infof("Created listener on address %q.", addr)
 
-	pool, err := postgres.Open(ctx, *postgresConnString)
+	svc, err := backend.New(ctx, backend.Config{Name: *backendFlag, DSN: resolvedDSN})
 	if err != nil {
-		return fmt.Errorf("failed to connect to Postgres: %w", err)
+		return fmt.Errorf("failed to construct -backend=%q: %w", *backendFlag, err)
 	}
-	defer pool.Close()
-	klog.Infof("Created Postgres connection pool with connection string: %q", *postgresConnString)
+	klog.Infof("Constructed %q backend.", *backendFlag)
 
 	interceptor, err := basic.Interceptor(*username, *password)
 	if err != nil {
 		return fmt.Errorf("failed to create basic authentication interceptor: %w", err)
 	}
 
-	srv := grpc.NewServer(
-		grpc.Creds(transportCreds),
-		grpc.UnaryInterceptor(interceptor),
-	)
-	pb.RegisterTasksServer(srv, service.New(pool))
+	if *labelTemplateFile != "" {
+		ensurer, ok := svc.(labelEnsurer)
+		if !ok {
+			return fmt.Errorf("-label_template_file is set, but -backend=%q does not support label templates", *backendFlag)
+		}
+		klog.Infof("Applying label template from -label_template_file=%q...", *labelTemplateFile)
+		f, err := os.Open(*labelTemplateFile)
+		if err != nil {
+			return fmt.Errorf("failed to open -label_template_file=%q: %w", *labelTemplateFile, err)
+		}
+		labels, err := service.ParseLabelTemplate(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse -label_template_file=%q: %w", *labelTemplateFile, err)
+		}
+		if err := ensurer.EnsureLabels(ctx, labels); err != nil {
+			return fmt.Errorf("failed to apply -label_template_file=%q: %w", *labelTemplateFile, err)
+		}
+		klog.Infof("Applied %d label(s) from -label_template_file=%q.", len(labels), *labelTemplateFile)
+	}
 
-	errc := make(chan error, 1)
-	go func() {
+	gsrv, err := grpcserver.New(grpcserver.Config{
+		Backend:        svc,
+		TransportCreds: transportCreds,
+		// Order matters: grpcrecovery goes first so it can recover a panic
+		// raised by any interceptor after it, and accesslog and grpcmetrics
+		// run around the basic-auth interceptor so their duration/status
+		// reflect the real outcome, including auth failures.
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			grpcrecovery.UnaryServerInterceptor(),
+			accesslog.UnaryServerInterceptor(),
+			grpcmetrics.UnaryServerInterceptor(),
+			interceptor,
+		},
+		StreamInterceptors: []grpc.StreamServerInterceptor{grpcrecovery.StreamServerInterceptor()},
+		ShutdownTimeout:    *shutdownTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to construct gRPC server: %w", err)
+	}
+
+	// ready tracks the same SERVING/NOT_SERVING state as gsrv's internal
+	// grpc.health.v1 service, for the admin server's /readyz endpoint.
+	var ready atomic.Bool
+
+	var g run.Group
+
+	// Actor: OS signal handling. Its execute blocks until ctx (derived from
+	// os.Interrupt/SIGTERM) is cancelled; its interrupt cancels ctx itself,
+	// so that if some other actor fails first, this one unblocks too instead
+	// of leaving the process waiting on a signal that may never come.
+	g.Add(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(error) {
+		stop()
+	})
+
+	// Actor: the gRPC server. gsrv.Start binds the listener synchronously, so
+	// by the time execute reaches <-ctx.Done() the server is already known
+	// to be up; a caller with a handle on gsrv (e.g. a test) can call
+	// gsrv.Addr() right after Start returns without waiting for that.
+	g.Add(func() error {
+		if err := gsrv.Start("tcp", listenAddr, *grpcUnixSocket); err != nil {
+			return fmt.Errorf("failed to start gRPC server: %w", err)
+		}
+		addr := gsrv.Addr().String()
 		klog.Infof("Serving gRPC server on %q...", addr)
-		errc <- srv.Serve(lis)
-	}()
+		ready.Store(true)
+		if sent, err := daemon.SdNotify(false, "READY=1\nSTATUS=serving on "+addr); err != nil {
+			klog.Errorf("Failed to send systemd readiness notification: %v", err)
+		} else if sent {
+			klog.Info("Sent systemd readiness notification.")
+			if interval, err := daemon.SdWatchdogEnabled(false); err != nil {
+				klog.Errorf("Failed to check systemd watchdog status: %v", err)
+			} else if interval > 0 {
+				go watchdog(ctx, interval/2)
+			}
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(error) {
+		klog.Info("Stopping gRPC server...")
+		ready.Store(false)
+		if _, err := daemon.SdNotify(false, "STOPPING=1"); err != nil {
+			klog.Errorf("Failed to send systemd stopping notification: %v", err)
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		if err := gsrv.Stop(stopCtx); err != nil {
+			klog.Errorf("Failed to stop gRPC server: %v", err)
+		}
+	})
+
+	// Actor: the admin HTTP server (Prometheus metrics, /healthz, /readyz).
+	// There is deliberately no grpc-gateway JSON/HTTP actor yet: that needs
+	// *.pb.gw.go stubs generated from tasks.proto by protoc-gen-grpc-gateway,
+	// which this tree doesn't have checked in.
+	if *adminEnabled {
+		adminLis, err := grpcserver.Listen("tcp", *adminAddr, *adminUnixSocket)
+		if err != nil {
+			return fmt.Errorf("failed to create admin listener: %w", err)
+		}
+		adminAddrStr := adminLis.Addr().String()
+		klog.Infof("Created admin listener on address %q.", adminAddrStr)
 
-	klog.Info("Blocking on context cancellation...")
-	<-ctx.Done()
-	klog.Info("Context cancelled; gracefully stopping gRPC server...")
-	srv.GracefulStop()
-	klog.Info("Stopped gRPC server.")
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			// Liveness: as long as the process can answer this at all, it's
+			// alive. This intentionally doesn't depend on ready.
+			w.WriteHeader(http.StatusOK)
+		})
+		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+			if !ready.Load() {
+				http.Error(w, "not ready", http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		adminSrv := &http.Server{Handler: mux}
 
-	if err := <-errc; err != nil {
-		return fmt.Errorf("failed to serve gRPC server: %w", err)
+		g.Add(func() error {
+			klog.Infof("Serving admin HTTP server on %q...", adminAddrStr)
+			if err := adminSrv.Serve(adminLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}, func(error) {
+			klog.Info("Stopping admin HTTP server...")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+			defer cancel()
+			if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+				klog.Errorf("Failed to shut down admin HTTP server: %v", err)
+			}
+		})
 	}
 
-	return nil
+	return g.Run()
+}
+
+// watchdog periodically pings systemd's watchdog at the given interval until
+// ctx is cancelled, so long as NOTIFY_SOCKET is set and the watchdog is
+// enabled.
+func watchdog(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := daemon.SdNotify(false, "WATCHDOG=1"); err != nil {
+				klog.Errorf("Failed to send systemd watchdog notification: %v", err)
+			}
+		}
+	}
 }
 
 func main() {
@@ -0,0 +1,114 @@
+// Package filter implements a small AIP-160 style filter expression
+// language, used by List RPCs (e.g. ListTasks, ListProjects, ListLabels) to
+// let callers describe ad-hoc predicates over a resource's fields.
+//
+// The grammar supports:
+//
+//	expr       := or
+//	or         := and ("OR" and)*
+//	and        := unary ("AND" unary)*
+//	unary      := "NOT" unary | "(" or ")" | comparison
+//	comparison := field op value
+//	op         := "=" | "!=" | "<" | "<=" | ">" | ">=" | ":"
+//	value      := string | "NULL"
+//
+// ":" is the "has" operator: for string fields it means substring match, and
+// for repeated fields it means set membership (e.g. `labels:"labels/7"`).
+//
+// A Schema describes which fields are allowed and what operators make sense
+// for each one; Parse rejects anything else with an error suitable for
+// wrapping in a codes.InvalidArgument status.
+package filter
+
+import (
+	"fmt"
+)
+
+// Op is a comparison operator appearing in a filter expression.
+type Op int
+
+const (
+	OpEq Op = iota
+	OpNeq
+	OpLt
+	OpLte
+	OpGt
+	OpGte
+	OpHas // ":" - substring match or set membership.
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpEq:
+		return "="
+	case OpNeq:
+		return "!="
+	case OpLt:
+		return "<"
+	case OpLte:
+		return "<="
+	case OpGt:
+		return ">"
+	case OpGte:
+		return ">="
+	case OpHas:
+		return ":"
+	default:
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+}
+
+// Expr is a node in a parsed filter expression's AST.
+type Expr interface {
+	isExpr()
+}
+
+// And is the logical conjunction of Exprs.
+type And struct {
+	Exprs []Expr
+}
+
+// Or is the logical disjunction of Exprs.
+type Or struct {
+	Exprs []Expr
+}
+
+// Not negates Expr.
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares Field against Value using Op.
+type Comparison struct {
+	Field string
+	Op    Op
+	// Value is either a string or the untyped nil, representing the NULL
+	// literal.
+	Value any
+}
+
+func (*And) isExpr()        {}
+func (*Or) isExpr()         {}
+func (*Not) isExpr()        {}
+func (*Comparison) isExpr() {}
+
+// Parse parses s as a filter expression. An empty string parses to a nil
+// Expr, meaning "no filter".
+func Parse(s string) (Expr, error) {
+	if s == "" {
+		return nil, nil
+	}
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	p := &parser{toks: toks}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("filter: unexpected token %q", p.peek().text)
+	}
+	return expr, nil
+}
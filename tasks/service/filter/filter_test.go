@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   string
+		want Expr
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: nil,
+		},
+		{
+			name: "simple equality",
+			in:   `title="buy milk"`,
+			want: &Comparison{Field: "title", Op: OpEq, Value: "buy milk"},
+		},
+		{
+			name: "null literal",
+			in:   "complete_time=NULL",
+			want: &Comparison{Field: "complete_time", Op: OpEq, Value: nil},
+		},
+		{
+			name: "has operator",
+			in:   `labels:"labels/7"`,
+			want: &Comparison{Field: "labels", Op: OpHas, Value: "labels/7"},
+		},
+		{
+			name: "not",
+			in:   `NOT description:"draft"`,
+			want: &Not{Expr: &Comparison{Field: "description", Op: OpHas, Value: "draft"}},
+		},
+		{
+			name: "and/or with parens",
+			in:   `title:"buy milk" OR (parent="tasks/42" AND complete_time=NULL)`,
+			want: &Or{Exprs: []Expr{
+				&Comparison{Field: "title", Op: OpHas, Value: "buy milk"},
+				&And{Exprs: []Expr{
+					&Comparison{Field: "parent", Op: OpEq, Value: "tasks/42"},
+					&Comparison{Field: "complete_time", Op: OpEq, Value: nil},
+				}},
+			}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) err = %v; want nil", tt.in, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Parse(%q) (-want +got)\n%s", tt.in, diff)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{
+		`title=`,
+		`title="unterminated`,
+		`(title="a"`,
+		`title="a" AND`,
+		`title="a" "b"`,
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) err = nil; want non-nil", in)
+		}
+	}
+}
+
+func TestToSQL(t *testing.T) {
+	schema := Schema{
+		"title": {
+			Column: "title",
+			Ops:    map[Op]bool{OpEq: true, OpHas: true},
+		},
+		"complete_time": {
+			Column: "complete_time",
+			Ops:    map[Op]bool{OpEq: true, OpNeq: true},
+		},
+	}
+	expr, err := Parse(`title:"milk" AND complete_time=NULL`)
+	if err != nil {
+		t.Fatalf("Parse() err = %v", err)
+	}
+	sqlizer, err := ToSQL(expr, schema)
+	if err != nil {
+		t.Fatalf("ToSQL() err = %v", err)
+	}
+	sql, args, err := sqlizer.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() err = %v", err)
+	}
+	if sql == "" {
+		t.Errorf("ToSql() sql is empty")
+	}
+	_ = args
+
+	if _, err := ToSQL(mustParse(t, `unknown="x"`), schema); err == nil {
+		t.Errorf("ToSQL() with unknown field err = nil; want non-nil")
+	}
+	if _, err := ToSQL(mustParse(t, `title>"x"`), schema); err == nil {
+		t.Errorf("ToSQL() with unsupported operator err = nil; want non-nil")
+	}
+}
+
+func mustParse(t *testing.T, s string) Expr {
+	t.Helper()
+	expr, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) err = %v", s, err)
+	}
+	return expr
+}
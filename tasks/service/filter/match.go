@@ -0,0 +1,192 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Fields holds the in-memory field values of a single resource instance, for
+// use with Match. Values should use the same Go types that schema's Convert
+// functions produce (or plain strings, for fields without a Convert), so
+// that comparisons behave the same way as ToSQL's.
+type Fields map[string]any
+
+// Match reports whether fields satisfies expr, using schema to validate
+// fields/operators and convert filter literals, the same way ToSQL does. A
+// nil expr matches everything. It's meant for backends (such as an in-memory
+// fake) that can't delegate filtering to SQL.
+func Match(expr Expr, schema Schema, fields Fields) (bool, error) {
+	if expr == nil {
+		return true, nil
+	}
+	return match(expr, schema, fields)
+}
+
+func match(expr Expr, schema Schema, fields Fields) (bool, error) {
+	switch e := expr.(type) {
+	case *And:
+		for _, sub := range e.Exprs {
+			ok, err := match(sub, schema, fields)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+		return true, nil
+	case *Or:
+		for _, sub := range e.Exprs {
+			ok, err := match(sub, schema, fields)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *Not:
+		ok, err := match(e.Expr, schema, fields)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	case *Comparison:
+		return matchComparison(e, schema, fields)
+	default:
+		return false, fmt.Errorf("filter: unknown expression type %T", expr)
+	}
+}
+
+func matchComparison(c *Comparison, schema Schema, fields Fields) (bool, error) {
+	fs, ok := schema[c.Field]
+	if !ok {
+		return false, status.Errorf(codes.InvalidArgument, "The field %q cannot be used in a filter expression.", c.Field)
+	}
+	if !fs.Ops[c.Op] {
+		return false, status.Errorf(codes.InvalidArgument, "The operator %q cannot be used with the field %q.", c.Op, c.Field)
+	}
+	actual := fields[c.Field]
+
+	if c.Op == OpHas && fs.Repeated {
+		value, err := convert(fs, c.Value)
+		if err != nil {
+			return false, err
+		}
+		elems, _ := actual.([]any)
+		for _, e := range elems {
+			if e == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if c.Value == nil {
+		switch c.Op {
+		case OpEq:
+			return actual == nil, nil
+		case OpNeq:
+			return actual != nil, nil
+		default:
+			return false, status.Errorf(codes.InvalidArgument, "The NULL literal can only be used with = or != (field %q).", c.Field)
+		}
+	}
+
+	value, err := convert(fs, c.Value)
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "The value for field %q is invalid: %v", c.Field, err)
+	}
+
+	if at, ok := actual.(time.Time); ok {
+		vt, ok := value.(time.Time)
+		if !ok {
+			return false, fmt.Errorf("filter: cannot compare time.Time to %T", value)
+		}
+		switch c.Op {
+		case OpEq:
+			return at.Equal(vt), nil
+		case OpNeq:
+			return !at.Equal(vt), nil
+		}
+	}
+
+	switch c.Op {
+	case OpEq:
+		return actual == value, nil
+	case OpNeq:
+		return actual != value, nil
+	case OpLt, OpLte, OpGt, OpGte:
+		return compareOrdered(c.Op, actual, value)
+	case OpHas:
+		as, ok := actual.(string)
+		if !ok {
+			return false, fmt.Errorf("filter: field %q is not a string; cannot use %q", c.Field, OpHas)
+		}
+		return strings.Contains(as, fmt.Sprint(value)), nil
+	default:
+		return false, fmt.Errorf("filter: unsupported operator %v", c.Op)
+	}
+}
+
+// compareOrdered evaluates op (one of OpLt, OpLte, OpGt, OpGte) between
+// actual and value, which must both be strings, time.Time, or any type
+// satisfying ordered constraints via Go's built-in < and > on the underlying
+// kind.
+func compareOrdered(op Op, actual, value any) (bool, error) {
+	switch a := actual.(type) {
+	case time.Time:
+		v, ok := value.(time.Time)
+		if !ok {
+			return false, fmt.Errorf("filter: cannot compare time.Time to %T", value)
+		}
+		switch op {
+		case OpLt:
+			return a.Before(v), nil
+		case OpLte:
+			return a.Before(v) || a.Equal(v), nil
+		case OpGt:
+			return a.After(v), nil
+		case OpGte:
+			return a.After(v) || a.Equal(v), nil
+		}
+	case string:
+		v, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("filter: cannot compare string to %T", value)
+		}
+		switch op {
+		case OpLt:
+			return a < v, nil
+		case OpLte:
+			return a <= v, nil
+		case OpGt:
+			return a > v, nil
+		case OpGte:
+			return a >= v, nil
+		}
+	case int64:
+		v, ok := value.(int64)
+		if !ok {
+			return false, fmt.Errorf("filter: cannot compare int64 to %T", value)
+		}
+		switch op {
+		case OpLt:
+			return a < v, nil
+		case OpLte:
+			return a <= v, nil
+		case OpGt:
+			return a > v, nil
+		case OpGte:
+			return a >= v, nil
+		}
+	default:
+		return false, fmt.Errorf("filter: values of type %T are not orderable", actual)
+	}
+	return false, fmt.Errorf("filter: unsupported operator %v", op)
+}
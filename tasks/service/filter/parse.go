@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *parser) peek() token {
+	if p.atEnd() {
+		return token{}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseOr := parseAnd ("OR" parseAnd)*
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for !p.atEnd() && strings.EqualFold(p.peek().text, "OR") {
+		p.next()
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &Or{Exprs: exprs}, nil
+}
+
+// parseAnd := parseUnary ("AND" parseUnary)*
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{first}
+	for !p.atEnd() && strings.EqualFold(p.peek().text, "AND") {
+		p.next()
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &And{Exprs: exprs}, nil
+}
+
+// parseUnary := "NOT" parseUnary | "(" parseOr ")" | comparison
+func (p *parser) parseUnary() (Expr, error) {
+	if p.atEnd() {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, "NOT") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: inner}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("expected closing parenthesis")
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	field := p.next()
+	if field.kind != tokIdent {
+		return nil, fmt.Errorf("expected field name, got %q", field.text)
+	}
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, fmt.Errorf("expected operator after field %q, got %q", field.text, opTok.text)
+	}
+	op, err := parseOp(opTok.text)
+	if err != nil {
+		return nil, err
+	}
+	valTok := p.next()
+	var value any
+	switch {
+	case valTok.kind == tokIdent && strings.EqualFold(valTok.text, "NULL"):
+		value = nil
+	case valTok.kind == tokIdent || valTok.kind == tokString:
+		value = valTok.text
+	default:
+		return nil, fmt.Errorf("expected value after operator %q, got %q", opTok.text, valTok.text)
+	}
+	return &Comparison{Field: field.text, Op: op, Value: value}, nil
+}
+
+func parseOp(s string) (Op, error) {
+	switch s {
+	case "=":
+		return OpEq, nil
+	case "!=":
+		return OpNeq, nil
+	case "<":
+		return OpLt, nil
+	case "<=":
+		return OpLte, nil
+	case ">":
+		return OpGt, nil
+	case ">=":
+		return OpGte, nil
+	case ":":
+		return OpHas, nil
+	default:
+		return 0, fmt.Errorf("unknown operator %q", s)
+	}
+}
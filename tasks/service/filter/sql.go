@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FieldSchema describes how a single filterable field maps onto a SQL
+// column, and which operators are valid for it.
+type FieldSchema struct {
+	// Column is the SQL column name (or expression) to compare against.
+	Column string
+	// Ops is the set of operators allowed for this field. Comparisons using
+	// any other operator are rejected.
+	Ops map[Op]bool
+	// Convert, if non-nil, converts the string value parsed out of the
+	// filter into the Go value that should be bound in the SQL query (e.g.
+	// parsing a timestamp or resolving a "tasks/{id}" name to an int64 ID).
+	// If nil, the raw string is used as-is.
+	Convert func(value string) (any, error)
+	// Repeated marks fields backed by a one-to-many relationship (such as
+	// labels), for which OpHas means "some associated row equals value"
+	// rather than "column contains substring". HasPredicate must be set for
+	// such fields.
+	Repeated bool
+	// HasPredicate builds the Sqlizer for an OpHas comparison against a
+	// Repeated field, typically a correlated subquery.
+	HasPredicate func(value any) squirrel.Sqlizer
+}
+
+// Schema maps field names appearing in a filter expression to FieldSchemas.
+type Schema map[string]FieldSchema
+
+// ToSQL translates expr (as produced by Parse) into a squirrel.Sqlizer using
+// schema to validate fields/operators and convert values. A nil expr
+// translates to nil, meaning "no predicate".
+func ToSQL(expr Expr, schema Schema) (squirrel.Sqlizer, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	return translate(expr, schema)
+}
+
+func translate(expr Expr, schema Schema) (squirrel.Sqlizer, error) {
+	switch e := expr.(type) {
+	case *And:
+		var parts squirrel.And
+		for _, sub := range e.Exprs {
+			s, err := translate(sub, schema)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		}
+		return parts, nil
+	case *Or:
+		var parts squirrel.Or
+		for _, sub := range e.Exprs {
+			s, err := translate(sub, schema)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		}
+		return parts, nil
+	case *Not:
+		inner, err := translate(e.Expr, schema)
+		if err != nil {
+			return nil, err
+		}
+		sql, args, err := inner.ToSql()
+		if err != nil {
+			return nil, err
+		}
+		return squirrel.Expr("NOT ("+sql+")", args...), nil
+	case *Comparison:
+		return translateComparison(e, schema)
+	default:
+		return nil, fmt.Errorf("filter: unknown expression type %T", expr)
+	}
+}
+
+func translateComparison(c *Comparison, schema Schema) (squirrel.Sqlizer, error) {
+	fs, ok := schema[c.Field]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "The field %q cannot be used in a filter expression.", c.Field)
+	}
+	if !fs.Ops[c.Op] {
+		return nil, status.Errorf(codes.InvalidArgument, "The operator %q cannot be used with the field %q.", c.Op, c.Field)
+	}
+
+	if c.Op == OpHas && fs.Repeated {
+		if fs.HasPredicate == nil {
+			return nil, fmt.Errorf("filter: field %q is repeated but has no HasPredicate", c.Field)
+		}
+		value, err := convert(fs, c.Value)
+		if err != nil {
+			return nil, err
+		}
+		return fs.HasPredicate(value), nil
+	}
+
+	if c.Value == nil {
+		switch c.Op {
+		case OpEq:
+			return squirrel.Eq{fs.Column: nil}, nil
+		case OpNeq:
+			return squirrel.NotEq{fs.Column: nil}, nil
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "The NULL literal can only be used with = or != (field %q).", c.Field)
+		}
+	}
+
+	value, err := convert(fs, c.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "The value for field %q is invalid: %v", c.Field, err)
+	}
+
+	switch c.Op {
+	case OpEq:
+		return squirrel.Eq{fs.Column: value}, nil
+	case OpNeq:
+		return squirrel.NotEq{fs.Column: value}, nil
+	case OpLt:
+		return squirrel.Lt{fs.Column: value}, nil
+	case OpLte:
+		return squirrel.LtOrEq{fs.Column: value}, nil
+	case OpGt:
+		return squirrel.Gt{fs.Column: value}, nil
+	case OpGte:
+		return squirrel.GtOrEq{fs.Column: value}, nil
+	case OpHas:
+		// Substring match on a scalar (string) column.
+		return squirrel.Like{fs.Column: "%" + fmt.Sprint(value) + "%"}, nil
+	default:
+		return nil, fmt.Errorf("filter: unsupported operator %v", c.Op)
+	}
+}
+
+func convert(fs FieldSchema, value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	if fs.Convert == nil {
+		return s, nil
+	}
+	return fs.Convert(s)
+}
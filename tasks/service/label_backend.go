@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// LabelBackend is the subset of the Tasks service's label RPCs needed to
+// drive label-focused tests. *Service implements it directly, by talking to
+// a real Postgres database; the labelfake package provides an in-memory
+// implementation for tests that want deterministic ordering and injectable
+// errors without standing up a database.
+type LabelBackend interface {
+	CreateLabel(ctx context.Context, req *pb.CreateLabelRequest) (*pb.Label, error)
+	GetLabel(ctx context.Context, req *pb.GetLabelRequest) (*pb.Label, error)
+	UpdateLabel(ctx context.Context, req *pb.UpdateLabelRequest) (*pb.Label, error)
+	DeleteLabel(ctx context.Context, req *pb.DeleteLabelRequest) (*emptypb.Empty, error)
+	ListLabels(ctx context.Context, req *pb.ListLabelsRequest) (*pb.ListLabelsResponse, error)
+}
+
+var _ LabelBackend = (*Service)(nil)
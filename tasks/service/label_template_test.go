@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestParseLabelTemplate(t *testing.T) {
+	const template = `
+d73a4a bug ; Something isn't working
+a2eeef enhancement
+#0075ca documentation ; Improvements or additions to documentation
+`
+	got, err := ParseLabelTemplate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate() err = %v; want nil", err)
+	}
+	want := []*pb.Label{
+		{Label: "bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Label: "enhancement", Color: "a2eeef"},
+		{Label: "documentation", Color: "0075ca", Description: "Improvements or additions to documentation"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ParseLabelTemplate() (-want +got)\n%s", diff)
+	}
+}
+
+func TestParseLabelTemplate_Error(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		template string
+		wantLine int
+	}{
+		{
+			name:     "MalformedLine",
+			template: "this line has no color",
+			wantLine: 1,
+		},
+		{
+			name:     "BadColor",
+			template: "not-a-color bug",
+			wantLine: 1,
+		},
+		{
+			name: "DuplicateName",
+			template: `d73a4a bug ; first
+a2eeef bug ; second`,
+			wantLine: 2,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLabelTemplate(strings.NewReader(tt.template))
+			var tmplErr *LabelTemplateError
+			if !errors.As(err, &tmplErr) {
+				t.Fatalf("ParseLabelTemplate(%q) err = %v (%T); want *LabelTemplateError", tt.template, err, err)
+			}
+			if tmplErr.Line != tt.wantLine {
+				t.Errorf("ParseLabelTemplate(%q) err.Line = %d; want %d", tt.template, tmplErr.Line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestParseLabelTemplate_ReloadIsNoop(t *testing.T) {
+	// ParseLabelTemplate itself is pure, so parsing the same template twice
+	// must produce identical results; the idempotency of applying them lives
+	// in EnsureLabels, which is covered by the testsuite since it needs a
+	// database.
+	const template = "d73a4a bug ; Something isn't working"
+	first, err := ParseLabelTemplate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate() err = %v; want nil", err)
+	}
+	second, err := ParseLabelTemplate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("ParseLabelTemplate() err = %v; want nil", err)
+	}
+	if diff := cmp.Diff(first, second, protocmp.Transform()); diff != "" {
+		t.Errorf("parsing the same template twice gave different results (-first +second)\n%s", diff)
+	}
+}
@@ -0,0 +1,120 @@
+// Package labelcmd parses free text, such as a task comment, into a
+// sequence of add/remove label operations.
+package labelcmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Op identifies whether a Command adds or removes a label.
+type Op int
+
+const (
+	Add Op = iota
+	Remove
+)
+
+func (op Op) String() string {
+	if op == Remove {
+		return "remove"
+	}
+	return "add"
+}
+
+// Command is a single add or remove operation extracted from text by Parse.
+// Label is exactly as written in the text, not normalized, so callers can
+// match it against known labels case-insensitively themselves.
+type Command struct {
+	Op    Op
+	Label string
+}
+
+// andRE matches the word "and" as a label separator, case-insensitively.
+var andRE = regexp.MustCompile(`(?i)\band\b`)
+
+// Parse extracts a deduped sequence of add/remove label commands from text
+// such as:
+//
+//	@bot please add needsfix, help wanted and remove needsinvestigation
+//
+// An optional leading "@mention" and optional "please" are skipped. The
+// verbs "add" and "remove" are case-insensitive, and each applies to every
+// label that follows it until the next verb; labels within a verb's span
+// may be separated by commas, semicolons, or the word "and". Repeated
+// commands for the same (verb, label) pair, compared case-insensitively,
+// are deduped to the first occurrence. Parse returns nil if the text
+// doesn't contain a recognized verb.
+func Parse(text string) []Command {
+	words := strings.Fields(text)
+	i := 0
+	if i < len(words) && strings.HasPrefix(words[i], "@") {
+		i++
+	}
+	if i < len(words) && strings.EqualFold(trimPunct(words[i]), "please") {
+		i++
+	}
+
+	var commands []Command
+	seen := map[Op]map[string]bool{Add: {}, Remove: {}}
+	emit := func(op Op, label string) {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return
+		}
+		key := strings.ToLower(label)
+		if seen[op][key] {
+			return
+		}
+		seen[op][key] = true
+		commands = append(commands, Command{Op: op, Label: label})
+	}
+
+	var curOp Op
+	haveOp := false
+	var buf []string
+	flush := func() {
+		if haveOp {
+			for _, label := range splitLabels(strings.Join(buf, " ")) {
+				emit(curOp, label)
+			}
+		}
+		buf = nil
+	}
+	for ; i < len(words); i++ {
+		switch w := trimPunct(words[i]); {
+		case strings.EqualFold(w, "add"):
+			flush()
+			curOp, haveOp = Add, true
+		case strings.EqualFold(w, "remove"):
+			flush()
+			curOp, haveOp = Remove, true
+		default:
+			buf = append(buf, words[i])
+		}
+	}
+	flush()
+	return commands
+}
+
+// trimPunct trims leading/trailing punctuation from a word so that verbs
+// and "please" are recognized regardless of adjacent punctuation (e.g. a
+// trailing comma).
+func trimPunct(w string) string {
+	return strings.Trim(w, ",;.:!?")
+}
+
+// splitLabels splits a run of text naming one or more labels, separated by
+// commas, semicolons, or the word "and", into the individual label names.
+func splitLabels(s string) []string {
+	s = strings.ReplaceAll(s, ";", ",")
+	s = andRE.ReplaceAllString(s, ",")
+	var labels []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.Trim(part, " \t,;.")
+		if part != "" {
+			labels = append(labels, part)
+		}
+	}
+	return labels
+}
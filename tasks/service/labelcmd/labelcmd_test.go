@@ -0,0 +1,98 @@
+package labelcmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		text string
+		want []Command
+	}{
+		{
+			name: "BasicAddAndRemove",
+			text: "@bot please add needsfix, help wanted and remove needsinvestigation",
+			want: []Command{
+				{Op: Add, Label: "needsfix"},
+				{Op: Add, Label: "help wanted"},
+				{Op: Remove, Label: "needsinvestigation"},
+			},
+		},
+		{
+			name: "NoPlease",
+			text: "@bot add bug",
+			want: []Command{
+				{Op: Add, Label: "bug"},
+			},
+		},
+		{
+			name: "NoMention",
+			text: "please add bug",
+			want: []Command{
+				{Op: Add, Label: "bug"},
+			},
+		},
+		{
+			name: "CommaSeparated",
+			text: "add a, b, c",
+			want: []Command{
+				{Op: Add, Label: "a"},
+				{Op: Add, Label: "b"},
+				{Op: Add, Label: "c"},
+			},
+		},
+		{
+			name: "SemicolonSeparated",
+			text: "add a; b; c",
+			want: []Command{
+				{Op: Add, Label: "a"},
+				{Op: Add, Label: "b"},
+				{Op: Add, Label: "c"},
+			},
+		},
+		{
+			name: "MixedCase",
+			text: "@bot please ADD Bug and REMOVE Wontfix",
+			want: []Command{
+				{Op: Add, Label: "Bug"},
+				{Op: Remove, Label: "Wontfix"},
+			},
+		},
+		{
+			name: "RemoveThenAdd",
+			text: "remove a, b add c",
+			want: []Command{
+				{Op: Remove, Label: "a"},
+				{Op: Remove, Label: "b"},
+				{Op: Add, Label: "c"},
+			},
+		},
+		{
+			name: "DedupesCaseInsensitively",
+			text: "add bug, Bug, BUG",
+			want: []Command{
+				{Op: Add, Label: "bug"},
+			},
+		},
+		{
+			name: "NoRecognizedVerb",
+			text: "@bot hello there",
+			want: nil,
+		},
+		{
+			name: "Empty",
+			text: "",
+			want: nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Parse(%q) (-want +got)\n%s", tt.text, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package labelfake provides an in-memory implementation of
+// service.LabelBackend, for tests that exercise label-handling logic without
+// a database.
+package labelfake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.saser.se/tasks/service"
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+var _ service.LabelBackend = (*Fake)(nil)
+
+// Fake is an in-memory implementation of service.LabelBackend. The zero
+// value is ready to use. It is safe for concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	labels map[int64]*pb.Label
+	nextID int64
+	errors map[string]map[string]error
+}
+
+// InjectError makes the given method (e.g. "GetLabel") return err the next
+// time it's called for name (a label's resource name, or, for CreateLabel,
+// the label string being created). The injected error is consumed after one
+// use, so tests that need it to fire more than once must inject it again.
+func (f *Fake) InjectError(method, name string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.errors == nil {
+		f.errors = make(map[string]map[string]error)
+	}
+	if f.errors[method] == nil {
+		f.errors[method] = make(map[string]error)
+	}
+	f.errors[method][name] = err
+}
+
+// takeError returns and clears an injected error for method and name, if
+// any. Callers must hold f.mu.
+func (f *Fake) takeError(method, name string) error {
+	m := f.errors[method]
+	if m == nil {
+		return nil
+	}
+	err, ok := m[name]
+	if !ok {
+		return nil
+	}
+	delete(m, name)
+	return err
+}
+
+func (f *Fake) CreateLabel(ctx context.Context, req *pb.CreateLabelRequest) (*pb.Label, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	label := req.GetLabel()
+	if label.GetLabel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "The label must have a title.")
+	}
+	if err := f.takeError("CreateLabel", label.GetLabel()); err != nil {
+		return nil, err
+	}
+	for _, existing := range f.labels {
+		if strings.EqualFold(existing.GetLabel(), label.GetLabel()) {
+			return nil, status.Errorf(codes.AlreadyExists, "A label with string %q already exists.", label.GetLabel())
+		}
+	}
+	if f.labels == nil {
+		f.labels = make(map[int64]*pb.Label)
+	}
+	f.nextID++
+	id := f.nextID
+	stored := proto.Clone(label).(*pb.Label)
+	stored.Name = fmt.Sprintf("labels/%d", id)
+	f.labels[id] = stored
+	return proto.Clone(stored).(*pb.Label), nil
+}
+
+func (f *Fake) GetLabel(ctx context.Context, req *pb.GetLabelRequest) (*pb.Label, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError("GetLabel", req.GetName()); err != nil {
+		return nil, err
+	}
+	label, ok := f.find(req.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", req.GetName())
+	}
+	return proto.Clone(label).(*pb.Label), nil
+}
+
+func (f *Fake) UpdateLabel(ctx context.Context, req *pb.UpdateLabelRequest) (*pb.Label, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	patch := req.GetLabel()
+	if err := f.takeError("UpdateLabel", patch.GetName()); err != nil {
+		return nil, err
+	}
+	label, ok := f.find(patch.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", patch.GetName())
+	}
+	mask := req.GetUpdateMask()
+	if mask == nil || len(mask.GetPaths()) == 0 {
+		mask = &fieldmaskpb.FieldMask{Paths: []string{"label", "color", "description"}}
+	}
+	if !mask.IsValid(&pb.Label{}) {
+		return nil, status.Errorf(codes.InvalidArgument, "The update mask (%v) is invalid.", mask.GetPaths())
+	}
+	for _, path := range mask.GetPaths() {
+		switch path {
+		case "label":
+			label.Label = patch.GetLabel()
+		case "color":
+			label.Color = patch.GetColor()
+		case "description":
+			label.Description = patch.GetDescription()
+		}
+	}
+	return proto.Clone(label).(*pb.Label), nil
+}
+
+func (f *Fake) DeleteLabel(ctx context.Context, req *pb.DeleteLabelRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError("DeleteLabel", req.GetName()); err != nil {
+		return nil, err
+	}
+	id, ok := f.findID(req.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "A label with name %q does not exist.", req.GetName())
+	}
+	delete(f.labels, id)
+	return &emptypb.Empty{}, nil
+}
+
+// ListLabels returns every label in ascending ID (i.e. creation) order. It
+// doesn't implement req's filter, order_by, or paging semantics: Fake exists
+// to exercise label-handling logic that only needs a complete,
+// deterministically ordered list, not the production server's full querying
+// behavior.
+func (f *Fake) ListLabels(ctx context.Context, req *pb.ListLabelsRequest) (*pb.ListLabelsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError("ListLabels", req.GetParent()); err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(f.labels))
+	for id := range f.labels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	res := &pb.ListLabelsResponse{}
+	for _, id := range ids {
+		res.Labels = append(res.Labels, proto.Clone(f.labels[id]).(*pb.Label))
+	}
+	return res, nil
+}
+
+func (f *Fake) find(name string) (*pb.Label, bool) {
+	id, ok := f.findID(name)
+	if !ok {
+		return nil, false
+	}
+	return f.labels[id], true
+}
+
+func (f *Fake) findID(name string) (int64, bool) {
+	for id, label := range f.labels {
+		if label.GetName() == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package labelfake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestFake_CreateGetUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	f := &Fake{}
+
+	created, err := f.CreateLabel(ctx, &pb.CreateLabelRequest{Label: &pb.Label{Label: "bug"}})
+	if err != nil {
+		t.Fatalf("CreateLabel() err = %v; want nil", err)
+	}
+	if created.GetName() == "" {
+		t.Fatalf("CreateLabel() returned a label with no name: %v", created)
+	}
+
+	got, err := f.GetLabel(ctx, &pb.GetLabelRequest{Name: created.GetName()})
+	if err != nil {
+		t.Fatalf("GetLabel(%q) err = %v; want nil", created.GetName(), err)
+	}
+	if got.GetLabel() != "bug" {
+		t.Errorf("GetLabel(%q).Label = %q; want %q", created.GetName(), got.GetLabel(), "bug")
+	}
+
+	updated, err := f.UpdateLabel(ctx, &pb.UpdateLabelRequest{
+		Label:      &pb.Label{Name: created.GetName(), Color: "d73a4a"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"color"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateLabel(%q) err = %v; want nil", created.GetName(), err)
+	}
+	if updated.GetColor() != "d73a4a" {
+		t.Errorf("UpdateLabel(%q).Color = %q; want %q", created.GetName(), updated.GetColor(), "d73a4a")
+	}
+	if updated.GetLabel() != "bug" {
+		t.Errorf("UpdateLabel(%q).Label = %q; want unchanged %q", created.GetName(), updated.GetLabel(), "bug")
+	}
+
+	if _, err := f.DeleteLabel(ctx, &pb.DeleteLabelRequest{Name: created.GetName()}); err != nil {
+		t.Fatalf("DeleteLabel(%q) err = %v; want nil", created.GetName(), err)
+	}
+	if _, err := f.GetLabel(ctx, &pb.GetLabelRequest{Name: created.GetName()}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetLabel(%q) after deletion: code = %v; want %v", created.GetName(), status.Code(err), codes.NotFound)
+	}
+}
+
+func TestFake_ListLabels_DeterministicOrder(t *testing.T) {
+	ctx := context.Background()
+	f := &Fake{}
+	var names []string
+	for _, s := range []string{"c", "a", "b"} {
+		label, err := f.CreateLabel(ctx, &pb.CreateLabelRequest{Label: &pb.Label{Label: s}})
+		if err != nil {
+			t.Fatalf("CreateLabel(%q) err = %v; want nil", s, err)
+		}
+		names = append(names, label.GetName())
+	}
+
+	for i := 0; i < 3; i++ {
+		res, err := f.ListLabels(ctx, &pb.ListLabelsRequest{})
+		if err != nil {
+			t.Fatalf("ListLabels() err = %v; want nil", err)
+		}
+		if len(res.GetLabels()) != len(names) {
+			t.Fatalf("ListLabels() returned %d labels; want %d", len(res.GetLabels()), len(names))
+		}
+		for j, label := range res.GetLabels() {
+			if label.GetName() != names[j] {
+				t.Errorf("ListLabels() call %d: Labels[%d].Name = %q; want %q (order must stay creation-order across repeated calls)", i, j, label.GetName(), names[j])
+			}
+		}
+	}
+}
+
+func TestFake_InjectError(t *testing.T) {
+	ctx := context.Background()
+	f := &Fake{}
+	created, err := f.CreateLabel(ctx, &pb.CreateLabelRequest{Label: &pb.Label{Label: "bug"}})
+	if err != nil {
+		t.Fatalf("CreateLabel() err = %v; want nil", err)
+	}
+
+	injected := status.Error(codes.Unavailable, "injected failure")
+	f.InjectError("GetLabel", created.GetName(), injected)
+
+	if _, err := f.GetLabel(ctx, &pb.GetLabelRequest{Name: created.GetName()}); !errors.Is(err, injected) {
+		t.Errorf("GetLabel(%q) err = %v; want %v", created.GetName(), err, injected)
+	}
+
+	// The injected error is consumed after one use; the next call should see
+	// the label as it actually exists.
+	if _, err := f.GetLabel(ctx, &pb.GetLabelRequest{Name: created.GetName()}); err != nil {
+		t.Errorf("GetLabel(%q) (after error consumed) err = %v; want nil", created.GetName(), err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package orderby parses the comma-separated "field [asc|desc]" lists
+// accepted by the order_by field on List RPCs (e.g. ListTasksRequest),
+// following the convention described by AIP-132.
+package orderby
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Term is a single "field [asc|desc]" clause.
+type Term struct {
+	Field string
+	Desc  bool
+}
+
+// Parse splits s on commas into Terms. Each term is "field" or "field asc" or
+// "field desc" (case-insensitive); bare fields default to ascending.
+func Parse(s string) ([]Term, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var terms []Term
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		switch len(fields) {
+		case 1:
+			terms = append(terms, Term{Field: fields[0]})
+		case 2:
+			desc, err := parseDirection(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("order_by: %w", err)
+			}
+			terms = append(terms, Term{Field: fields[0], Desc: desc})
+		default:
+			return nil, fmt.Errorf("order_by: invalid clause %q", strings.TrimSpace(part))
+		}
+	}
+	return terms, nil
+}
+
+func parseDirection(s string) (desc bool, err error) {
+	switch strings.ToLower(s) {
+	case "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid direction %q, must be \"asc\" or \"desc\"", s)
+	}
+}
+
+// ToSQL validates terms' fields against columns (a map from allowed field
+// name to SQL column name) and renders a SQL ORDER BY clause (without the
+// "ORDER BY" prefix). tiebreaker, if non-empty, is appended as a final
+// ascending clause to keep pages deterministic, unless terms already ends
+// with it.
+func ToSQL(terms []Term, columns map[string]string, tiebreaker string) (string, error) {
+	var clauses []string
+	haveTiebreaker := false
+	for _, term := range terms {
+		col, ok := columns[term.Field]
+		if !ok {
+			return "", fmt.Errorf("order_by: field %q cannot be used for ordering", term.Field)
+		}
+		dir := "ASC"
+		if term.Desc {
+			dir = "DESC"
+		}
+		clauses = append(clauses, col+" "+dir)
+		if term.Field == tiebreaker {
+			haveTiebreaker = true
+		}
+	}
+	if tiebreaker != "" && !haveTiebreaker {
+		col, ok := columns[tiebreaker]
+		if !ok {
+			return "", fmt.Errorf("order_by: tiebreaker field %q is not in columns", tiebreaker)
+		}
+		clauses = append(clauses, col+" ASC")
+	}
+	return strings.Join(clauses, ", "), nil
+}
@@ -0,0 +1,61 @@
+package orderby
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []Term
+	}{
+		{in: "", want: nil},
+		{in: "id", want: []Term{{Field: "id"}}},
+		{in: "priority desc, due_time asc, id", want: []Term{
+			{Field: "priority", Desc: true},
+			{Field: "due_time"},
+			{Field: "id"},
+		}},
+	} {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q) err = %v", tt.in, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Parse(%q) (-want +got)\n%s", tt.in, diff)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{"a b c", "a sideways"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) err = nil; want non-nil", in)
+		}
+	}
+}
+
+func TestToSQL(t *testing.T) {
+	columns := map[string]string{
+		"priority": "priority",
+		"due_time": "due_time",
+		"id":       "id",
+	}
+	terms, err := Parse("priority desc, due_time asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToSQL(terms, columns, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "priority DESC, due_time ASC, id ASC"; got != want {
+		t.Errorf("ToSQL() = %q; want %q", got, want)
+	}
+
+	if _, err := ToSQL([]Term{{Field: "unknown"}}, columns, "id"); err == nil {
+		t.Errorf("ToSQL() with unknown field err = nil; want non-nil")
+	}
+}
@@ -0,0 +1,116 @@
+package pagetoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	key := []byte("test-key")
+	issued := time.Unix(1_700_000_000, 0)
+	payload := Payload{
+		MinimumID:   42,
+		ShowDeleted: true,
+		FilterHash:  FilterHash(`title="buy milk"`),
+		OrderBy:     "priority desc, id",
+	}
+
+	token, err := Sign(key, payload, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+
+	got, err := Verify(key, token, issued.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+	payload.IssuedAt = issued.Unix()
+	if diff := cmp.Diff(payload, got); diff != "" {
+		t.Errorf("Verify() (-want +got)\n%s", diff)
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	key := []byte("test-key")
+	issued := time.Unix(1_700_000_000, 0)
+	token, err := Sign(key, Payload{MinimumID: 1}, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+	if _, err := Verify(key, token, issued.Add(TTL+time.Second)); err == nil {
+		t.Errorf("Verify() of expired token err = nil; want non-nil")
+	}
+}
+
+func TestVerify_WrongKey(t *testing.T) {
+	issued := time.Unix(1_700_000_000, 0)
+	token, err := Sign([]byte("key-one"), Payload{MinimumID: 1}, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+	if _, err := Verify([]byte("key-two"), token, issued); err == nil {
+		t.Errorf("Verify() with wrong key err = nil; want non-nil")
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	key := []byte("test-key")
+	issued := time.Unix(1_700_000_000, 0)
+	token, err := Sign(key, Payload{MinimumID: 1}, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+	tampered := []rune(token)
+	tampered[len(tampered)-1] = 'x'
+	if _, err := Verify(key, string(tampered), issued); err == nil {
+		t.Errorf("Verify() of tampered token err = nil; want non-nil")
+	}
+}
+
+func TestVerify_Garbage(t *testing.T) {
+	if _, err := Verify([]byte("k"), "not a valid token", time.Now()); err == nil {
+		t.Errorf("Verify() of garbage token err = nil; want non-nil")
+	}
+}
+
+func TestVerifyAny_KeyRotation(t *testing.T) {
+	oldKey := []byte("old-key")
+	newKey := []byte("new-key")
+	issued := time.Unix(1_700_000_000, 0)
+	token, err := Sign(oldKey, Payload{MinimumID: 1}, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+
+	// The new key is listed first (it's the signing key going forward), but
+	// the token should still verify because the old key is also accepted.
+	if _, err := VerifyAny([][]byte{newKey, oldKey}, token, issued, TTL); err != nil {
+		t.Errorf("VerifyAny() with rotated keys err = %v; want nil", err)
+	}
+
+	// Once the old key is fully retired, the token should no longer verify.
+	if _, err := VerifyAny([][]byte{newKey}, token, issued, TTL); err == nil {
+		t.Errorf("VerifyAny() with retired key err = nil; want non-nil")
+	}
+}
+
+func TestVerifyAny_CustomTTL(t *testing.T) {
+	key := []byte("test-key")
+	issued := time.Unix(1_700_000_000, 0)
+	token, err := Sign(key, Payload{MinimumID: 1}, issued)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+	shortTTL := time.Minute
+	if _, err := VerifyAny([][]byte{key}, token, issued.Add(2*shortTTL), shortTTL); err == nil {
+		t.Errorf("VerifyAny() past custom ttl err = nil; want non-nil")
+	}
+}
+
+func TestVerifyAny_NoKeys(t *testing.T) {
+	if _, err := VerifyAny(nil, "whatever", time.Now(), TTL); err == nil {
+		t.Errorf("VerifyAny() with no keys err = nil; want non-nil")
+	}
+}
@@ -44,3 +44,19 @@ func TestService(t *testing.T) {
 	s := testsuite.New(client, &poolTruncater{pool: pool}, clock, maxPageSize)
 	suite.Run(t, s)
 }
+
+func TestService_Hammer(t *testing.T) {
+	ctx := context.Background()
+	pool := postgrestest.Open(ctx, t, "tasks/postgres/schema.sql")
+	svc := New(pool)
+	clock := clockwork.NewFakeClock()
+	svc.clock = clock
+	srv := grpctest.New(ctx, t, grpctest.Options{
+		ServiceDesc:    &pb.Tasks_ServiceDesc,
+		Implementation: svc,
+	})
+	client := pb.NewTasksClient(srv.ClientConn)
+	s := testsuite.New(client, &poolTruncater{pool: pool}, clock, maxPageSize)
+	s.SetT(t)
+	s.HammerListTasks()
+}
@@ -0,0 +1,52 @@
+// Package workspacectx extracts the caller's workspace from the incoming
+// gRPC metadata of a request, the same way go.saser.se/auth/n/basic extracts
+// HTTP Basic credentials. It only deals with getting the raw workspace
+// resource name off the wire; resolving that name to a workspace and
+// checking the caller's role within it is the service's job.
+package workspacectx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey is the gRPC metadata key under which the caller's workspace
+// (a resource name of the form "workspaces/{workspace}") is carried.
+const MetadataKey = "workspace"
+
+// FromIncomingContext extracts the workspace resource name from the gRPC
+// metadata attached to ctx. It returns an error if there is no metadata, or
+// if MetadataKey is not present exactly once.
+func FromIncomingContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errors.New("workspacectx: no metadata in incoming context")
+	}
+	values := md.Get(MetadataKey)
+	if got, want := len(values), 1; got != want {
+		return "", fmt.Errorf("workspacectx: metadata key %q has %d values; want exactly %d", MetadataKey, got, want)
+	}
+	if values[0] == "" {
+		return "", fmt.Errorf("workspacectx: metadata key %q is empty", MetadataKey)
+	}
+	return values[0], nil
+}
+
+// Interceptor returns a gRPC unary server interceptor that rejects requests
+// with codes.Unauthenticated unless they carry a workspace in their
+// metadata, so that handlers further down the chain can assume one is
+// always present.
+func Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, err := FromIncomingContext(ctx); err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "workspacectx: %v", err)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,49 @@
+package workspacectx
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromIncomingContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "workspaces/1"))
+	got, err := FromIncomingContext(ctx)
+	if err != nil {
+		t.Fatalf("FromIncomingContext() err = %v; want nil", err)
+	}
+	if want := "workspaces/1"; got != want {
+		t.Errorf("FromIncomingContext() = %q; want %q", got, want)
+	}
+}
+
+func TestFromIncomingContext_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{
+			name: "NoMetadata",
+			ctx:  context.Background(),
+		},
+		{
+			name: "NoKey",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+		},
+		{
+			name: "EmptyValue",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "")),
+		},
+		{
+			name: "TooManyValues",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "workspaces/1", MetadataKey, "workspaces/2")),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromIncomingContext(tt.ctx); err == nil {
+				t.Errorf("FromIncomingContext() err = nil; want non-nil")
+			}
+		})
+	}
+}
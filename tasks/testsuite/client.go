@@ -116,6 +116,51 @@ func (c *testClient) UncompleteTaskT(ctx context.Context, tb testing.TB, req *pb
 	return task
 }
 
+func (c *testClient) AddLabelsToTaskT(ctx context.Context, tb testing.TB, req *pb.AddLabelsToTaskRequest) *pb.Task {
+	tb.Helper()
+	task, err := c.AddLabelsToTask(ctx, req)
+	if err != nil {
+		tb.Fatalf("AddLabelsToTask(%v) err = %v; want nil", req, err)
+	}
+	return task
+}
+
+func (c *testClient) RemoveLabelsFromTaskT(ctx context.Context, tb testing.TB, req *pb.RemoveLabelsFromTaskRequest) *pb.Task {
+	tb.Helper()
+	task, err := c.RemoveLabelsFromTask(ctx, req)
+	if err != nil {
+		tb.Fatalf("RemoveLabelsFromTask(%v) err = %v; want nil", req, err)
+	}
+	return task
+}
+
+func (c *testClient) ApplyLabelCommandsT(ctx context.Context, tb testing.TB, req *pb.ApplyLabelCommandsRequest) *pb.ApplyLabelCommandsResponse {
+	tb.Helper()
+	res, err := c.ApplyLabelCommands(ctx, req)
+	if err != nil {
+		tb.Fatalf("ApplyLabelCommands(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) ReplaceTaskLabelsT(ctx context.Context, tb testing.TB, req *pb.ReplaceTaskLabelsRequest) *pb.Task {
+	tb.Helper()
+	task, err := c.ReplaceTaskLabels(ctx, req)
+	if err != nil {
+		tb.Fatalf("ReplaceTaskLabels(%v) err = %v; want nil", req, err)
+	}
+	return task
+}
+
+func (c *testClient) ListLabelsForTaskT(ctx context.Context, tb testing.TB, req *pb.ListLabelsForTaskRequest) *pb.ListLabelsForTaskResponse {
+	tb.Helper()
+	res, err := c.ListLabelsForTask(ctx, req)
+	if err != nil {
+		tb.Fatalf("ListLabelsForTask(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // Project operations.
 ///////////////////////////////////////////////////////////////////////////////
@@ -165,13 +210,56 @@ func (c *testClient) CreateProjectT(ctx context.Context, tb testing.TB, req *pb.
 
 func (c *testClient) CreateProjectsT(ctx context.Context, tb testing.TB, projects []*pb.Project) []*pb.Project {
 	tb.Helper()
-	var created []*pb.Project
-	for _, project := range projects {
-		created = append(created, c.CreateProjectT(ctx, tb, &pb.CreateProjectRequest{
-			Project: project,
-		}))
+	reqs := make([]*pb.CreateProjectRequest, len(projects))
+	for i, project := range projects {
+		reqs[i] = &pb.CreateProjectRequest{Project: project}
 	}
-	return created
+	return c.BatchCreateProjectsT(ctx, tb, &pb.BatchCreateProjectsRequest{Requests: reqs}).GetProjects()
+}
+
+func (c *testClient) BatchGetProjectsT(ctx context.Context, tb testing.TB, req *pb.BatchGetProjectsRequest) *pb.BatchGetProjectsResponse {
+	tb.Helper()
+	res, err := c.BatchGetProjects(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchGetProjects(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) BatchCreateProjectsT(ctx context.Context, tb testing.TB, req *pb.BatchCreateProjectsRequest) *pb.BatchCreateProjectsResponse {
+	tb.Helper()
+	res, err := c.BatchCreateProjects(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchCreateProjects(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) BatchDeleteProjectsT(ctx context.Context, tb testing.TB, req *pb.BatchDeleteProjectsRequest) *emptypb.Empty {
+	tb.Helper()
+	empty, err := c.BatchDeleteProjects(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchDeleteProjects(%v) err = %v; want nil", req, err)
+	}
+	return empty
+}
+
+func (c *testClient) BatchUpdateProjectsT(ctx context.Context, tb testing.TB, req *pb.BatchUpdateProjectsRequest) *pb.BatchUpdateProjectsResponse {
+	tb.Helper()
+	res, err := c.BatchUpdateProjects(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchUpdateProjects(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) BatchUndeleteProjectsT(ctx context.Context, tb testing.TB, req *pb.BatchUndeleteProjectsRequest) *pb.BatchUndeleteProjectsResponse {
+	tb.Helper()
+	res, err := c.BatchUndeleteProjects(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchUndeleteProjects(%v) err = %v; want nil", req, err)
+	}
+	return res
 }
 
 func (c *testClient) UpdateProjectT(ctx context.Context, tb testing.TB, req *pb.UpdateProjectRequest) *pb.Project {
@@ -219,6 +307,15 @@ func (c *testClient) UnarchiveProjectT(ctx context.Context, tb testing.TB, req *
 	return project
 }
 
+func (c *testClient) ForceExpirationSweepT(ctx context.Context, tb testing.TB, req *pb.ForceExpirationSweepRequest) *pb.ForceExpirationSweepResponse {
+	tb.Helper()
+	res, err := c.ForceExpirationSweep(ctx, req)
+	if err != nil {
+		tb.Fatalf("ForceExpirationSweep(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // Label operations.
 ///////////////////////////////////////////////////////////////////////////////
@@ -268,13 +365,38 @@ func (c *testClient) CreateLabelT(ctx context.Context, tb testing.TB, req *pb.Cr
 
 func (c *testClient) CreateLabelsT(ctx context.Context, tb testing.TB, labels []*pb.Label) []*pb.Label {
 	tb.Helper()
-	var created []*pb.Label
-	for _, label := range labels {
-		created = append(created, c.CreateLabelT(ctx, tb, &pb.CreateLabelRequest{
-			Label: label,
-		}))
+	reqs := make([]*pb.CreateLabelRequest, len(labels))
+	for i, label := range labels {
+		reqs[i] = &pb.CreateLabelRequest{Label: label}
 	}
-	return created
+	return c.BatchCreateLabelsT(ctx, tb, &pb.BatchCreateLabelsRequest{Requests: reqs}).GetLabels()
+}
+
+func (c *testClient) BatchGetLabelsT(ctx context.Context, tb testing.TB, req *pb.BatchGetLabelsRequest) *pb.BatchGetLabelsResponse {
+	tb.Helper()
+	res, err := c.BatchGetLabels(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchGetLabels(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) BatchCreateLabelsT(ctx context.Context, tb testing.TB, req *pb.BatchCreateLabelsRequest) *pb.BatchCreateLabelsResponse {
+	tb.Helper()
+	res, err := c.BatchCreateLabels(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchCreateLabels(%v) err = %v; want nil", req, err)
+	}
+	return res
+}
+
+func (c *testClient) BatchDeleteLabelsT(ctx context.Context, tb testing.TB, req *pb.BatchDeleteLabelsRequest) *emptypb.Empty {
+	tb.Helper()
+	empty, err := c.BatchDeleteLabels(ctx, req)
+	if err != nil {
+		tb.Fatalf("BatchDeleteLabels(%v) err = %v; want nil", req, err)
+	}
+	return empty
 }
 
 func (c *testClient) UpdateLabelT(ctx context.Context, tb testing.TB, req *pb.UpdateLabelRequest) *pb.Label {
@@ -0,0 +1,212 @@
+package testsuite
+
+import (
+	"context"
+	"testing"
+
+	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// wantErrT fatals if err is nil, or if its gRPC status code isn't wantCode,
+// and otherwise returns the status so callers can inspect details such as
+// errdetails.BadRequest field violations. name and req are only used to
+// format the failure message.
+func wantErrT(tb testing.TB, name string, req any, err error, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	if err == nil {
+		tb.Fatalf("%s(%v) err = nil; want code %v", name, req, wantCode)
+	}
+	st := status.Convert(err)
+	if got := st.Code(); got != wantCode {
+		tb.Fatalf("%s(%v) code = %v; want %v (err = %v)", name, req, got, wantCode, err)
+	}
+	return st
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Task operations.
+///////////////////////////////////////////////////////////////////////////////
+
+func (c *testClient) GetTaskErrT(ctx context.Context, tb testing.TB, req *pb.GetTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.GetTask(ctx, req)
+	return wantErrT(tb, "GetTask", req, err, wantCode)
+}
+
+func (c *testClient) ListTasksErrT(ctx context.Context, tb testing.TB, req *pb.ListTasksRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ListTasks(ctx, req)
+	return wantErrT(tb, "ListTasks", req, err, wantCode)
+}
+
+func (c *testClient) CreateTaskErrT(ctx context.Context, tb testing.TB, req *pb.CreateTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.CreateTask(ctx, req)
+	return wantErrT(tb, "CreateTask", req, err, wantCode)
+}
+
+func (c *testClient) UpdateTaskErrT(ctx context.Context, tb testing.TB, req *pb.UpdateTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UpdateTask(ctx, req)
+	return wantErrT(tb, "UpdateTask", req, err, wantCode)
+}
+
+func (c *testClient) DeleteTaskErrT(ctx context.Context, tb testing.TB, req *pb.DeleteTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.DeleteTask(ctx, req)
+	return wantErrT(tb, "DeleteTask", req, err, wantCode)
+}
+
+func (c *testClient) UndeleteTaskErrT(ctx context.Context, tb testing.TB, req *pb.UndeleteTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UndeleteTask(ctx, req)
+	return wantErrT(tb, "UndeleteTask", req, err, wantCode)
+}
+
+func (c *testClient) CompleteTaskErrT(ctx context.Context, tb testing.TB, req *pb.CompleteTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.CompleteTask(ctx, req)
+	return wantErrT(tb, "CompleteTask", req, err, wantCode)
+}
+
+func (c *testClient) UncompleteTaskErrT(ctx context.Context, tb testing.TB, req *pb.UncompleteTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UncompleteTask(ctx, req)
+	return wantErrT(tb, "UncompleteTask", req, err, wantCode)
+}
+
+func (c *testClient) AddLabelsToTaskErrT(ctx context.Context, tb testing.TB, req *pb.AddLabelsToTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.AddLabelsToTask(ctx, req)
+	return wantErrT(tb, "AddLabelsToTask", req, err, wantCode)
+}
+
+func (c *testClient) RemoveLabelsFromTaskErrT(ctx context.Context, tb testing.TB, req *pb.RemoveLabelsFromTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.RemoveLabelsFromTask(ctx, req)
+	return wantErrT(tb, "RemoveLabelsFromTask", req, err, wantCode)
+}
+
+func (c *testClient) ApplyLabelCommandsErrT(ctx context.Context, tb testing.TB, req *pb.ApplyLabelCommandsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ApplyLabelCommands(ctx, req)
+	return wantErrT(tb, "ApplyLabelCommands", req, err, wantCode)
+}
+
+func (c *testClient) ReplaceTaskLabelsErrT(ctx context.Context, tb testing.TB, req *pb.ReplaceTaskLabelsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ReplaceTaskLabels(ctx, req)
+	return wantErrT(tb, "ReplaceTaskLabels", req, err, wantCode)
+}
+
+func (c *testClient) ListLabelsForTaskErrT(ctx context.Context, tb testing.TB, req *pb.ListLabelsForTaskRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ListLabelsForTask(ctx, req)
+	return wantErrT(tb, "ListLabelsForTask", req, err, wantCode)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Project operations.
+///////////////////////////////////////////////////////////////////////////////
+
+func (c *testClient) GetProjectErrT(ctx context.Context, tb testing.TB, req *pb.GetProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.GetProject(ctx, req)
+	return wantErrT(tb, "GetProject", req, err, wantCode)
+}
+
+func (c *testClient) ListProjectsErrT(ctx context.Context, tb testing.TB, req *pb.ListProjectsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ListProjects(ctx, req)
+	return wantErrT(tb, "ListProjects", req, err, wantCode)
+}
+
+func (c *testClient) CreateProjectErrT(ctx context.Context, tb testing.TB, req *pb.CreateProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.CreateProject(ctx, req)
+	return wantErrT(tb, "CreateProject", req, err, wantCode)
+}
+
+func (c *testClient) UpdateProjectErrT(ctx context.Context, tb testing.TB, req *pb.UpdateProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UpdateProject(ctx, req)
+	return wantErrT(tb, "UpdateProject", req, err, wantCode)
+}
+
+func (c *testClient) DeleteProjectErrT(ctx context.Context, tb testing.TB, req *pb.DeleteProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.DeleteProject(ctx, req)
+	return wantErrT(tb, "DeleteProject", req, err, wantCode)
+}
+
+func (c *testClient) UndeleteProjectErrT(ctx context.Context, tb testing.TB, req *pb.UndeleteProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UndeleteProject(ctx, req)
+	return wantErrT(tb, "UndeleteProject", req, err, wantCode)
+}
+
+func (c *testClient) ArchiveProjectErrT(ctx context.Context, tb testing.TB, req *pb.ArchiveProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ArchiveProject(ctx, req)
+	return wantErrT(tb, "ArchiveProject", req, err, wantCode)
+}
+
+func (c *testClient) UnarchiveProjectErrT(ctx context.Context, tb testing.TB, req *pb.UnarchiveProjectRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UnarchiveProject(ctx, req)
+	return wantErrT(tb, "UnarchiveProject", req, err, wantCode)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Label operations.
+///////////////////////////////////////////////////////////////////////////////
+
+func (c *testClient) GetLabelErrT(ctx context.Context, tb testing.TB, req *pb.GetLabelRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.GetLabel(ctx, req)
+	return wantErrT(tb, "GetLabel", req, err, wantCode)
+}
+
+func (c *testClient) ListLabelsErrT(ctx context.Context, tb testing.TB, req *pb.ListLabelsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.ListLabels(ctx, req)
+	return wantErrT(tb, "ListLabels", req, err, wantCode)
+}
+
+func (c *testClient) CreateLabelErrT(ctx context.Context, tb testing.TB, req *pb.CreateLabelRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.CreateLabel(ctx, req)
+	return wantErrT(tb, "CreateLabel", req, err, wantCode)
+}
+
+func (c *testClient) BatchGetLabelsErrT(ctx context.Context, tb testing.TB, req *pb.BatchGetLabelsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.BatchGetLabels(ctx, req)
+	return wantErrT(tb, "BatchGetLabels", req, err, wantCode)
+}
+
+func (c *testClient) BatchCreateLabelsErrT(ctx context.Context, tb testing.TB, req *pb.BatchCreateLabelsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.BatchCreateLabels(ctx, req)
+	return wantErrT(tb, "BatchCreateLabels", req, err, wantCode)
+}
+
+func (c *testClient) BatchDeleteLabelsErrT(ctx context.Context, tb testing.TB, req *pb.BatchDeleteLabelsRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.BatchDeleteLabels(ctx, req)
+	return wantErrT(tb, "BatchDeleteLabels", req, err, wantCode)
+}
+
+func (c *testClient) UpdateLabelErrT(ctx context.Context, tb testing.TB, req *pb.UpdateLabelRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.UpdateLabel(ctx, req)
+	return wantErrT(tb, "UpdateLabel", req, err, wantCode)
+}
+
+func (c *testClient) DeleteLabelErrT(ctx context.Context, tb testing.TB, req *pb.DeleteLabelRequest, wantCode codes.Code) *status.Status {
+	tb.Helper()
+	_, err := c.DeleteLabel(ctx, req)
+	return wantErrT(tb, "DeleteLabel", req, err, wantCode)
+}
@@ -0,0 +1,89 @@
+package testsuite
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.saser.se/tasks/tasks_go_proto"
+)
+
+// hammerN is the number of concurrent goroutines HammerListTasks hammers the
+// implementation under test with. It's a flag, rather than a constant, so
+// that it can be turned up when chasing a specific race without recompiling.
+var hammerN = flag.Int("hammer.n", 50, "number of concurrent goroutines to use in HammerListTasks")
+
+// hammerRounds is how many sequential calls each of the hammerN goroutines
+// makes.
+const hammerRounds = 20
+
+// HammerListTasks concurrently issues a mix of reads (ListTasks) and writes
+// (CreateTask) against the shared implementation under test, to catch races
+// that the rest of the suite -- which runs everything sequentially -- can't.
+// Unlike the other methods on Suite, its name doesn't start with Test, so it
+// isn't picked up by suite.Run; call it explicitly from a backend's own test
+// file instead, the way TestService_Hammer does in tasks/fake and
+// tasks/service.
+func (s *Suite) HammerListTasks() {
+	t := s.T()
+	ctx := context.Background()
+	c := s.client
+
+	n := *hammerN
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+	)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	start := time.Now()
+	for g := 0; g < n; g++ {
+		g := g
+		go func() {
+			defer wg.Done()
+			for r := 0; r < hammerRounds; r++ {
+				callStart := time.Now()
+				var err error
+				if r%2 == 0 {
+					_, err = c.CreateTask(ctx, &pb.CreateTaskRequest{
+						Task: &pb.Task{Title: fmt.Sprintf("hammer %d-%d", g, r)},
+					})
+				} else {
+					_, err = c.ListTasks(ctx, &pb.ListTasksRequest{})
+				}
+				elapsed := time.Since(callStart)
+
+				mu.Lock()
+				latencies = append(latencies, elapsed)
+				mu.Unlock()
+
+				if err != nil {
+					t.Errorf("goroutine %d round %d: err = %v; want nil", g, r, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	total := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	calls := n * hammerRounds
+	rps := float64(calls) / total.Seconds()
+	t.Logf(
+		"hammer: %d goroutines x %d calls = %d calls in %v (%.1f rps), p50 = %v, p99 = %v",
+		n, hammerRounds, calls, total, rps, percentile(latencies, 0.50), percentile(latencies, 0.99),
+	)
+}
+
+// percentile returns the p-th percentile (0 <= p <= 1) of a slice of
+// durations already sorted in ascending order.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
@@ -545,6 +545,26 @@ func (s *Suite) TestCreateLabel_Duplicate() {
 	if got, want := err.Error(), original.GetName(); !strings.Contains(got, want) {
 		t.Errorf("Creating duplicate: CreateLabel(%v) err = %q; want substring %q", req, got, want)
 	}
+	assertBadRequestReason(t, err, "LABEL_DUPLICATE", "existing_name", original.GetName())
+}
+
+// TestCreateLabel_Duplicate_CaseInsensitive verifies that label strings are
+// compared case-insensitively, so "Email" is rejected as a duplicate of an
+// existing "email".
+func (s *Suite) TestCreateLabel_Duplicate_CaseInsensitive() {
+	t := s.T()
+	ctx := context.Background()
+
+	original := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+
+	req := &pb.CreateLabelRequest{Label: &pb.Label{Label: "Email"}}
+	_, err := s.client.CreateLabel(ctx, req)
+	if got, want := status.Code(err), codes.AlreadyExists; got != want {
+		t.Fatalf("CreateLabel(%v) err = %v; want code %v", req, err, want)
+	}
+	assertBadRequestReason(t, err, "LABEL_DUPLICATE", "existing_name", original.GetName())
 }
 
 func (s *Suite) TestCreateLabel_Error() {
@@ -552,9 +572,14 @@ func (s *Suite) TestCreateLabel_Error() {
 	ctx := context.Background()
 
 	for _, tt := range []struct {
-		name string
-		req  *pb.CreateLabelRequest
-		want codes.Code
+		name       string
+		req        *pb.CreateLabelRequest
+		want       codes.Code
+		wantReason string
+		// wantFields is passed to assertBadRequestReason as alternating
+		// key/value pairs; left nil when there's nothing to check beyond the
+		// reason itself.
+		wantFields []string
 	}{
 		{
 			name: "EmptyTitle",
@@ -563,7 +588,8 @@ func (s *Suite) TestCreateLabel_Error() {
 					Label: "",
 				},
 			},
-			want: codes.InvalidArgument,
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_EMPTY",
 		},
 		{
 			name: "ForbiddenCharacters_OutsideAZ",
@@ -572,7 +598,9 @@ func (s *Suite) TestCreateLabel_Error() {
 					Label: "brÃ¶d",
 				},
 			},
-			want: codes.InvalidArgument,
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_FORBIDDEN_CHARACTER",
+			wantFields: []string{"rune", "Ã", "byte_offset", "2"},
 		},
 		{
 			name: "ForbiddenCharacters_OtherSpecialCharacters",
@@ -581,7 +609,9 @@ func (s *Suite) TestCreateLabel_Error() {
 					Label: "!!!",
 				},
 			},
-			want: codes.InvalidArgument,
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_FORBIDDEN_CHARACTER",
+			wantFields: []string{"rune", "!", "byte_offset", "0"},
 		},
 		{
 			name: "ForbiddenCharacters_Space",
@@ -590,7 +620,45 @@ func (s *Suite) TestCreateLabel_Error() {
 					Label: "First label",
 				},
 			},
-			want: codes.InvalidArgument,
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_FORBIDDEN_CHARACTER",
+			wantFields: []string{"rune", " ", "byte_offset", "5"},
+		},
+		{
+			name: "InvalidColor_NonHexDigits",
+			req: &pb.CreateLabelRequest{
+				Label: &pb.Label{
+					Label: "color-test",
+					Color: "#zzzzzz",
+				},
+			},
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_INVALID_COLOR",
+			wantFields: []string{"color", "#zzzzzz"},
+		},
+		{
+			name: "InvalidColor_WrongLength",
+			req: &pb.CreateLabelRequest{
+				Label: &pb.Label{
+					Label: "color-test",
+					Color: "#abc",
+				},
+			},
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_INVALID_COLOR",
+			wantFields: []string{"color", "#abc"},
+		},
+		{
+			name: "DescriptionTooLong",
+			req: &pb.CreateLabelRequest{
+				Label: &pb.Label{
+					Label:       "description-test",
+					Description: strings.Repeat("x", 257),
+				},
+			},
+			want:       codes.InvalidArgument,
+			wantReason: "LABEL_DESCRIPTION_TOO_LONG",
+			wantFields: []string{"length", "257"},
 		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
@@ -599,6 +667,7 @@ func (s *Suite) TestCreateLabel_Error() {
 				t.Errorf("CreateLabel(%v) code = %v; want %v", tt.req, got, tt.want)
 				t.Logf("err = %v", err)
 			}
+			assertBadRequestReason(t, err, tt.wantReason, tt.wantFields...)
 		})
 	}
 }
@@ -997,3 +1066,825 @@ func (s *Suite) TestDeleteLabel_Error() {
 		})
 	}
 }
+
+func (s *Suite) TestAddLabelsToTask() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task with labels"},
+	})
+	email := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+	urgent := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "urgent"},
+	})
+
+	got := s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName(), urgent.GetName()},
+	})
+	if diff := cmp.Diff(
+		[]string{email.GetName(), urgent.GetName()},
+		got.GetLabels(),
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+	); diff != "" {
+		t.Errorf("AddLabelsToTask: unexpected labels (-want +got)\n%s", diff)
+	}
+
+	// Adding a label that's already attached should be a no-op, not an
+	// error, and not produce a duplicate entry in task.Labels.
+	got = s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName()},
+	})
+	if diff := cmp.Diff(
+		[]string{email.GetName(), urgent.GetName()},
+		got.GetLabels(),
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+	); diff != "" {
+		t.Errorf("AddLabelsToTask (duplicate): unexpected labels (-want +got)\n%s", diff)
+	}
+}
+
+func (s *Suite) TestAddLabelsToTask_Error() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task"},
+	})
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+
+	for _, tt := range []struct {
+		name string
+		req  *pb.AddLabelsToTaskRequest
+		want codes.Code
+	}{
+		{
+			name: "TaskNotFound",
+			req:  &pb.AddLabelsToTaskRequest{Task: "tasks/999999", Labels: []string{label.GetName()}},
+			want: codes.NotFound,
+		},
+		{
+			name: "LabelNotFound",
+			req:  &pb.AddLabelsToTaskRequest{Task: task.GetName(), Labels: []string{"labels/999999"}},
+			want: codes.NotFound,
+		},
+		{
+			name: "InvalidTaskName",
+			req:  &pb.AddLabelsToTaskRequest{Task: "invalid/123", Labels: []string{label.GetName()}},
+			want: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.client.AddLabelsToTask(ctx, tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("AddLabelsToTask(%v) code = %v; want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAddLabelsToTask_DeletedLabel verifies that attaching a label that was
+// created and then deleted fails with NotFound, the same way updating a
+// deleted label does (see TestUpdateLabel_AfterDeletion).
+func (s *Suite) TestAddLabelsToTask_DeletedLabel() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task"},
+	})
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "temporary"},
+	})
+	s.client.DeleteLabelT(ctx, t, &pb.DeleteLabelRequest{Name: label.GetName()})
+
+	req := &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{label.GetName()},
+	}
+	_, err := s.client.AddLabelsToTask(ctx, req)
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("AddLabelsToTask(%v) code = %v; want %v", req, got, want)
+	}
+}
+
+func (s *Suite) TestReplaceTaskLabels() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task with labels"},
+	})
+	email := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+	urgent := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "urgent"},
+	})
+	bug := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "bug"},
+	})
+	task = s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName(), urgent.GetName()},
+	})
+
+	got := s.client.ReplaceTaskLabelsT(ctx, t, &pb.ReplaceTaskLabelsRequest{
+		Task:   task.GetName(),
+		Labels: []string{urgent.GetName(), bug.GetName()},
+	})
+	if diff := cmp.Diff(
+		[]string{urgent.GetName(), bug.GetName()},
+		got.GetLabels(),
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+	); diff != "" {
+		t.Errorf("ReplaceTaskLabels: unexpected labels (-want +got)\n%s", diff)
+	}
+
+	// Replacing with an empty set should clear all labels.
+	got = s.client.ReplaceTaskLabelsT(ctx, t, &pb.ReplaceTaskLabelsRequest{
+		Task:   task.GetName(),
+		Labels: nil,
+	})
+	if len(got.GetLabels()) != 0 {
+		t.Errorf("ReplaceTaskLabels(empty): Labels = %v; want empty", got.GetLabels())
+	}
+}
+
+func (s *Suite) TestReplaceTaskLabels_Error() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task"},
+	})
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+
+	for _, tt := range []struct {
+		name string
+		req  *pb.ReplaceTaskLabelsRequest
+		want codes.Code
+	}{
+		{
+			name: "TaskNotFound",
+			req:  &pb.ReplaceTaskLabelsRequest{Task: "tasks/999999", Labels: []string{label.GetName()}},
+			want: codes.NotFound,
+		},
+		{
+			name: "LabelNotFound",
+			req:  &pb.ReplaceTaskLabelsRequest{Task: task.GetName(), Labels: []string{"labels/999999"}},
+			want: codes.NotFound,
+		},
+		{
+			name: "InvalidTaskName",
+			req:  &pb.ReplaceTaskLabelsRequest{Task: "invalid/123", Labels: []string{label.GetName()}},
+			want: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.client.ReplaceTaskLabels(ctx, tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("ReplaceTaskLabels(%v) code = %v; want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyLabelCommands verifies that free text naming labels to add and
+// remove is parsed and applied to the resulting task's label set, and that
+// a name not matching any existing label is reported back as unknown
+// instead of failing the call.
+func (s *Suite) TestApplyLabelCommands() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task"},
+	})
+	bug := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "bug"},
+	})
+	wontfix := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "wontfix"},
+	})
+	task = s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{wontfix.GetName()},
+	})
+
+	res := s.client.ApplyLabelCommandsT(ctx, t, &pb.ApplyLabelCommandsRequest{
+		Task: task.GetName(),
+		Text: "@bot please add Bug, typo-name and remove wontfix",
+	})
+	if diff := cmp.Diff(
+		[]string{bug.GetName()},
+		res.GetTask().GetLabels(),
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+	); diff != "" {
+		t.Errorf("ApplyLabelCommands: unexpected resulting labels (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"typo-name"}, res.GetUnknownLabels()); diff != "" {
+		t.Errorf("ApplyLabelCommands: unexpected UnknownLabels (-want +got)\n%s", diff)
+	}
+}
+
+func (s *Suite) TestApplyLabelCommands_Error() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.ApplyLabelCommandsRequest{Task: "tasks/999999", Text: "add bug"}
+	_, err := s.client.ApplyLabelCommands(ctx, req)
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("ApplyLabelCommands(%v) code = %v; want %v", req, got, want)
+	}
+}
+
+func (s *Suite) TestRemoveLabelsFromTask() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task with labels"},
+	})
+	email := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+	urgent := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "urgent"},
+	})
+	task = s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName(), urgent.GetName()},
+	})
+
+	got := s.client.RemoveLabelsFromTaskT(ctx, t, &pb.RemoveLabelsFromTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName()},
+	})
+	if diff := cmp.Diff([]string{urgent.GetName()}, got.GetLabels(), protocmp.Transform()); diff != "" {
+		t.Errorf("RemoveLabelsFromTask: unexpected labels (-want +got)\n%s", diff)
+	}
+
+	// Removing a label that isn't attached (anymore) should be a no-op,
+	// not an error.
+	got = s.client.RemoveLabelsFromTaskT(ctx, t, &pb.RemoveLabelsFromTaskRequest{
+		Task:   task.GetName(),
+		Labels: []string{email.GetName()},
+	})
+	if diff := cmp.Diff([]string{urgent.GetName()}, got.GetLabels(), protocmp.Transform()); diff != "" {
+		t.Errorf("RemoveLabelsFromTask (not attached): unexpected labels (-want +got)\n%s", diff)
+	}
+}
+
+func (s *Suite) TestRemoveLabelsFromTask_Error() {
+	t := s.T()
+	ctx := context.Background()
+
+	task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "task"},
+	})
+
+	for _, tt := range []struct {
+		name string
+		req  *pb.RemoveLabelsFromTaskRequest
+		want codes.Code
+	}{
+		{
+			name: "TaskNotFound",
+			req:  &pb.RemoveLabelsFromTaskRequest{Task: "tasks/999999", Labels: []string{"labels/1"}},
+			want: codes.NotFound,
+		},
+		{
+			name: "InvalidTaskName",
+			req:  &pb.RemoveLabelsFromTaskRequest{Task: "invalid/123", Labels: []string{"labels/1"}},
+			want: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.client.RemoveLabelsFromTask(ctx, tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("RemoveLabelsFromTask(%v) code = %v; want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func (s *Suite) TestDeleteLabel_DetachesFromAllTasks() {
+	t := s.T()
+	ctx := context.Background()
+
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "will_be_deleted"},
+	})
+	var tasks []*pb.Task
+	for i := 0; i < 2; i++ {
+		task := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+			Task: &pb.Task{Title: fmt.Sprintf("task %d", i)},
+		})
+		task = s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+			Task:   task.GetName(),
+			Labels: []string{label.GetName()},
+		})
+		tasks = append(tasks, task)
+	}
+
+	// The label is still attached to tasks, so deleting it without `force:
+	// true` must fail.
+	{
+		req := &pb.DeleteLabelRequest{Name: label.GetName()}
+		_, err := s.client.DeleteLabel(ctx, req)
+		if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+			t.Fatalf("DeleteLabel(%v) err = %v; want code %v", req, err, want)
+		}
+	}
+
+	s.client.DeleteLabelT(ctx, t, &pb.DeleteLabelRequest{Name: label.GetName(), Force: true})
+
+	for _, task := range tasks {
+		got := s.client.GetTaskT(ctx, t, &pb.GetTaskRequest{Name: task.GetName()})
+		if len(got.GetLabels()) != 0 {
+			t.Errorf("after deleting %s: GetTask(%s).Labels = %v; want empty", label.GetName(), task.GetName(), got.GetLabels())
+		}
+	}
+}
+
+// TestDeleteLabel_Unreferenced verifies that a label with no task
+// associations can be deleted without `force: true`.
+func (s *Suite) TestDeleteLabel_Unreferenced() {
+	t := s.T()
+	ctx := context.Background()
+
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "unreferenced"},
+	})
+	s.client.DeleteLabelT(ctx, t, &pb.DeleteLabelRequest{Name: label.GetName()})
+
+	_, err := s.client.GetLabel(ctx, &pb.GetLabelRequest{Name: label.GetName()})
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("after deletion: GetLabel(%v) err = %v; want code %v", label.GetName(), err, want)
+	}
+}
+
+func (s *Suite) TestListTasks_FilterByLabel() {
+	t := s.T()
+	ctx := context.Background()
+
+	email := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "email"},
+	})
+	urgent := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "urgent"},
+	})
+	both := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "has both labels"},
+	})
+	s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   both.GetName(),
+		Labels: []string{email.GetName(), urgent.GetName()},
+	})
+	onlyEmail := s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "has only email"},
+	})
+	s.client.AddLabelsToTaskT(ctx, t, &pb.AddLabelsToTaskRequest{
+		Task:   onlyEmail.GetName(),
+		Labels: []string{email.GetName()},
+	})
+	s.client.CreateTaskT(ctx, t, &pb.CreateTaskRequest{
+		Task: &pb.Task{Title: "has no labels"},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		filter *pb.TaskLabelFilter
+		want   []string
+	}{
+		{
+			name: "OR",
+			filter: &pb.TaskLabelFilter{
+				Labels:   []string{email.GetName(), urgent.GetName()},
+				Operator: pb.TaskLabelFilter_OR,
+			},
+			want: []string{both.GetName(), onlyEmail.GetName()},
+		},
+		{
+			name: "AND",
+			filter: &pb.TaskLabelFilter{
+				Labels:   []string{email.GetName(), urgent.GetName()},
+				Operator: pb.TaskLabelFilter_AND,
+			},
+			want: []string{both.GetName()},
+		},
+		{
+			name: "DefaultOperatorIsOR",
+			filter: &pb.TaskLabelFilter{
+				Labels: []string{email.GetName(), urgent.GetName()},
+			},
+			want: []string{both.GetName(), onlyEmail.GetName()},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.client.ListTasksT(ctx, t, &pb.ListTasksRequest{LabelFilter: tt.filter})
+			var got []string
+			for _, task := range res.GetTasks() {
+				got = append(got, task.GetName())
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("ListTasks(label_filter=%v): unexpected tasks (-want +got)\n%s", tt.filter, diff)
+			}
+		})
+	}
+}
+
+func (s *Suite) TestListLabels_OrderBy() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateLabelsT(ctx, t, []*pb.Label{
+		{Label: "charlie"},
+		{Label: "alpha"},
+		{Label: "bravo"},
+	})
+
+	for _, tt := range []struct {
+		name    string
+		orderBy string
+		want    []string
+	}{
+		{
+			name:    "Ascending",
+			orderBy: "label",
+			want:    []string{"alpha", "bravo", "charlie"},
+		},
+		{
+			name:    "Descending",
+			orderBy: "label desc",
+			want:    []string{"charlie", "bravo", "alpha"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.client.ListLabelsT(ctx, t, &pb.ListLabelsRequest{OrderBy: tt.orderBy})
+			var got []string
+			for _, label := range res.GetLabels() {
+				got = append(got, label.GetLabel())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ListLabels(order_by=%q): unexpected order (-want +got)\n%s", tt.orderBy, diff)
+			}
+		})
+	}
+}
+
+func (s *Suite) TestListLabels_Filter() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateLabelsT(ctx, t, []*pb.Label{
+		{Label: "email"},
+		{Label: "phonecall"},
+		{Label: "home"},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{
+			name:   "Exact",
+			filter: `label = "email"`,
+			want:   []string{"email"},
+		},
+		{
+			name:   "Substring",
+			filter: `label:"one"`,
+			want:   []string{"phonecall"},
+		},
+		{
+			name:   "NoMatch",
+			filter: `label = "doesnotexist"`,
+			want:   nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.client.ListLabelsT(ctx, t, &pb.ListLabelsRequest{Filter: tt.filter})
+			var got []string
+			for _, label := range res.GetLabels() {
+				got = append(got, label.GetLabel())
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("ListLabels(filter=%q): unexpected result (-want +got)\n%s", tt.filter, diff)
+			}
+		})
+	}
+}
+
+func (s *Suite) TestListLabels_ChangedFilterInvalidatesToken() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateLabelsT(ctx, t, []*pb.Label{
+		{Label: "email"},
+		{Label: "phonecall"},
+		{Label: "home"},
+	})
+
+	req := &pb.ListLabelsRequest{
+		PageSize: 1,
+		Filter:   `label != "home"`,
+	}
+	res := s.client.ListLabelsT(ctx, t, req)
+	token := res.GetNextPageToken()
+	if token == "" {
+		t.Fatalf("first page returned empty next_page_token")
+	}
+
+	// Reusing the token with a different filter should be rejected.
+	{
+		req := &pb.ListLabelsRequest{
+			PageToken: token,
+			Filter:    `label != "email"`,
+		}
+		_, err := s.client.ListLabels(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("changed filter: ListLabels(%v) code = %v; want %v", req, got, want)
+		}
+	}
+
+	// Reusing the token with a different order_by should also be rejected.
+	{
+		req := &pb.ListLabelsRequest{
+			PageToken: token,
+			Filter:    `label != "home"`,
+			OrderBy:   "label desc",
+		}
+		_, err := s.client.ListLabels(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("changed order_by: ListLabels(%v) code = %v; want %v", req, got, want)
+		}
+	}
+
+	// Reusing it unchanged should still work.
+	{
+		req := &pb.ListLabelsRequest{
+			PageToken: token,
+			Filter:    `label != "home"`,
+		}
+		if _, err := s.client.ListLabels(ctx, req); err != nil {
+			t.Errorf("unchanged: ListLabels(%v) err = %v; want nil", req, err)
+		}
+	}
+}
+
+func (s *Suite) TestCreateLabel_Metadata() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.CreateLabelRequest{
+		Label: &pb.Label{
+			Label:       "with-metadata",
+			Color:       "1a2b3c",
+			Description: "A label with some presentation metadata.",
+			Params: map[string]string{
+				"icon": "star",
+			},
+		},
+	}
+	got := s.client.CreateLabelT(ctx, t, req)
+	want := req.GetLabel()
+	if diff := cmp.Diff(want, got, protocmp.Transform(), protocmp.IgnoreFields(&pb.Label{}, "name", "create_time")); diff != "" {
+		t.Errorf("CreateLabel(%v): unexpected result (-want +got)\n%s", want, diff)
+	}
+}
+
+// TestCreateLabel_ColorNormalization verifies that a color given with a
+// leading '#' is accepted and normalized to its bare 6-digit form.
+func (s *Suite) TestCreateLabel_ColorNormalization() {
+	t := s.T()
+	ctx := context.Background()
+
+	got := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "hashed-color", Color: "#1a2b3c"},
+	})
+	if got, want := got.GetColor(), "1a2b3c"; got != want {
+		t.Errorf("got.GetColor() = %q; want %q", got, want)
+	}
+}
+
+// TestUpdateLabel_Metadata exercises update_mask semantics for the
+// color, description, and params fields: updating color alone, clearing
+// description via an explicit mask path, and both merging into and fully
+// replacing params.
+func (s *Suite) TestUpdateLabel_Metadata() {
+	t := s.T()
+	ctx := context.Background()
+
+	label := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{
+			Label:       "metadata-target",
+			Color:       "111111",
+			Description: "original description",
+			Params: map[string]string{
+				"icon": "star",
+				"sort": "1",
+			},
+		},
+	})
+
+	t.Run("ColorAlone", func(t *testing.T) {
+		got := s.client.UpdateLabelT(ctx, t, &pb.UpdateLabelRequest{
+			Label: &pb.Label{
+				Name:  label.GetName(),
+				Color: "222222",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"color"}},
+		})
+		if got, want := got.GetColor(), "222222"; got != want {
+			t.Errorf("got.GetColor() = %q; want %q", got, want)
+		}
+		if got, want := got.GetDescription(), label.GetDescription(); got != want {
+			t.Errorf("got.GetDescription() = %q; want unchanged %q", got, want)
+		}
+		label = got
+	})
+
+	t.Run("ClearDescription", func(t *testing.T) {
+		got := s.client.UpdateLabelT(ctx, t, &pb.UpdateLabelRequest{
+			Label: &pb.Label{
+				Name:        label.GetName(),
+				Description: "",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description"}},
+		})
+		if got := got.GetDescription(); got != "" {
+			t.Errorf("got.GetDescription() = %q; want empty", got)
+		}
+		label = got
+	})
+
+	t.Run("MergeParamsKey", func(t *testing.T) {
+		got := s.client.UpdateLabelT(ctx, t, &pb.UpdateLabelRequest{
+			Label: &pb.Label{
+				Name: label.GetName(),
+				Params: map[string]string{
+					"icon": "heart",
+				},
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"params.icon"}},
+		})
+		want := map[string]string{"icon": "heart", "sort": "1"}
+		if diff := cmp.Diff(want, got.GetParams()); diff != "" {
+			t.Errorf("UpdateLabel merging params.icon: unexpected params (-want +got)\n%s", diff)
+		}
+		label = got
+	})
+
+	t.Run("RemoveParamsKey", func(t *testing.T) {
+		got := s.client.UpdateLabelT(ctx, t, &pb.UpdateLabelRequest{
+			Label: &pb.Label{
+				Name: label.GetName(),
+				Params: map[string]string{
+					"sort": "",
+				},
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"params.sort"}},
+		})
+		want := map[string]string{"icon": "heart"}
+		if diff := cmp.Diff(want, got.GetParams()); diff != "" {
+			t.Errorf("UpdateLabel removing params.sort: unexpected params (-want +got)\n%s", diff)
+		}
+		label = got
+	})
+
+	t.Run("ReplaceParams", func(t *testing.T) {
+		got := s.client.UpdateLabelT(ctx, t, &pb.UpdateLabelRequest{
+			Label: &pb.Label{
+				Name: label.GetName(),
+				Params: map[string]string{
+					"new": "value",
+				},
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"params"}},
+		})
+		want := map[string]string{"new": "value"}
+		if diff := cmp.Diff(want, got.GetParams()); diff != "" {
+			t.Errorf("UpdateLabel replacing params: unexpected params (-want +got)\n%s", diff)
+		}
+	})
+}
+
+func (s *Suite) TestBatchCreateLabels() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.BatchCreateLabelsRequest{
+		Requests: []*pb.CreateLabelRequest{
+			{Label: &pb.Label{Label: "batch-one"}},
+			{Label: &pb.Label{Label: "batch-two"}},
+			{Label: &pb.Label{Label: "batch-three"}},
+		},
+	}
+	res := s.client.BatchCreateLabelsT(ctx, t, req)
+	if got, want := len(res.GetLabels()), len(req.GetRequests()); got != want {
+		t.Fatalf("BatchCreateLabels(%v) returned %d labels; want %d", req, got, want)
+	}
+	for i, label := range res.GetLabels() {
+		want := req.GetRequests()[i].GetLabel()
+		if diff := cmp.Diff(want, label, protocmp.Transform(), protocmp.IgnoreFields(&pb.Label{}, "name", "create_time")); diff != "" {
+			t.Errorf("BatchCreateLabels(%v)[%d]: unexpected result (-want +got)\n%s", req, i, diff)
+		}
+		if label.GetName() == "" {
+			t.Errorf("BatchCreateLabels(%v)[%d]: got.GetName() is empty", req, i)
+		}
+	}
+}
+
+func (s *Suite) TestBatchCreateLabels_PartialConflict_RollsBack() {
+	t := s.T()
+	ctx := context.Background()
+
+	existing := s.client.CreateLabelT(ctx, t, &pb.CreateLabelRequest{
+		Label: &pb.Label{Label: "already-there"},
+	})
+
+	req := &pb.BatchCreateLabelsRequest{
+		Requests: []*pb.CreateLabelRequest{
+			{Label: &pb.Label{Label: "new-one"}},
+			{Label: &pb.Label{Label: existing.GetLabel()}},
+			{Label: &pb.Label{Label: "new-two"}},
+		},
+	}
+	_, err := s.client.BatchCreateLabels(ctx, req)
+	if got, want := status.Code(err), codes.AlreadyExists; got != want {
+		t.Fatalf("BatchCreateLabels(%v) err = %v; want code %v", req, err, want)
+	}
+	if got, want := err.Error(), existing.GetName(); !strings.Contains(got, want) {
+		t.Errorf("BatchCreateLabels(%v) err = %q; want substring %q naming the first conflicting label", req, got, want)
+	}
+
+	// None of the non-conflicting labels in the batch should have been
+	// persisted: the whole batch rolls back.
+	all := s.client.ListAllLabelsT(ctx, t, &pb.ListLabelsRequest{})
+	for _, label := range all {
+		if label.GetLabel() == "new-one" || label.GetLabel() == "new-two" {
+			t.Errorf("found label %q after a rolled-back batch; want it absent", label.GetLabel())
+		}
+	}
+}
+
+func (s *Suite) TestBatchGetLabels_PreservesRequestOrder() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateLabelsT(ctx, t, []*pb.Label{
+		{Label: "order-a"},
+		{Label: "order-b"},
+		{Label: "order-c"},
+	})
+
+	req := &pb.BatchGetLabelsRequest{
+		Names: []string{
+			created[2].GetName(),
+			created[0].GetName(),
+			created[1].GetName(),
+		},
+	}
+	res := s.client.BatchGetLabelsT(ctx, t, req)
+	want := []*pb.Label{created[2], created[0], created[1]}
+	if diff := cmp.Diff(want, res.GetLabels(), protocmp.Transform()); diff != "" {
+		t.Errorf("BatchGetLabels(%v): unexpected result (-want +got)\n%s", req, diff)
+	}
+}
+
+func (s *Suite) TestBatchDeleteLabels_Atomic() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateLabelsT(ctx, t, []*pb.Label{
+		{Label: "delete-a"},
+		{Label: "delete-b"},
+	})
+	names := []string{created[0].GetName(), created[1].GetName()}
+
+	req := &pb.BatchDeleteLabelsRequest{Names: names}
+	s.client.BatchDeleteLabelsT(ctx, t, req)
+	for _, name := range names {
+		_, err := s.client.GetLabel(ctx, &pb.GetLabelRequest{Name: name})
+		if got, want := status.Code(err), codes.NotFound; got != want {
+			t.Errorf("after BatchDeleteLabels(%v): GetLabel(%q) err = %v; want code %v", req, name, err, want)
+		}
+	}
+
+	// Deleting the same names again is a no-op, not an error: the call is
+	// idempotent.
+	if _, err := s.client.BatchDeleteLabels(ctx, req); err != nil {
+		t.Errorf("BatchDeleteLabels(%v) (second call) err = %v; want nil", req, err)
+	}
+}
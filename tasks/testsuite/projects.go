@@ -3,6 +3,7 @@ package testsuite
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -585,6 +586,422 @@ func (s *Suite) TestListProjects_Error() {
 	}
 }
 
+func (s *Suite) TestListProjects_Filter() {
+	t := s.T()
+	ctx := context.Background()
+
+	buyMilk := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Buy milk", Description: "From the corner store"},
+	})
+	laundry := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Do the laundry", Description: "Darks and lights separately"},
+	})
+	s.clock.Advance(1 * time.Hour)
+	midpoint := s.clock.Now()
+	s.clock.Advance(1 * time.Hour)
+	swole := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Get swole", Description: "From the corner store"},
+	})
+
+	deleted := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: laundry.GetName()})
+
+	for _, tt := range []struct {
+		name        string
+		req         *pb.ListProjectsRequest
+		want        []*pb.Project
+	}{
+		{
+			name: "TitleSubstring",
+			req:  &pb.ListProjectsRequest{Filter: `title:"milk"`},
+			want: []*pb.Project{buyMilk},
+		},
+		{
+			name: "DescriptionEquality",
+			req:  &pb.ListProjectsRequest{Filter: `description = "From the corner store"`},
+			want: []*pb.Project{buyMilk, swole},
+		},
+		{
+			name: "CreateTimeRange",
+			req:  &pb.ListProjectsRequest{Filter: fmt.Sprintf(`create_time >= %q`, midpoint.Format(time.RFC3339))},
+			want: []*pb.Project{swole},
+		},
+		{
+			name: "DeletedFalse",
+			req:  &pb.ListProjectsRequest{Filter: `delete_time = NULL`, ShowDeleted: true},
+			want: []*pb.Project{buyMilk, swole},
+		},
+		{
+			name: "DeletedTrue",
+			req:  &pb.ListProjectsRequest{Filter: `delete_time != NULL`, ShowDeleted: true},
+			want: []*pb.Project{deleted},
+		},
+		{
+			name: "NoMatch",
+			req:  &pb.ListProjectsRequest{Filter: `title = "does not exist"`},
+			want: nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.client.ListProjectsT(ctx, t, tt.req)
+			if diff := cmp.Diff(tt.want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+				t.Errorf("ListProjects(filter=%q): unexpected result (-want +got)\n%s", tt.req.GetFilter(), diff)
+			}
+		})
+	}
+}
+
+func (s *Suite) TestListProjects_Filter_Invalid() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.ListProjectsRequest{Filter: `not_a_real_field = "whatever"`}
+	_, err := s.client.ListProjects(ctx, req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("ListProjects(%v) code = %v; want %v", req, got, want)
+		t.Logf("err = %v", err)
+	}
+}
+
+func (s *Suite) TestListProjects_ChangedFilterInvalidatesToken() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "Buy milk"},
+		{Title: "Do the laundry"},
+		{Title: "Get swole"},
+	})
+
+	req := &pb.ListProjectsRequest{
+		PageSize: 1,
+		Filter:   `title != "Do the laundry"`,
+	}
+	res := s.client.ListProjectsT(ctx, t, req)
+	token := res.GetNextPageToken()
+	if token == "" {
+		t.Fatalf("first page returned empty next_page_token")
+	}
+
+	// Reusing the token with a different filter should be rejected.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken: token,
+			Filter:    `title != "Get swole"`,
+		}
+		_, err := s.client.ListProjects(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("changed filter: ListProjects(%v) code = %v; want %v", req, got, want)
+		}
+	}
+
+	// Reusing it unchanged should still work.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken: token,
+			Filter:    `title != "Do the laundry"`,
+		}
+		if _, err := s.client.ListProjects(ctx, req); err != nil {
+			t.Errorf("unchanged: ListProjects(%v) err = %v; want nil", req, err)
+		}
+	}
+}
+
+func (s *Suite) TestListProjects_OrderBy() {
+	t := s.T()
+	ctx := context.Background()
+
+	buyMilk := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Buy milk"},
+	})
+	doLaundry := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Do the laundry"},
+	})
+	getSwole := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Get swole"},
+	})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{OrderBy: "title desc"})
+	want := []*pb.Project{getSwole, doLaundry, buyMilk}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform()); diff != "" {
+		t.Errorf("ListProjects(order_by=title desc): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+// TestListProjects_OrderBy_Pages verifies that order_by is honored across
+// multiple pages, not just within a single page.
+func (s *Suite) TestListProjects_OrderBy_Pages() {
+	t := s.T()
+	ctx := context.Background()
+
+	buyMilk := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Buy milk"},
+	})
+	doLaundry := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Do the laundry"},
+	})
+	getSwole := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "Get swole"},
+	})
+
+	var got []*pb.Project
+	req := &pb.ListProjectsRequest{PageSize: 1, OrderBy: "title desc"}
+	for {
+		res := s.client.ListProjectsT(ctx, t, req)
+		got = append(got, res.GetProjects()...)
+		if res.GetNextPageToken() == "" {
+			break
+		}
+		req = &pb.ListProjectsRequest{PageToken: res.GetNextPageToken(), OrderBy: "title desc"}
+	}
+	want := []*pb.Project{getSwole, doLaundry, buyMilk}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("paginated ListProjects(order_by=title desc): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+func (s *Suite) TestListProjects_OrderBy_Invalid() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.ListProjectsRequest{OrderBy: "not_a_real_field desc"}
+	_, err := s.client.ListProjects(ctx, req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("ListProjects(%v) code = %v; want %v", req, got, want)
+		t.Logf("err = %v", err)
+	}
+}
+
+func (s *Suite) TestListProjects_ChangedOrderByInvalidatesToken() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "Buy milk"},
+		{Title: "Do the laundry"},
+		{Title: "Get swole"},
+	})
+
+	req := &pb.ListProjectsRequest{
+		PageSize: 1,
+		OrderBy:  "title desc",
+	}
+	res := s.client.ListProjectsT(ctx, t, req)
+	token := res.GetNextPageToken()
+	if token == "" {
+		t.Fatalf("first page returned empty next_page_token")
+	}
+
+	// Reusing the token with a different order_by should be rejected.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken: token,
+			OrderBy:   "title asc",
+		}
+		_, err := s.client.ListProjects(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("changed order_by: ListProjects(%v) code = %v; want %v", req, got, want)
+		}
+	}
+
+	// Reusing it unchanged should still work.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken: token,
+			OrderBy:   "title desc",
+		}
+		if _, err := s.client.ListProjects(ctx, req); err != nil {
+			t.Errorf("unchanged: ListProjects(%v) err = %v; want nil", req, err)
+		}
+	}
+}
+
+// TestListProjects_StateFilter_Active verifies that state_filter = ACTIVE
+// excludes both trashed and archived projects, mirroring the default (no
+// show_deleted, no show_archived) behavior.
+func (s *Suite) TestListProjects_StateFilter_Active() {
+	t := s.T()
+	ctx := context.Background()
+
+	projects := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "stays active"},
+		{Title: "gets trashed"},
+		{Title: "gets archived"},
+	})
+	s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: projects[1].GetName()})
+	s.client.ArchiveProjectT(ctx, t, &pb.ArchiveProjectRequest{Name: projects[2].GetName()})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		StateFilter: pb.ListProjectsRequest_ACTIVE,
+	})
+	want := []*pb.Project{projects[0]}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+		t.Errorf("ListProjects(state_filter=ACTIVE): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+// TestListProjects_StateFilter_Trashed verifies that state_filter = TRASHED
+// returns only soft-deleted projects, and that it still drops entries whose
+// expire_time has passed, the same way show_deleted = true already does.
+func (s *Suite) TestListProjects_StateFilter_Trashed() {
+	t := s.T()
+	ctx := context.Background()
+
+	projects := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "stays active"},
+		{Title: "gets trashed"},
+		{Title: "expires soon"},
+	})
+	trashed := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: projects[1].GetName()})
+	expiring := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: projects[2].GetName()})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		StateFilter: pb.ListProjectsRequest_TRASHED,
+	})
+	want := []*pb.Project{trashed, expiring}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+		t.Errorf("ListProjects(state_filter=TRASHED): unexpected result (-want +got)\n%s", diff)
+	}
+
+	// Once expiring's expire_time has passed, it should drop out of the
+	// TRASHED listing.
+	s.clock.Advance(expiring.GetExpireTime().AsTime().Sub(s.clock.Now()))
+	s.clock.Advance(1 * time.Minute)
+	res = s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		StateFilter: pb.ListProjectsRequest_TRASHED,
+	})
+	want = []*pb.Project{trashed}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+		t.Errorf("after expiration: ListProjects(state_filter=TRASHED): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+// TestListProjects_StateFilter_Archived verifies that state_filter = ARCHIVED
+// returns only archived projects, sorted by archive_time, unless the caller
+// gave an explicit order_by.
+func (s *Suite) TestListProjects_StateFilter_Archived() {
+	t := s.T()
+	ctx := context.Background()
+
+	projects := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "stays active"},
+		{Title: "archived first"},
+		{Title: "archived second"},
+	})
+	s.clock.Advance(1 * time.Hour)
+	archivedFirst := s.client.ArchiveProjectT(ctx, t, &pb.ArchiveProjectRequest{Name: projects[1].GetName()})
+	s.clock.Advance(1 * time.Hour)
+	archivedSecond := s.client.ArchiveProjectT(ctx, t, &pb.ArchiveProjectRequest{Name: projects[2].GetName()})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		StateFilter: pb.ListProjectsRequest_ARCHIVED,
+	})
+	want := []*pb.Project{archivedFirst, archivedSecond}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform()); diff != "" {
+		t.Errorf("ListProjects(state_filter=ARCHIVED): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+// TestListProjects_StateFilter_All verifies that state_filter = ALL returns
+// active, trashed, and archived projects together.
+func (s *Suite) TestListProjects_StateFilter_All() {
+	t := s.T()
+	ctx := context.Background()
+
+	projects := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "stays active"},
+		{Title: "gets trashed"},
+		{Title: "gets archived"},
+	})
+	trashed := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: projects[1].GetName()})
+	archived := s.client.ArchiveProjectT(ctx, t, &pb.ArchiveProjectRequest{Name: projects[2].GetName()})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		StateFilter: pb.ListProjectsRequest_ALL,
+	})
+	want := []*pb.Project{projects[0], trashed, archived}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+		t.Errorf("ListProjects(state_filter=ALL): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+// TestListProjects_StateFilter_BackwardCompatible verifies that omitting
+// state_filter (STATE_UNSPECIFIED) continues to be driven by show_deleted
+// and show_archived exactly as before the field existed.
+func (s *Suite) TestListProjects_StateFilter_BackwardCompatible() {
+	t := s.T()
+	ctx := context.Background()
+
+	projects := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "stays active"},
+		{Title: "gets trashed"},
+	})
+	trashed := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: projects[1].GetName()})
+
+	res := s.client.ListProjectsT(ctx, t, &pb.ListProjectsRequest{
+		ShowDeleted: true,
+	})
+	want := []*pb.Project{projects[0], trashed}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform(), cmpopts.SortSlices(projectLessFunc)); diff != "" {
+		t.Errorf("ListProjects(show_deleted=true): unexpected result (-want +got)\n%s", diff)
+	}
+}
+
+func (s *Suite) TestListProjects_StateFilter_Invalid() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.ListProjectsRequest{StateFilter: pb.ListProjectsRequest_StateFilter(99)}
+	_, err := s.client.ListProjects(ctx, req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("ListProjects(%v) code = %v; want %v", req, got, want)
+		t.Logf("err = %v", err)
+	}
+}
+
+func (s *Suite) TestListProjects_ChangedStateFilterInvalidatesToken() {
+	t := s.T()
+	ctx := context.Background()
+
+	s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "Buy milk"},
+		{Title: "Do the laundry"},
+		{Title: "Get swole"},
+	})
+
+	req := &pb.ListProjectsRequest{
+		PageSize:    1,
+		StateFilter: pb.ListProjectsRequest_ACTIVE,
+	}
+	res := s.client.ListProjectsT(ctx, t, req)
+	token := res.GetNextPageToken()
+	if token == "" {
+		t.Fatalf("first page returned empty next_page_token")
+	}
+
+	// Reusing the token with a different state_filter should be rejected.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken:   token,
+			StateFilter: pb.ListProjectsRequest_ALL,
+		}
+		_, err := s.client.ListProjects(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("changed state_filter: ListProjects(%v) code = %v; want %v", req, got, want)
+		}
+	}
+
+	// Reusing it unchanged should still work.
+	{
+		req := &pb.ListProjectsRequest{
+			PageToken:   token,
+			StateFilter: pb.ListProjectsRequest_ACTIVE,
+		}
+		if _, err := s.client.ListProjects(ctx, req); err != nil {
+			t.Errorf("unchanged: ListProjects(%v) err = %v; want nil", req, err)
+		}
+	}
+}
+
 func (s *Suite) TestCreateProject() {
 	t := s.T()
 	ctx := context.Background()
@@ -1047,6 +1464,117 @@ func (s *Suite) TestUpdateProject_Error() {
 	}
 }
 
+func (s *Suite) TestUpdateProject_IfMatch() {
+	t := s.T()
+	ctx := context.Background()
+
+	t.Run("MatchingIfMatchSucceeds", func(t *testing.T) {
+		project := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+			Project: &pb.Project{Title: "Before the update"},
+		})
+		s.clock.Advance(time.Minute)
+		got := s.client.UpdateProjectT(ctx, t, &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "After the update",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			IfMatch:    project.GetEtag(),
+		})
+		if got.GetEtag() == "" || got.GetEtag() == project.GetEtag() {
+			t.Errorf("UpdateProject with matching if_match: etag = %q; want a new, non-empty etag (was %q)", got.GetEtag(), project.GetEtag())
+		}
+	})
+
+	t.Run("StaleIfMatchFails", func(t *testing.T) {
+		project := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+			Project: &pb.Project{Title: "Before the update"},
+		})
+		staleEtag := project.GetEtag()
+
+		// A first update, from the same snapshot, succeeds and changes the etag.
+		s.clock.Advance(time.Minute)
+		current := s.client.UpdateProjectT(ctx, t, &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "Updated by the first writer",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			IfMatch:    staleEtag,
+		})
+
+		// A second update using the now-stale etag must be rejected, and must
+		// not change the project.
+		s.clock.Advance(time.Minute)
+		req := &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "Updated by the second writer",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			IfMatch:    staleEtag,
+		}
+		_, err := s.client.UpdateProject(ctx, req)
+		if got, want := status.Code(err), codes.Aborted; got != want {
+			t.Errorf("UpdateProject(%v) with stale if_match code = %v; want %v", req, got, want)
+		}
+
+		got := s.client.GetProjectT(ctx, t, &pb.GetProjectRequest{Name: project.GetName()})
+		if diff := cmp.Diff(current, got, protocmp.Transform()); diff != "" {
+			t.Errorf("project changed after rejected stale-if_match update (-want +got)\n%s", diff)
+		}
+	})
+
+	t.Run("EmptyIfMatchForcesUpdate", func(t *testing.T) {
+		project := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+			Project: &pb.Project{Title: "Before the update"},
+		})
+		// Bump the etag once, without anyone else's knowledge of it, just
+		// like StaleIfMatchFails above.
+		s.clock.Advance(time.Minute)
+		s.client.UpdateProjectT(ctx, t, &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "Updated by the first writer",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			IfMatch:    project.GetEtag(),
+		})
+
+		// An update without an if_match should still succeed, per today's
+		// last-writer-wins behavior, even though the caller's view is stale.
+		s.clock.Advance(time.Minute)
+		got := s.client.UpdateProjectT(ctx, t, &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "Updated by the second writer, without an if_match",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+		})
+		if got, want := got.GetTitle(), "Updated by the second writer, without an if_match"; got != want {
+			t.Errorf("UpdateProject without if_match: title = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("NoOpUpdateLeavesEtagUnchanged", func(t *testing.T) {
+		project := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+			Project: &pb.Project{Title: "Same title throughout"},
+		})
+		s.clock.Advance(time.Minute)
+		got := s.client.UpdateProjectT(ctx, t, &pb.UpdateProjectRequest{
+			Project: &pb.Project{
+				Name:  project.GetName(),
+				Title: "Same title throughout",
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			IfMatch:    project.GetEtag(),
+		})
+		if got, want := got.GetEtag(), project.GetEtag(); got != want {
+			t.Errorf("UpdateProject with no-op patch: etag = %q; want unchanged %q", got, want)
+		}
+	})
+}
+
 func (s *Suite) TestUpdateProject_AfterDeletion() {
 	t := s.T()
 	ctx := context.Background()
@@ -1219,6 +1747,41 @@ func (s *Suite) TestUndeleteProject_Error() {
 	}
 }
 
+// TestDeleteProject_ExpirationSweepPurges verifies that once a soft-deleted
+// project's expire_time has passed and an expiration sweep has run, the
+// project is gone for good: GetProject and UndeleteProject both report
+// NotFound, as opposed to the AlreadyExists that UndeleteProject would
+// otherwise report for a project that exists but was never deleted. This is
+// what distinguishes "soft-deleted, still recoverable" from "hard-deleted,
+// gone".
+func (s *Suite) TestDeleteProject_ExpirationSweepPurges() {
+	t := s.T()
+	ctx := context.Background()
+
+	project := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "will be purged"},
+	})
+	deleted := s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{
+		Name: project.GetName(),
+	})
+
+	// Advance the clock past expire_time, then force the sweep to run
+	// immediately instead of waiting for its normal interval.
+	s.clock.Advance(deleted.GetExpireTime().AsTime().Sub(s.clock.Now()) + time.Second)
+	s.client.ForceExpirationSweepT(ctx, t, &pb.ForceExpirationSweepRequest{})
+
+	if _, err := s.client.GetProject(ctx, &pb.GetProjectRequest{
+		Name: project.GetName(),
+	}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetProject after purge: code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+
+	req := &pb.UndeleteProjectRequest{Name: project.GetName()}
+	if _, err := s.client.UndeleteProject(ctx, req); status.Code(err) != codes.NotFound {
+		t.Errorf("UndeleteProject(%v) after purge: code = %v; want %v", req, status.Code(err), codes.NotFound)
+	}
+}
+
 func (s *Suite) TestArchiveProject_UnarchiveProject_ClearsArchiveTime() {
 	t := s.T()
 	ctx := context.Background()
@@ -1382,3 +1945,300 @@ func (s *Suite) TestUnarchiveProject_NotArchived() {
 		t.Fatalf("Uncompleting an uncompleted project wasn't a no-op (-want +got)\n%s", diff)
 	}
 }
+
+func (s *Suite) TestBatchCreateProjects() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.BatchCreateProjectsRequest{
+		Requests: []*pb.CreateProjectRequest{
+			{Project: &pb.Project{Title: "batch-one"}},
+			{Project: &pb.Project{Title: "batch-two"}},
+			{Project: &pb.Project{Title: "batch-three"}},
+		},
+	}
+	res := s.client.BatchCreateProjectsT(ctx, t, req)
+	if got, want := len(res.GetProjects()), len(req.GetRequests()); got != want {
+		t.Fatalf("BatchCreateProjects(%v) returned %d projects; want %d", req, got, want)
+	}
+	for i, project := range res.GetProjects() {
+		want := req.GetRequests()[i].GetProject()
+		if diff := cmp.Diff(want, project, protocmp.Transform(), protocmp.IgnoreFields(&pb.Project{}, "name", "create_time", "workspace")); diff != "" {
+			t.Errorf("BatchCreateProjects(%v)[%d]: unexpected result (-want +got)\n%s", req, i, diff)
+		}
+		if project.GetName() == "" {
+			t.Errorf("BatchCreateProjects(%v)[%d]: got.GetName() is empty", req, i)
+		}
+	}
+}
+
+func (s *Suite) TestBatchCreateProjects_PartialFailure_RollsBack() {
+	t := s.T()
+	ctx := context.Background()
+
+	req := &pb.BatchCreateProjectsRequest{
+		Requests: []*pb.CreateProjectRequest{
+			{Project: &pb.Project{Title: "new-one"}},
+			{Project: &pb.Project{Title: ""}}, // Invalid: a project must have a title.
+			{Project: &pb.Project{Title: "new-two"}},
+		},
+	}
+	_, err := s.client.BatchCreateProjects(ctx, req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Fatalf("BatchCreateProjects(%v) err = %v; want code %v", req, err, want)
+	}
+
+	// None of the valid projects in the batch should have been persisted:
+	// the whole batch rolls back.
+	all := s.client.ListAllProjectsT(ctx, t, &pb.ListProjectsRequest{})
+	for _, project := range all {
+		if project.GetTitle() == "new-one" || project.GetTitle() == "new-two" {
+			t.Errorf("found project %q after a rolled-back batch; want it absent", project.GetTitle())
+		}
+	}
+}
+
+func (s *Suite) TestBatchGetProjects_PreservesRequestOrder() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "order-a"},
+		{Title: "order-b"},
+		{Title: "order-c"},
+	})
+
+	req := &pb.BatchGetProjectsRequest{
+		Names: []string{
+			created[2].GetName(),
+			created[0].GetName(),
+			created[1].GetName(),
+		},
+	}
+	res := s.client.BatchGetProjectsT(ctx, t, req)
+	want := []*pb.Project{created[2], created[0], created[1]}
+	if diff := cmp.Diff(want, res.GetProjects(), protocmp.Transform()); diff != "" {
+		t.Errorf("BatchGetProjects(%v): unexpected result (-want +got)\n%s", req, diff)
+	}
+}
+
+func (s *Suite) TestBatchGetProjects_Missing() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "exists"},
+	})
+
+	req := &pb.BatchGetProjectsRequest{
+		Names: []string{created.GetName(), "projects/999999999"},
+	}
+	_, err := s.client.BatchGetProjects(ctx, req)
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("BatchGetProjects(%v) code = %v; want %v", req, got, want)
+	}
+	if got, want := err.Error(), "999999999"; !strings.Contains(got, want) {
+		t.Errorf("BatchGetProjects(%v) err = %q; want substring %q naming the missing project", req, got, want)
+	}
+}
+
+func (s *Suite) TestBatchDeleteProjects_Atomic() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "delete-a"},
+		{Title: "delete-b"},
+	})
+	names := []string{created[0].GetName(), created[1].GetName()}
+
+	req := &pb.BatchDeleteProjectsRequest{Names: names}
+	s.client.BatchDeleteProjectsT(ctx, t, req)
+	for _, name := range names {
+		_, err := s.client.GetProject(ctx, &pb.GetProjectRequest{Name: name})
+		if got, want := status.Code(err), codes.NotFound; got != want {
+			t.Errorf("after BatchDeleteProjects(%v): GetProject(%q) err = %v; want code %v", req, name, err, want)
+		}
+	}
+}
+
+// TestBatchDeleteProjects_SharedDeleteTime verifies that every project in a
+// batch gets the exact same delete_time and expire_time, since they should
+// be derived from a single clock read rather than one per project.
+func (s *Suite) TestBatchDeleteProjects_SharedDeleteTime() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "shared-a"},
+		{Title: "shared-b"},
+		{Title: "shared-c"},
+	})
+	names := []string{created[0].GetName(), created[1].GetName(), created[2].GetName()}
+
+	s.client.BatchDeleteProjectsT(ctx, t, &pb.BatchDeleteProjectsRequest{Names: names})
+
+	all := s.client.ListAllProjectsT(ctx, t, &pb.ListProjectsRequest{ShowDeleted: true})
+	var deleteTimes []*timestamppb.Timestamp
+	for _, project := range all {
+		for _, name := range names {
+			if project.GetName() == name {
+				deleteTimes = append(deleteTimes, project.GetDeleteTime())
+			}
+		}
+	}
+	if len(deleteTimes) != len(names) {
+		t.Fatalf("found %d deleted projects among %v; want %d", len(deleteTimes), names, len(names))
+	}
+	for i, dt := range deleteTimes {
+		if !dt.AsTime().Equal(deleteTimes[0].AsTime()) {
+			t.Errorf("delete_time[%d] = %v; want it to equal delete_time[0] = %v", i, dt.AsTime(), deleteTimes[0].AsTime())
+		}
+	}
+}
+
+func (s *Suite) TestBatchUpdateProjects_PartialFailure_RollsBack() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "update-a"},
+		{Title: "update-b"},
+	})
+
+	req := &pb.BatchUpdateProjectsRequest{
+		Requests: []*pb.UpdateProjectRequest{
+			{
+				Project:    &pb.Project{Name: created[0].GetName(), Title: "update-a-changed"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			},
+			{
+				// Invalid: this project does not exist.
+				Project:    &pb.Project{Name: "projects/999999999", Title: "nope"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			},
+		},
+	}
+	_, err := s.client.BatchUpdateProjects(ctx, req)
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Fatalf("BatchUpdateProjects(%v) err = %v; want code %v", req, err, want)
+	}
+
+	// After the failed update the project should be intact: the title
+	// change to update-a should not have been persisted either.
+	got := s.client.GetProjectT(ctx, t, &pb.GetProjectRequest{Name: created[0].GetName()})
+	if got.GetTitle() != "update-a" {
+		t.Errorf("after failed BatchUpdateProjects(%v): GetProject(%q).Title = %q; want %q", req, created[0].GetName(), got.GetTitle(), "update-a")
+	}
+}
+
+// TestBatchUpdateProjects_SharedUpdateTime verifies that every updated
+// project in a batch gets the exact same update_time.
+func (s *Suite) TestBatchUpdateProjects_SharedUpdateTime() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "batch-update-a"},
+		{Title: "batch-update-b"},
+		{Title: "batch-update-c"},
+	})
+
+	req := &pb.BatchUpdateProjectsRequest{
+		Requests: []*pb.UpdateProjectRequest{
+			{Project: &pb.Project{Name: created[0].GetName(), Title: "batch-update-a-2"}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}}},
+			{Project: &pb.Project{Name: created[1].GetName(), Title: "batch-update-b-2"}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}}},
+			{Project: &pb.Project{Name: created[2].GetName(), Title: "batch-update-c-2"}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}}},
+		},
+	}
+	res := s.client.BatchUpdateProjectsT(ctx, t, req)
+	if got, want := len(res.GetProjects()), len(req.GetRequests()); got != want {
+		t.Fatalf("BatchUpdateProjects(%v) returned %d projects; want %d", req, got, want)
+	}
+	want := res.GetProjects()[0].GetUpdateTime()
+	for i, project := range res.GetProjects() {
+		if !project.GetUpdateTime().AsTime().Equal(want.AsTime()) {
+			t.Errorf("update_time[%d] = %v; want it to equal update_time[0] = %v", i, project.GetUpdateTime().AsTime(), want.AsTime())
+		}
+	}
+}
+
+// TestBatchUpdateProjects_NoOpLeavesUpdateTimeUnchanged verifies that a
+// batch update whose patch doesn't actually change anything leaves
+// update_time (and the etag) untouched, the same way UpdateProject does.
+func (s *Suite) TestBatchUpdateProjects_NoOpLeavesUpdateTimeUnchanged() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectT(ctx, t, &pb.CreateProjectRequest{
+		Project: &pb.Project{Title: "no-op-batch"},
+	})
+
+	s.clock.Advance(time.Minute)
+
+	req := &pb.BatchUpdateProjectsRequest{
+		Requests: []*pb.UpdateProjectRequest{
+			{
+				Project:    &pb.Project{Name: created.GetName(), Title: created.GetTitle()},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+			},
+		},
+	}
+	res := s.client.BatchUpdateProjectsT(ctx, t, req)
+	got := res.GetProjects()[0]
+	if got.GetUpdateTime().IsValid() || created.GetUpdateTime().IsValid() {
+		if !got.GetUpdateTime().AsTime().Equal(created.GetUpdateTime().AsTime()) {
+			t.Errorf("after no-op BatchUpdateProjects(%v): update_time = %v; want unchanged %v", req, got.GetUpdateTime().AsTime(), created.GetUpdateTime().AsTime())
+		}
+	}
+	if got.GetEtag() != created.GetEtag() {
+		t.Errorf("after no-op BatchUpdateProjects(%v): etag = %q; want unchanged %q", req, got.GetEtag(), created.GetEtag())
+	}
+}
+
+func (s *Suite) TestBatchUndeleteProjects() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "undelete-a"},
+		{Title: "undelete-b"},
+	})
+	names := []string{created[0].GetName(), created[1].GetName()}
+	s.client.BatchDeleteProjectsT(ctx, t, &pb.BatchDeleteProjectsRequest{Names: names})
+
+	s.client.BatchUndeleteProjectsT(ctx, t, &pb.BatchUndeleteProjectsRequest{Names: names})
+	for _, name := range names {
+		project := s.client.GetProjectT(ctx, t, &pb.GetProjectRequest{Name: name})
+		if project.GetDeleteTime().IsValid() {
+			t.Errorf("after BatchUndeleteProjects(%v): GetProject(%q).DeleteTime is still set", names, name)
+		}
+	}
+}
+
+func (s *Suite) TestBatchUndeleteProjects_PartialFailure_RollsBack() {
+	t := s.T()
+	ctx := context.Background()
+
+	created := s.client.CreateProjectsT(ctx, t, []*pb.Project{
+		{Title: "undelete-rollback-a"},
+		{Title: "undelete-rollback-b"},
+	})
+	// Only delete the first project, so the second one is not eligible for
+	// undeletion (it was never deleted) and the whole batch should fail.
+	s.client.DeleteProjectT(ctx, t, &pb.DeleteProjectRequest{Name: created[0].GetName()})
+
+	req := &pb.BatchUndeleteProjectsRequest{
+		Names: []string{created[0].GetName(), created[1].GetName()},
+	}
+	_, err := s.client.BatchUndeleteProjects(ctx, req)
+	if got, want := status.Code(err), codes.AlreadyExists; got != want {
+		t.Fatalf("BatchUndeleteProjects(%v) err = %v; want code %v", req, err, want)
+	}
+
+	// The first project should still be deleted: the whole batch rolls back.
+	got := s.client.GetProjectT(ctx, t, &pb.GetProjectRequest{Name: created[0].GetName()})
+	if !got.GetDeleteTime().IsValid() {
+		t.Errorf("after failed BatchUndeleteProjects(%v): GetProject(%q).DeleteTime is unset; want it still deleted", req, created[0].GetName())
+	}
+}
@@ -2,16 +2,33 @@ package testsuite
 
 import (
 	"context"
+	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/suite"
 	pb "go.saser.se/tasks/tasks_go_proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 func taskLessFunc(t1, t2 *pb.Task) bool {
 	return t1.GetName() < t2.GetName()
 }
 
+// DiffTasksT diffs want against got using protocmp.Transform and a stable
+// ordering by task name (cmpopts.SortSlices(taskLessFunc)), so callers don't
+// have to repeat that boilerplate at every call site. Additional opts are
+// appended after those two, so callers can add further cmp.Options (e.g.
+// protocmp.IgnoreFields) without losing the defaults.
+func DiffTasksT(tb testing.TB, want, got []*pb.Task, opts ...cmp.Option) string {
+	tb.Helper()
+	allOpts := append([]cmp.Option{protocmp.Transform(), cmpopts.SortSlices(taskLessFunc)}, opts...)
+	return cmp.Diff(want, got, allOpts...)
+}
+
 // Suite contains a suite of tests for an implementation of Tasks service.
 type Suite struct {
 	suite.Suite
@@ -54,3 +71,35 @@ func (s *Suite) truncate(ctx context.Context) {
 		t.Fatal(err)
 	}
 }
+
+// assertBadRequestReason asserts that err carries an errdetails.ErrorInfo
+// detail with the given reason, and that its metadata contains every
+// key/value pair in wantFields (given as alternating key, value, key,
+// value, ...).
+func assertBadRequestReason(t testing.TB, err error, wantReason string, wantFields ...string) {
+	t.Helper()
+	if len(wantFields)%2 != 0 {
+		t.Fatalf("assertBadRequestReason: wantFields must have an even number of elements (key, value pairs); got %d", len(wantFields))
+	}
+	st := status.Convert(err)
+	var info *errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		if ei, ok := d.(*errdetails.ErrorInfo); ok {
+			info = ei
+			break
+		}
+	}
+	if info == nil {
+		t.Errorf("err = %v: want an errdetails.ErrorInfo detail with reason %q; found none", err, wantReason)
+		return
+	}
+	if info.GetReason() != wantReason {
+		t.Errorf("err = %v: ErrorInfo.Reason = %q; want %q", err, info.GetReason(), wantReason)
+	}
+	for i := 0; i < len(wantFields); i += 2 {
+		key, want := wantFields[i], wantFields[i+1]
+		if got := info.GetMetadata()[key]; got != want {
+			t.Errorf("err = %v: ErrorInfo.Metadata[%q] = %q; want %q", err, key, got, want)
+		}
+	}
+}
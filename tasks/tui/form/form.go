@@ -0,0 +1,115 @@
+// Package form implements a small modal input overlay used by the TUI
+// whenever a view needs to collect a handful of text fields from the user,
+// such as a task's title, description, and parent.
+package form
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SubmitMsg is emitted when the user confirms the form (by pressing enter on
+// the last field). Values are in the same order as the labels passed to New.
+type SubmitMsg struct {
+	Values []string
+}
+
+// CancelMsg is emitted when the user dismisses the form without submitting
+// it, by pressing esc.
+type CancelMsg struct{}
+
+var (
+	titleStyle = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	labelStyle = lipgloss.NewStyle().Width(12)
+	boxStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2)
+)
+
+// Model is a modal form with a fixed set of single-line text fields. Tab and
+// shift+tab move focus between fields; enter on the last field submits the
+// form; esc cancels it at any point.
+type Model struct {
+	title  string
+	inputs []textinput.Model
+	focus  int
+}
+
+// New creates a form titled title, with one text input per label, in order.
+// Any values are used to pre-fill the corresponding input, for editing an
+// existing resource; it may be shorter than labels or nil.
+func New(title string, labels []string, values []string) *Model {
+	inputs := make([]textinput.Model, len(labels))
+	for i, label := range labels {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.Placeholder = label
+		if i < len(values) {
+			ti.SetValue(values[i])
+		}
+		if i == 0 {
+			ti.Focus()
+		}
+		inputs[i] = ti
+	}
+	return &Model{
+		title:  title,
+		inputs: inputs,
+	}
+}
+
+func (m *Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEsc:
+			return m, func() tea.Msg { return CancelMsg{} }
+		case tea.KeyTab, tea.KeyDown:
+			m.advanceFocus(1)
+			return m, nil
+		case tea.KeyShiftTab, tea.KeyUp:
+			m.advanceFocus(-1)
+			return m, nil
+		case tea.KeyEnter:
+			if m.focus == len(m.inputs)-1 {
+				values := make([]string, len(m.inputs))
+				for i, in := range m.inputs {
+					values[i] = in.Value()
+				}
+				return m, func() tea.Msg { return SubmitMsg{Values: values} }
+			}
+			m.advanceFocus(1)
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+// advanceFocus moves focus by delta fields, wrapping around.
+func (m *Model) advanceFocus(delta int) {
+	m.inputs[m.focus].Blur()
+	n := len(m.inputs)
+	m.focus = ((m.focus+delta)%n + n) % n
+	m.inputs[m.focus].Focus()
+}
+
+func (m *Model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(m.title))
+	b.WriteString("\n")
+	for i, in := range m.inputs {
+		b.WriteString(labelStyle.Render(in.Placeholder))
+		b.WriteString(in.View())
+		if i < len(m.inputs)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return boxStyle.Render(b.String())
+}
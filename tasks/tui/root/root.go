@@ -5,10 +5,15 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	pb "go.saser.se/tasks/tasks_go_proto"
 	"go.saser.se/tasks/tui/tasklist"
 )
 
+var helpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+const helpText = "a create · e edit · d delete · x complete · u undo-complete · L load more · ctrl+r refresh · ctrl+c quit"
+
 type Model struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -54,6 +59,8 @@ func (m *Model) UpdateContext(ctx context.Context, msg tea.Msg) (tea.Model, tea.
 func (m *Model) View() string {
 	var b strings.Builder
 	b.WriteString(m.list.View())
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(helpText))
 	return b.String()
 }
 
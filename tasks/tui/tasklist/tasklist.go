@@ -2,16 +2,57 @@ package tasklist
 
 import (
 	"context"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	pb "go.saser.se/tasks/tasks_go_proto"
+	"go.saser.se/tasks/tui/form"
 	"go.saser.se/tasks/tui/tasksclient"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
+// mode identifies which of the list view or the modal form is currently
+// driving input.
+type mode int
+
+const (
+	modeList mode = iota
+	modeCreate
+	modeEdit
+)
+
+// scrollLoadThreshold is how many items from the bottom of the currently
+// loaded list the cursor may get before the next page is fetched
+// automatically.
+const scrollLoadThreshold = 3
+
+var errStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
 type Model struct {
-	client pb.TasksClient
-	list   list.Model
+	client  pb.TasksClient
+	list    list.Model
+	spinner spinner.Model
+
+	// nextPageToken is the token for the next page of tasks, as returned by
+	// the most recent ListTasks call; empty once the last page is loaded.
+	nextPageToken string
+	// loadingMore is true while a ListTasksCmd for a non-first page is
+	// in-flight, so that scrolling or repeated key presses don't fire off
+	// duplicate requests for the same page.
+	loadingMore bool
+
+	mode mode
+	// form is the active modal form, non-nil in modeCreate and modeEdit.
+	form *form.Model
+	// editing is the task being edited, set only in modeEdit.
+	editing *pb.Task
+
+	// err is the error from the most recently failed RPC, surfaced on the
+	// status line until the next successful one.
+	err error
 }
 
 type item struct {
@@ -26,40 +67,212 @@ var _ list.DefaultItem = (*item)(nil)
 
 func New(client pb.TasksClient) *Model {
 	l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
 	return &Model{
-		client: client,
-		list:   l,
+		client:  client,
+		list:    l,
+		spinner: sp,
 	}
 }
 
 func (m *Model) InitContext(ctx context.Context) tea.Cmd {
+	return m.firstPageCmd(ctx)
+}
+
+// firstPageCmd fetches the first page of tasks, discarding any page token
+// tracked from a previous listing.
+func (m *Model) firstPageCmd(ctx context.Context) tea.Cmd {
 	return tasksclient.ListTasksCmd(ctx, m.client, &pb.ListTasksRequest{})
 }
 
+// loadMoreCmd fetches the next page of tasks, if there is a next page and
+// one isn't already being fetched.
+func (m *Model) loadMoreCmd(ctx context.Context) tea.Cmd {
+	if m.loadingMore || m.nextPageToken == "" {
+		return nil
+	}
+	m.loadingMore = true
+	return tea.Batch(
+		tasksclient.ListTasksCmd(ctx, m.client, &pb.ListTasksRequest{PageToken: m.nextPageToken}),
+		m.spinner.Tick,
+	)
+}
+
+// selected returns the task backing the currently highlighted list item, or
+// nil if the list is empty.
+func (m *Model) selected() *pb.Task {
+	it, ok := m.list.SelectedItem().(*item)
+	if !ok {
+		return nil
+	}
+	return it.task
+}
+
 func (m *Model) UpdateContext(ctx context.Context, msg tea.Msg) (*Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case *tasksclient.ListTasksDone:
+		m.loadingMore = false
 		if msg.Err != nil {
-			// TODO: error handling
+			m.err = msg.Err
+			return m, nil
 		}
+		m.err = nil
+		m.nextPageToken = msg.Response.GetNextPageToken()
 		var items []list.Item
+		if msg.Request.GetPageToken() != "" {
+			// This was a request for a subsequent page, so its results are
+			// appended to what's already loaded instead of replacing it.
+			items = append(items, m.list.Items()...)
+		}
 		for _, t := range msg.Response.GetTasks() {
 			items = append(items, &item{t})
 		}
 		return m, m.list.SetItems(items)
+	case *tasksclient.CreateTaskDone:
+		return m, m.handleDone(ctx, msg.Err)
+	case *tasksclient.UpdateTaskDone:
+		return m, m.handleDone(ctx, msg.Err)
+	case *tasksclient.DeleteTaskDone:
+		return m, m.handleDone(ctx, msg.Err)
+	case *tasksclient.CompleteTaskDone:
+		return m, m.handleDone(ctx, msg.Err)
+	case *tasksclient.UncompleteTaskDone:
+		return m, m.handleDone(ctx, msg.Err)
+	case form.SubmitMsg:
+		return m, m.submitForm(ctx, msg)
+	case form.CancelMsg:
+		m.mode = modeList
+		m.form = nil
+		m.editing = nil
+		return m, nil
+	case spinner.TickMsg:
+		if !m.loadingMore {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
 	case tea.WindowSizeMsg:
 		m.list.SetSize(msg.Width, msg.Height)
 	case tea.KeyMsg:
+		if m.mode != modeList {
+			break
+		}
 		switch msg.Type {
 		case tea.KeyCtrlR:
-			return m, tasksclient.ListTasksCmd(ctx, m.client, &pb.ListTasksRequest{})
+			return m, m.firstPageCmd(ctx)
+		}
+		switch msg.String() {
+		case "a":
+			m.mode = modeCreate
+			m.editing = nil
+			m.form = form.New("New task", []string{"Title", "Description", "Parent"}, nil)
+			return m, m.form.Init()
+		case "e":
+			task := m.selected()
+			if task == nil {
+				return m, nil
+			}
+			m.mode = modeEdit
+			m.editing = task
+			m.form = form.New("Edit task", []string{"Title", "Description"}, []string{task.GetTitle(), task.GetDescription()})
+			return m, m.form.Init()
+		case "d":
+			task := m.selected()
+			if task == nil {
+				return m, nil
+			}
+			return m, tasksclient.DeleteTaskCmd(ctx, m.client, &pb.DeleteTaskRequest{Name: task.GetName()})
+		case "x":
+			task := m.selected()
+			if task == nil {
+				return m, nil
+			}
+			return m, tasksclient.CompleteTaskCmd(ctx, m.client, &pb.CompleteTaskRequest{Name: task.GetName()})
+		case "u":
+			task := m.selected()
+			if task == nil {
+				return m, nil
+			}
+			return m, tasksclient.UncompleteTaskCmd(ctx, m.client, &pb.UncompleteTaskRequest{Name: task.GetName()})
+		case "L":
+			return m, m.loadMoreCmd(ctx)
 		}
 	}
+	if m.mode != modeList {
+		var cmd tea.Cmd
+		m.form, cmd = m.form.Update(msg)
+		return m, cmd
+	}
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
+	// Infinite scroll: once the cursor gets close to the bottom of what's
+	// currently loaded, fetch the next page ahead of the user reaching it.
+	if m.list.Index() >= len(m.list.Items())-scrollLoadThreshold {
+		return m, tea.Batch(cmd, m.loadMoreCmd(ctx))
+	}
 	return m, cmd
 }
 
+// handleDone is the common tail of every mutating RPC: on error it's
+// surfaced on the status line, on success the list is refreshed from the
+// first page so it reflects the change.
+func (m *Model) handleDone(ctx context.Context, err error) tea.Cmd {
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.err = nil
+	return m.firstPageCmd(ctx)
+}
+
+// submitForm dispatches the create or edit RPC that the active form was
+// collecting values for, and returns to the list view.
+func (m *Model) submitForm(ctx context.Context, msg form.SubmitMsg) tea.Cmd {
+	mode, editing := m.mode, m.editing
+	m.mode = modeList
+	m.form = nil
+	m.editing = nil
+	switch mode {
+	case modeCreate:
+		title, description, parent := msg.Values[0], msg.Values[1], msg.Values[2]
+		return tasksclient.CreateTaskCmd(ctx, m.client, &pb.CreateTaskRequest{
+			Task: &pb.Task{
+				Title:       title,
+				Description: description,
+				Parent:      parent,
+			},
+		})
+	case modeEdit:
+		title, description := msg.Values[0], msg.Values[1]
+		return tasksclient.UpdateTaskCmd(ctx, m.client, &pb.UpdateTaskRequest{
+			Task: &pb.Task{
+				Name:        editing.GetName(),
+				Title:       title,
+				Description: description,
+			},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title", "description"}},
+		})
+	default:
+		return nil
+	}
+}
+
 func (m *Model) View() string {
-	return m.list.View()
+	if m.mode != modeList {
+		return m.form.View()
+	}
+	var b strings.Builder
+	b.WriteString(m.list.View())
+	if m.loadingMore {
+		b.WriteString("\n")
+		b.WriteString(m.spinner.View())
+		b.WriteString(" loading more tasks...")
+	}
+	if m.err != nil {
+		b.WriteString("\n")
+		b.WriteString(errStyle.Render("error: " + m.err.Error()))
+	}
+	return b.String()
 }
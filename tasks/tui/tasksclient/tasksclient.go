@@ -8,6 +8,10 @@ import (
 )
 
 type ListTasksDone struct {
+	// Request is the request that this is a response to, so that callers
+	// handling paginated results can tell a first page (an empty
+	// PageToken) from a subsequent one.
+	Request  *pb.ListTasksRequest
 	Response *pb.ListTasksResponse
 	Err      error
 }
@@ -16,8 +20,84 @@ func ListTasksCmd(ctx context.Context, c pb.TasksClient, req *pb.ListTasksReques
 	return func() tea.Msg {
 		res, err := c.ListTasks(ctx, req)
 		return &ListTasksDone{
+			Request:  req,
 			Response: res,
 			Err:      err,
 		}
 	}
 }
+
+type CreateTaskDone struct {
+	Task *pb.Task
+	Err  error
+}
+
+func CreateTaskCmd(ctx context.Context, c pb.TasksClient, req *pb.CreateTaskRequest) tea.Cmd {
+	return func() tea.Msg {
+		task, err := c.CreateTask(ctx, req)
+		return &CreateTaskDone{
+			Task: task,
+			Err:  err,
+		}
+	}
+}
+
+type UpdateTaskDone struct {
+	Task *pb.Task
+	Err  error
+}
+
+func UpdateTaskCmd(ctx context.Context, c pb.TasksClient, req *pb.UpdateTaskRequest) tea.Cmd {
+	return func() tea.Msg {
+		task, err := c.UpdateTask(ctx, req)
+		return &UpdateTaskDone{
+			Task: task,
+			Err:  err,
+		}
+	}
+}
+
+type DeleteTaskDone struct {
+	Task *pb.Task
+	Err  error
+}
+
+func DeleteTaskCmd(ctx context.Context, c pb.TasksClient, req *pb.DeleteTaskRequest) tea.Cmd {
+	return func() tea.Msg {
+		task, err := c.DeleteTask(ctx, req)
+		return &DeleteTaskDone{
+			Task: task,
+			Err:  err,
+		}
+	}
+}
+
+type CompleteTaskDone struct {
+	Task *pb.Task
+	Err  error
+}
+
+func CompleteTaskCmd(ctx context.Context, c pb.TasksClient, req *pb.CompleteTaskRequest) tea.Cmd {
+	return func() tea.Msg {
+		task, err := c.CompleteTask(ctx, req)
+		return &CompleteTaskDone{
+			Task: task,
+			Err:  err,
+		}
+	}
+}
+
+type UncompleteTaskDone struct {
+	Task *pb.Task
+	Err  error
+}
+
+func UncompleteTaskCmd(ctx context.Context, c pb.TasksClient, req *pb.UncompleteTaskRequest) tea.Cmd {
+	return func() tea.Msg {
+		task, err := c.UncompleteTask(ctx, req)
+		return &UncompleteTaskDone{
+			Task: task,
+			Err:  err,
+		}
+	}
+}
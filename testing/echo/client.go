@@ -0,0 +1,246 @@
+package echo
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	pb "go.saser.se/testing/echo_go_proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options configures the retry/backoff behavior of a Client.
+type Options struct {
+	// MaxRetries is how many times a call is retried after an initial
+	// failure. The zero value defaults to 3.
+	MaxRetries int
+	// InitialBackoff is how long Client waits before the first retry. The
+	// zero value defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps how long Client ever waits between retries. The zero
+	// value defaults to 2s.
+	MaxBackoff time.Duration
+	// Multiplier is how much the backoff grows after each retry. The zero
+	// value defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction of each backoff, in both directions, that is
+	// randomized, to avoid many clients retrying in lockstep. The zero
+	// value defaults to 0.2 (20%).
+	Jitter float64
+	// DefaultTimeout is applied to a call's context if it doesn't already
+	// have a deadline. The zero value means no default is applied.
+	DefaultTimeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.InitialBackoff == 0 {
+		o.InitialBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = 2 * time.Second
+	}
+	if o.Multiplier == 0 {
+		o.Multiplier = 2
+	}
+	if o.Jitter == 0 {
+		o.Jitter = 0.2
+	}
+	return o
+}
+
+// backoff returns how long to wait before the retry numbered attempt
+// (0-indexed), with jitter applied.
+func (o Options) backoff(attempt int) time.Duration {
+	d := float64(o.InitialBackoff)
+	for i := 0; i < attempt; i++ {
+		d *= o.Multiplier
+		if d > float64(o.MaxBackoff) {
+			d = float64(o.MaxBackoff)
+			break
+		}
+	}
+	if o.Jitter > 0 {
+		d += d * o.Jitter * (2*rand.Float64() - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// isRetryable reports whether err is a gRPC status that's worth retrying:
+// Unavailable (the usual transient-connectivity code) or DeadlineExceeded
+// (which, under a Client's own per-attempt deadline, typically means a
+// single attempt was slow rather than the overall call being impossible).
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryUnary calls fn, retrying it with exponential backoff and jitter
+// while it returns a retryable error, up to opts.MaxRetries times, or until
+// ctx is done.
+func retryUnary(ctx context.Context, opts Options, fn func(context.Context) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !isRetryable(err) || attempt == opts.MaxRetries {
+			return err
+		}
+		t := time.NewTimer(opts.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// withDeadline returns ctx as-is if it already has a deadline or timeout is
+// non-positive; otherwise it returns ctx with timeout applied. The returned
+// cancel func must be called once the context is no longer needed,
+// including by a stream that outlives the call that created it.
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Client wraps the generated Echo client stub, transparently retrying
+// unary calls and stream creation on Unavailable and DeadlineExceeded, and
+// applying Options.DefaultTimeout to calls whose context has no deadline of
+// its own.
+type Client struct {
+	cc   pb.EchoClient
+	opts Options
+}
+
+// NewClient returns a Client that issues calls over cc.
+func NewClient(cc grpc.ClientConnInterface, opts Options) *Client {
+	return &Client{cc: pb.NewEchoClient(cc), opts: opts.withDefaults()}
+}
+
+// Echo calls the Echo RPC, retrying on Unavailable and DeadlineExceeded.
+func (c *Client) Echo(ctx context.Context, req *pb.EchoRequest, callOpts ...grpc.CallOption) (*pb.EchoResponse, error) {
+	ctx, cancel := withDeadline(ctx, c.opts.DefaultTimeout)
+	defer cancel()
+	var res *pb.EchoResponse
+	err := retryUnary(ctx, c.opts, func(ctx context.Context) error {
+		var err error
+		res, err = c.cc.Echo(ctx, req, callOpts...)
+		return err
+	})
+	return res, err
+}
+
+// echoStreamClient wraps pb.Echo_EchoStreamClient to release the deadline
+// context withDeadline created once the stream ends.
+type echoStreamClient struct {
+	pb.Echo_EchoStreamClient
+	cancel context.CancelFunc
+}
+
+func (s *echoStreamClient) Recv() (*pb.EchoResponse, error) {
+	res, err := s.Echo_EchoStreamClient.Recv()
+	if err != nil {
+		s.cancel()
+	}
+	return res, err
+}
+
+// EchoStream opens the EchoStream RPC, retrying stream creation on
+// Unavailable and DeadlineExceeded. Retries only cover opening the stream;
+// once opened, errors from Recv are returned as-is, since messages already
+// sent can't be replayed.
+func (c *Client) EchoStream(ctx context.Context, req *pb.EchoStreamRequest, callOpts ...grpc.CallOption) (pb.Echo_EchoStreamClient, error) {
+	ctx, cancel := withDeadline(ctx, c.opts.DefaultTimeout)
+	var stream pb.Echo_EchoStreamClient
+	err := retryUnary(ctx, c.opts, func(ctx context.Context) error {
+		var err error
+		stream, err = c.cc.EchoStream(ctx, req, callOpts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &echoStreamClient{Echo_EchoStreamClient: stream, cancel: cancel}, nil
+}
+
+// echoCollectClient wraps pb.Echo_EchoCollectClient to release the deadline
+// context withDeadline created once the stream ends.
+type echoCollectClient struct {
+	pb.Echo_EchoCollectClient
+	cancel context.CancelFunc
+}
+
+func (s *echoCollectClient) CloseAndRecv() (*pb.EchoResponse, error) {
+	res, err := s.Echo_EchoCollectClient.CloseAndRecv()
+	s.cancel()
+	return res, err
+}
+
+// EchoCollect opens the EchoCollect RPC, retrying stream creation on
+// Unavailable and DeadlineExceeded. Retries only cover opening the stream;
+// once opened, errors from Send or CloseAndRecv are returned as-is.
+func (c *Client) EchoCollect(ctx context.Context, callOpts ...grpc.CallOption) (pb.Echo_EchoCollectClient, error) {
+	ctx, cancel := withDeadline(ctx, c.opts.DefaultTimeout)
+	var stream pb.Echo_EchoCollectClient
+	err := retryUnary(ctx, c.opts, func(ctx context.Context) error {
+		var err error
+		stream, err = c.cc.EchoCollect(ctx, callOpts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &echoCollectClient{Echo_EchoCollectClient: stream, cancel: cancel}, nil
+}
+
+// echoChatClient wraps pb.Echo_EchoChatClient to release the deadline
+// context withDeadline created once the stream ends.
+type echoChatClient struct {
+	pb.Echo_EchoChatClient
+	cancel context.CancelFunc
+}
+
+func (s *echoChatClient) Recv() (*pb.EchoResponse, error) {
+	res, err := s.Echo_EchoChatClient.Recv()
+	if err != nil {
+		s.cancel()
+	}
+	return res, err
+}
+
+// EchoChat opens the bidirectional EchoChat RPC, retrying stream creation
+// on Unavailable and DeadlineExceeded. Retries only cover opening the
+// stream; once opened, errors from Send or Recv are returned as-is.
+func (c *Client) EchoChat(ctx context.Context, callOpts ...grpc.CallOption) (pb.Echo_EchoChatClient, error) {
+	ctx, cancel := withDeadline(ctx, c.opts.DefaultTimeout)
+	var stream pb.Echo_EchoChatClient
+	err := retryUnary(ctx, c.opts, func(ctx context.Context) error {
+		var err error
+		stream, err = c.cc.EchoChat(ctx, callOpts...)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &echoChatClient{Echo_EchoChatClient: stream, cancel: cancel}, nil
+}
@@ -3,6 +3,9 @@ package echo
 
 import (
 	"context"
+	"io"
+	"strings"
+	"time"
 
 	pb "go.saser.se/testing/echo_go_proto"
 )
@@ -17,3 +20,57 @@ var _ pb.EchoServer = Server{}
 func (Server) Echo(ctx context.Context, req *pb.EchoRequest) (*pb.EchoResponse, error) {
 	return &pb.EchoResponse{Message: req.GetMessage()}, nil
 }
+
+// EchoStream sends req.GetMessage() back req.GetCount() times, waiting
+// req.GetDelay() between each send if it's set. It returns early if the
+// stream's context is cancelled while waiting out a delay.
+func (Server) EchoStream(req *pb.EchoStreamRequest, stream pb.Echo_EchoStreamServer) error {
+	delay := req.GetDelay().AsDuration()
+	for i := int32(0); i < req.GetCount(); i++ {
+		if err := stream.Send(&pb.EchoResponse{Message: req.GetMessage()}); err != nil {
+			return err
+		}
+		if delay <= 0 || i == req.GetCount()-1 {
+			continue
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+// EchoCollect reads requests from the client until it closes the stream,
+// then responds with the concatenation of every message it received.
+func (Server) EchoCollect(stream pb.Echo_EchoCollectServer) error {
+	var b strings.Builder
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.EchoResponse{Message: b.String()})
+		}
+		if err != nil {
+			return err
+		}
+		b.WriteString(req.GetMessage())
+	}
+}
+
+// EchoChat echoes each request back to the client as it arrives, for as
+// long as the client keeps the stream open.
+func (Server) EchoChat(stream pb.Echo_EchoChatServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&pb.EchoResponse{Message: req.GetMessage()}); err != nil {
+			return err
+		}
+	}
+}